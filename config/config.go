@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -19,6 +22,65 @@ type Config struct {
 	JWTSecret string
 	APIKey    string
 	Port      string
+	DBDriver  string // "mongo" (default) atau "memory"
+	UploadDir string // folder penyimpanan file upload
+
+	// DefaultMaksHariPinjam dipakai untuk kategori yang belum punya aturan
+	DefaultMaksHariPinjam int
+	// DefaultMaksPerpanjangan adalah jumlah perpanjangan yang disetujui
+	// otomatis untuk kategori yang belum mengaturnya
+	DefaultMaksPerpanjangan int
+
+	// DefaultDendaPerHari dipakai untuk alat yang belum punya kebijakan denda
+	DefaultDendaPerHari int64
+
+	// AccessTokenTTL adalah umur JWT access token, RefreshTokenTTL umur
+	// refresh token (session login)
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Pengiriman email: MailDriver "log" (default) menulis email ke
+	// MailLogFile / log server, "smtp" mengirim lewat server SMTP
+	MailDriver   string
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+
+	// ResetPasswordURL adalah halaman frontend untuk reset password. Token
+	// ditambahkan sebagai ?token=. ResetPasswordTTL adalah umur token reset.
+	ResetPasswordURL string
+	ResetPasswordTTL time.Duration
+
+	// VerifikasiEmailURL adalah halaman frontend verifikasi email, token
+	// ditambahkan sebagai ?token=. VerifikasiEmailTTL adalah umur tokennya.
+	VerifikasiEmailURL string
+	VerifikasiEmailTTL time.Duration
+
+	// DomainEmailRegistrasi membatasi registrasi ke domain email kampus,
+	// misalnya "student.univ.ac.id". Kosong berarti semua domain diterima.
+	// Hanya diatur lewat env dan dibaca sekali saat server start, sama
+	// seperti SUPER_ADMIN_EMAILS, sehingga tidak bisa dibuka lewat API
+	// oleh admin yang akunnya bocor.
+	DomainEmailRegistrasi []string
+
+	// FormatNIM adalah regex NIM yang diterima saat registrasi. nil berarti
+	// format NIM tidak diperiksa, karena format NIM berbeda antar kampus.
+	FormatNIM *regexp.Regexp
+
+	// SuperAdminEmails adalah email user yang dijadikan super_admin saat
+	// server start, untuk menyiapkan super admin pertama
+	SuperAdminEmails []string
+
+	// PengembalianMandiri mengizinkan peminjam menutup sendiri peminjamannya.
+	// Jika false, pengembalian hanya lewat check-in oleh staff lab.
+	PengembalianMandiri bool
+
+	// RetensiAlatDihapus adalah lama alat yang dihapus masih bisa dipulihkan
+	// sebelum dihapus permanen. 0 berarti alat tidak pernah dihapus permanen.
+	RetensiAlatDihapus time.Duration
 }
 
 // AppConfig adalah variabel global untuk menyimpan konfigurasi
@@ -27,12 +89,8 @@ var AppConfig Config
 // MongoClient adalah client global MongoDB
 var MongoClient *mongo.Client
 
-// Koleksi global agar mudah dipakai di handler
-var (
-	UserCollection         *mongo.Collection
-	AlatCollection         *mongo.Collection
-	TransactionCollection  *mongo.Collection
-)
+// MongoDB adalah database aplikasi, dipakai untuk membangun repository
+var MongoDB *mongo.Database
 
 // LoadConfig membaca file .env lalu isi AppConfig
 func LoadConfig() {
@@ -45,16 +103,158 @@ func LoadConfig() {
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		APIKey:    os.Getenv("API_KEY"),
 		Port:      os.Getenv("PORT"),
+		DBDriver:  os.Getenv("DB_DRIVER"),
+		UploadDir: os.Getenv("UPLOAD_DIR"),
+
+		MailDriver:       os.Getenv("MAIL_DRIVER"),
+		MailFrom:         os.Getenv("MAIL_FROM"),
+		MailLogFile:      os.Getenv("MAIL_LOG_FILE"),
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPUser:         os.Getenv("SMTP_USER"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		ResetPasswordURL: os.Getenv("RESET_PASSWORD_URL"),
+
+		VerifikasiEmailURL: os.Getenv("VERIFIKASI_EMAIL_URL"),
 	}
 
 	if AppConfig.Port == "" {
 		AppConfig.Port = "8080"
 	}
+	if AppConfig.UploadDir == "" {
+		AppConfig.UploadDir = "uploads"
+	}
+
+	AppConfig.DefaultMaksHariPinjam = 7
+	if v := os.Getenv("DEFAULT_MAKS_HARI_PINJAM"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("DEFAULT_MAKS_HARI_PINJAM harus angka > 0")
+		}
+		AppConfig.DefaultMaksHariPinjam = n
+	}
+
+	AppConfig.DefaultMaksPerpanjangan = 1
+	if v := os.Getenv("DEFAULT_MAKS_PERPANJANGAN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("DEFAULT_MAKS_PERPANJANGAN harus angka >= 0")
+		}
+		AppConfig.DefaultMaksPerpanjangan = n
+	}
+
+	if v := os.Getenv("DEFAULT_DENDA_PER_HARI"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			log.Fatal("DEFAULT_DENDA_PER_HARI harus angka >= 0")
+		}
+		AppConfig.DefaultDendaPerHari = n
+	}
+
+	AppConfig.AccessTokenTTL = 15 * time.Minute
+	if v := os.Getenv("ACCESS_TOKEN_MENIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("ACCESS_TOKEN_MENIT harus angka > 0")
+		}
+		AppConfig.AccessTokenTTL = time.Duration(n) * time.Minute
+	}
+
+	AppConfig.RefreshTokenTTL = 30 * 24 * time.Hour
+	if v := os.Getenv("REFRESH_TOKEN_HARI"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("REFRESH_TOKEN_HARI harus angka > 0")
+		}
+		AppConfig.RefreshTokenTTL = time.Duration(n) * 24 * time.Hour
+	}
+
+	AppConfig.ResetPasswordTTL = 30 * time.Minute
+	if v := os.Getenv("RESET_PASSWORD_MENIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("RESET_PASSWORD_MENIT harus angka > 0")
+		}
+		AppConfig.ResetPasswordTTL = time.Duration(n) * time.Minute
+	}
+
+	AppConfig.VerifikasiEmailTTL = 24 * time.Hour
+	if v := os.Getenv("VERIFIKASI_EMAIL_JAM"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("VERIFIKASI_EMAIL_JAM harus angka > 0")
+		}
+		AppConfig.VerifikasiEmailTTL = time.Duration(n) * time.Hour
+	}
+
+	AppConfig.PengembalianMandiri = true
+	if v := os.Getenv("PENGEMBALIAN_MANDIRI"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("PENGEMBALIAN_MANDIRI harus true atau false")
+		}
+		AppConfig.PengembalianMandiri = b
+	}
+
+	AppConfig.RetensiAlatDihapus = 30 * 24 * time.Hour
+	if v := os.Getenv("RETENSI_ALAT_HARI"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("RETENSI_ALAT_HARI harus angka >= 0")
+		}
+		AppConfig.RetensiAlatDihapus = time.Duration(n) * 24 * time.Hour
+	}
+
+	for _, d := range strings.Split(os.Getenv("REGISTER_EMAIL_DOMAINS"), ",") {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d != "" {
+			AppConfig.DomainEmailRegistrasi = append(AppConfig.DomainEmailRegistrasi, d)
+		}
+	}
+
+	for _, e := range strings.Split(os.Getenv("SUPER_ADMIN_EMAILS"), ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e != "" {
+			AppConfig.SuperAdminEmails = append(AppConfig.SuperAdminEmails, e)
+		}
+	}
+
+	if formatNIM := os.Getenv("NIM_REGEX"); formatNIM != "" {
+		re, err := regexp.Compile(formatNIM)
+		if err != nil {
+			log.Fatalf("NIM_REGEX tidak valid: %v", err)
+		}
+		AppConfig.FormatNIM = re
+	}
+
+	if AppConfig.MailDriver == "" {
+		AppConfig.MailDriver = "log"
+	}
+	if AppConfig.MailFrom == "" {
+		AppConfig.MailFrom = "SIPAK <no-reply@sipak.local>"
+	}
+	AppConfig.SMTPPort = 587
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("SMTP_PORT harus angka > 0")
+		}
+		AppConfig.SMTPPort = n
+	}
+
+	if AppConfig.DBDriver == "" {
+		AppConfig.DBDriver = "mongo"
+	}
 
 	// Validasi sederhana
-	if AppConfig.MongoURI == "" || AppConfig.DBName == "" {
+	if AppConfig.DBDriver == "mongo" && (AppConfig.MongoURI == "" || AppConfig.DBName == "") {
 		log.Fatal("MONGO_URI atau DB_NAME belum di-set di .env")
 	}
+	if AppConfig.MailDriver == "smtp" && AppConfig.SMTPHost == "" {
+		log.Fatal("SMTP_HOST belum di-set di .env")
+	}
+	if AppConfig.MailDriver != "smtp" && AppConfig.MailDriver != "log" {
+		log.Fatal("MAIL_DRIVER harus smtp atau log")
+	}
 	if AppConfig.JWTSecret == "" {
 		log.Fatal("JWT_SECRET belum di-set di .env")
 	}
@@ -80,12 +280,7 @@ func ConnectMongo() {
 	}
 
 	MongoClient = client
-	db := client.Database(AppConfig.DBName)
-
-	// Inisialisasi koleksi
-	UserCollection = db.Collection("users")
-	AlatCollection = db.Collection("alat")
-	TransactionCollection = db.Collection("transactions")
+	MongoDB = client.Database(AppConfig.DBName)
 
 	fmt.Println("✅ Koneksi MongoDB berhasil")
 }
@@ -3,30 +3,111 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"SIPAK/config"
+	"SIPAK/middleware"
 	"SIPAK/models"
+	"SIPAK/repository"
 	"SIPAK/utils"
 
 	"github.com/go-chi/chi/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// errAlatTidakDihapus dikembalikan saat memulihkan alat yang masih aktif
+var errAlatTidakDihapus = errors.New("alat tidak sedang dihapus")
+
 // AlatHandler mengelola CRUD alat
-type AlatHandler struct{}
+type AlatHandler struct {
+	alat         repository.AlatRepository
+	unit         repository.AlatUnitRepository
+	transaksi    repository.TransactionRepository
+	reservasi    repository.ReservasiRepository
+	labs         repository.LabRepository
+	audit        repository.AuditRepository
+	tx           repository.Transactor
+	ketersediaan ketersediaan
+	akses        aksesLab
+	buku         bukuStok
+}
+
+// NewAlatHandler membuat AlatHandler dari repository di store
+func NewAlatHandler(store *repository.Store) *AlatHandler {
+	return &AlatHandler{
+		alat:         store.Alat,
+		unit:         store.Unit,
+		transaksi:    store.Transactions,
+		reservasi:    store.Reservasi,
+		labs:         store.Lab,
+		audit:        store.Audit,
+		tx:           store.Tx,
+		ketersediaan: newKetersediaan(store),
+		akses:        newAksesLab(store),
+		buku:         newBukuStok(store),
+	}
+}
+
+// maksSlotKetersediaan membatasi jumlah slot kalender dalam satu request
+const maksSlotKetersediaan = 500
+
+// SlotKetersediaan adalah jumlah unit yang masih bisa dipinjam pada satu slot waktu
+type SlotKetersediaan struct {
+	Mulai    time.Time `json:"mulai"`
+	Selesai  time.Time `json:"selesai"`
+	Tersedia int       `json:"tersedia"`
+}
 
 // Request body untuk membuat/mengupdate alat
 type alatRequest struct {
-	Nama      string `json:"nama"`
-	Kategori  string `json:"kategori"`
-	Deskripsi string `json:"deskripsi"`
-	StokTotal int    `json:"stok_total"`
+	Nama           string                 `json:"nama"`
+	Kategori       string                 `json:"kategori"`
+	Deskripsi      string                 `json:"deskripsi"`
+	LabID          *primitive.ObjectID    `json:"lab_id,omitempty"`
+	StokTotal      int                    `json:"stok_total"`
+	NilaiBarang    int64                  `json:"nilai_barang"`
+	KebijakanDenda *models.KebijakanDenda `json:"kebijakan_denda,omitempty"`
+	// Unit opsional saat membuat alat. Jika kosong dibuat stok_total unit
+	// dengan kode aset otomatis.
+	Unit []unitRequest `json:"unit,omitempty"`
+}
+
+// cekLabTujuan memastikan lab tujuan alat ada dan boleh dikelola user.
+// Jika gagal, response error sudah ditulis dan hasilnya false.
+func (h *AlatHandler) cekLabTujuan(ctx context.Context, w http.ResponseWriter, r *http.Request, labID *primitive.ObjectID) bool {
+	l, err := h.akses.lingkup(ctx, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa lingkup lab")
+		return false
+	}
+	if labID == nil {
+		if !l.semua {
+			utils.WriteError(w, http.StatusBadRequest, "lab_id wajib diisi")
+			return false
+		}
+		return true
+	}
+	if _, err := h.labs.FindByID(ctx, *labID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusBadRequest, "Lab tidak ditemukan")
+			return false
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa lab")
+		return false
+	}
+	if !l.boleh(labID) {
+		utils.WriteError(w, http.StatusForbidden, "Tidak bisa mengelola alat di lab lain")
+		return false
+	}
+	return true
 }
 
-// CreateAlat (admin) menambah alat baru
+// CreateAlat (admin) menambah alat baru. Role dengan lingkup lab wajib
+// mengisi lab_id dengan lab tempatnya bertugas.
 func (h *AlatHandler) CreateAlat(w http.ResponseWriter, r *http.Request) {
 	var req alatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -35,27 +116,88 @@ func (h *AlatHandler) CreateAlat(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	if req.Nama == "" || req.StokTotal <= 0 {
-		utils.WriteError(w, http.StatusBadRequest, "Nama dan stok_total wajib, stok_total > 0")
+	if req.Nama == "" || (req.StokTotal <= 0 && len(req.Unit) == 0) {
+		utils.WriteError(w, http.StatusBadRequest, "Nama dan stok_total (atau daftar unit) wajib, stok_total > 0")
+		return
+	}
+	if req.NilaiBarang < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "nilai_barang tidak boleh negatif")
+		return
+	}
+	if req.KebijakanDenda != nil && !req.KebijakanDenda.Valid() {
+		utils.WriteError(w, http.StatusBadRequest, "kebijakan_denda tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !h.cekLabTujuan(ctx, w, r, req.LabID) {
 		return
 	}
 
 	now := time.Now()
 	alat := models.Alat{
-		ID:           primitive.NewObjectID(),
-		Nama:         req.Nama,
-		Kategori:     req.Kategori,
-		Deskripsi:    req.Deskripsi,
-		StokTotal:    req.StokTotal,
-		StokTersedia: req.StokTotal,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:             primitive.NewObjectID(),
+		Nama:           req.Nama,
+		Kategori:       req.Kategori,
+		Deskripsi:      req.Deskripsi,
+		LabID:          req.LabID,
+		NilaiBarang:    req.NilaiBarang,
+		KebijakanDenda: req.KebijakanDenda,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	units := req.Unit
+	if len(units) == 0 {
+		units = make([]unitRequest, req.StokTotal)
+	}
+	for i := range units {
+		if units[i].KodeAset == "" {
+			units[i].KodeAset = models.KodeAsetOtomatis(alat.ID, i+1)
+		}
+		if units[i].Kondisi == "" {
+			units[i].Kondisi = models.KondisiBaik
+		}
+		if !models.KondisiValid(units[i].Kondisi) {
+			utils.WriteError(w, http.StatusBadRequest, "kondisi unit tidak valid")
+			return
+		}
+	}
 
-	_, err := config.AlatCollection.InsertOne(ctx, alat)
+	// Stok alat dihitung dari unit yang dibuat bersamaan dengan alatnya
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.alat.Create(ctx, &alat); err != nil {
+			return err
+		}
+		ids := make([]primitive.ObjectID, len(units))
+		for i, u := range units {
+			unit := u.unit(alat.ID, now)
+			if err := h.unit.Create(ctx, &unit); err != nil {
+				return err
+			}
+			ids[i] = unit.ID
+		}
+		_, err := h.buku.catat(ctx, r, saldoStok{alatID: alat.ID}, models.MutasiStok{
+			Jenis:      models.MutasiPembelian,
+			UnitIDs:    ids,
+			Keterangan: "Alat baru",
+		})
+		if err != nil {
+			return err
+		}
+		created, err := h.alat.FindByID(ctx, alat.ID)
+		if err != nil {
+			return err
+		}
+		alat = *created
+		return catatPerubahan(ctx, h.audit, r, models.AuditAlatCreate, "alat", alat.ID, nil, alat)
+	})
+	if errors.Is(err, repository.ErrDuplikat) {
+		utils.WriteError(w, http.StatusConflict, "Kode aset sudah dipakai unit lain")
+		return
+	}
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan alat")
 		return
@@ -68,27 +210,97 @@ func (h *AlatHandler) CreateAlat(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListAlat menampilkan daftar alat (public: mahasiswa & admin)
+// parseAlatFilter membaca filter ?kategori=, ?lab_id= dan ?tersedia=true dari query
+func parseAlatFilter(r *http.Request) (repository.AlatFilter, error) {
+	q := r.URL.Query()
+	filter := repository.AlatFilter{Kategori: q.Get("kategori")}
+	labID, err := parseObjectIDQuery(r, "lab_id")
+	if err != nil {
+		return filter, err
+	}
+	if labID != nil {
+		filter.LabIDs = []primitive.ObjectID{*labID}
+	}
+	if s := q.Get("tersedia"); s != "" {
+		tersedia, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, errors.New("tersedia harus true atau false")
+		}
+		filter.HanyaTersedia = tersedia
+	}
+	return filter, nil
+}
+
+// ListAlat menampilkan daftar alat (public: mahasiswa & admin).
+// Query: q, kategori, lab_id, tersedia, page, limit, sort. Dengan q, hasil
+// diurutkan dari yang paling relevan kecuali sort diisi.
 func (h *AlatHandler) ListAlat(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "nama", "kategori", "stok_tersedia", "stok_total", "created_at")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := parseAlatFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Q = strings.TrimSpace(r.URL.Query().Get("q"))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := config.AlatCollection.Find(ctx, bson.M{})
+	alatList, total, err := h.alat.List(ctx, filter, opts)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var alatList []models.Alat
-	if err := cursor.All(ctx, &alatList); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data alat")
+	writeList(w, alatList, opts, total)
+}
+
+// Batas jumlah saran pencarian
+const (
+	defaultLimitSaran = 5
+	maksLimitSaran    = 20
+)
+
+// SuggestAlat memberi saran nama alat untuk kata yang sedang diketik.
+// Query: q (wajib), limit, kategori, tersedia. Salah ketik ringan tetap
+// menghasilkan saran, misalnya "osiloskp" → "Osiloskop Digital".
+func (h *AlatHandler) SuggestAlat(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		utils.WriteError(w, http.StatusBadRequest, "q wajib diisi")
+		return
+	}
+	limit := defaultLimitSaran
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maksLimitSaran {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("limit harus angka 1-%d", maksLimitSaran))
+			return
+		}
+		limit = n
+	}
+	filter, err := parseAlatFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	nama, err := h.alat.ListNama(ctx, filter)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil saran alat")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
-		Data:    alatList,
+		Data:    utils.SaranPrefix(q, nama, limit),
 	})
 }
 
@@ -104,9 +316,8 @@ func (h *AlatHandler) GetAlatByID(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var alat models.Alat
-	err = config.AlatCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&alat)
-	if err != nil {
+	alat, err := h.alat.FindByID(ctx, objID)
+	if err != nil || alat.Dihapus() {
 		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
 		return
 	}
@@ -133,22 +344,57 @@ func (h *AlatHandler) UpdateAlat(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	update := bson.M{
-		"nama":       req.Nama,
-		"kategori":   req.Kategori,
-		"deskripsi":  req.Deskripsi,
-		"updated_at": time.Now(),
+	if req.NilaiBarang < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "nilai_barang tidak boleh negatif")
+		return
 	}
-
-	if req.StokTotal > 0 {
-		// NOTE: ini sederhana, tidak menghitung stok_tersedia dari transaksi
-		update["stok_total"] = req.StokTotal
+	if req.KebijakanDenda != nil && !req.KebijakanDenda.Valid() {
+		utils.WriteError(w, http.StatusBadRequest, "kebijakan_denda tidak valid")
+		return
+	}
+	if req.StokTotal > 0 || len(req.Unit) > 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Stok dihitung dari unit, kelola lewat /api/admin/alat/{id}/unit")
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = config.AlatCollection.UpdateByID(ctx, objID, bson.M{"$set": update})
+	alat, err := h.akses.cekAlat(ctx, r, objID)
+	if errors.Is(err, errLuarLingkupLab) {
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+		return
+	}
+	sebelum := potret(alat)
+	if req.LabID != nil {
+		if !h.cekLabTujuan(ctx, w, r, req.LabID) {
+			return
+		}
+		alat.LabID = req.LabID
+	}
+
+	alat.Nama = req.Nama
+	alat.Kategori = req.Kategori
+	alat.Deskripsi = req.Deskripsi
+	alat.UpdatedAt = time.Now()
+
+	if req.NilaiBarang > 0 {
+		alat.NilaiBarang = req.NilaiBarang
+	}
+	if req.KebijakanDenda != nil {
+		alat.KebijakanDenda = req.KebijakanDenda
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.alat.Update(ctx, alat); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditAlatUpdate, "alat", alat.ID, sebelum, alat)
+	})
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate alat")
 		return
@@ -160,20 +406,134 @@ func (h *AlatHandler) UpdateAlat(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// DeleteAlat (admin) menghapus alat
+// alatDipakaiError dikembalikan saat alat yang akan dihapus masih punya
+// peminjaman atau reservasi berjalan
+type alatDipakaiError struct {
+	peminjaman int
+	reservasi  int
+}
+
+func (e *alatDipakaiError) Error() string {
+	return fmt.Sprintf("Alat masih dipakai %d peminjaman dan %d reservasi aktif, "+
+		"selesaikan dulu atau hapus dengan ?force=true", e.peminjaman, e.reservasi)
+}
+
+// statusPeminjamanBerjalan adalah status transaksi yang menahan penghapusan alat
+var statusPeminjamanBerjalan = []string{models.StatusDiajukan, models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali}
+
+// DeleteAlat (admin) menghapus alat dari katalog (soft delete). Alat yang
+// masih punya peminjaman atau reservasi berjalan ditolak dengan 409, kecuali
+// ?force=true: pengajuan dan reservasi aktif dibatalkan, sedangkan alat yang
+// sudah diambil tetap bisa dikembalikan. Alat bisa dipulihkan sampai masa
+// retensi habis.
 func (h *AlatHandler) DeleteAlat(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "id")
-	objID, err := primitive.ObjectIDFromHex(idParam)
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
 		return
 	}
+	force := false
+	if s := r.URL.Query().Get("force"); s != "" {
+		if force, err = strconv.ParseBool(s); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "force harus true atau false")
+			return
+		}
+	}
+	aktor, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = config.AlatCollection.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
+	var dibatalkan, reservasiDibatalkan int
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		dibatalkan, reservasiDibatalkan = 0, 0
+		// Kunci alat supaya tidak ada persetujuan atau reservasi baru di
+		// tengah penghapusan
+		if err := h.alat.Kunci(ctx, objID); err != nil {
+			return err
+		}
+		alat, err := h.akses.cekAlat(ctx, r, objID)
+		if err != nil {
+			return err
+		}
+
+		berjalan, _, err := h.transaksi.List(ctx, repository.TransactionFilter{
+			AlatID: &objID,
+			Status: statusPeminjamanBerjalan,
+		}, repository.ListOptions{})
+		if err != nil {
+			return err
+		}
+		reservasi, _, err := h.reservasi.List(ctx, repository.ReservasiFilter{
+			AlatID: &objID,
+			Status: models.ReservasiAktif,
+		}, repository.ListOptions{})
+		if err != nil {
+			return err
+		}
+		if !force && (len(berjalan) > 0 || len(reservasi) > 0) {
+			return &alatDipakaiError{peminjaman: len(berjalan), reservasi: len(reservasi)}
+		}
+
+		now := time.Now()
+		diproses := map[primitive.ObjectID]bool{}
+		for i := range berjalan {
+			if diproses[berjalan[i].ID] {
+				continue
+			}
+			grup, err := h.grupDibatalkan(ctx, r, &berjalan[i])
+			if err != nil {
+				return err
+			}
+			for j := range grup {
+				trans := &grup[j]
+				diproses[trans.ID] = true
+				if err := h.batalkanPeminjaman(ctx, r, trans, aktor, now); err != nil {
+					return err
+				}
+				if trans.Status == models.StatusDibatalkan {
+					dibatalkan++
+				}
+			}
+		}
+		for i := range reservasi {
+			res := &reservasi[i]
+			sebelum := potret(res)
+			res.Status = models.ReservasiDibatalkan
+			res.UpdatedAt = now
+			if err := h.reservasi.Update(ctx, res); err != nil {
+				return err
+			}
+			if err := catatPerubahan(ctx, h.audit, r, models.AuditReservasiBatal, "reservasi", res.ID, sebelum, res); err != nil {
+				return err
+			}
+			reservasiDibatalkan++
+		}
+
+		sebelum := potret(alat)
+		if err := h.alat.Hapus(ctx, objID, now); err != nil {
+			return err
+		}
+		alat.DeletedAt = &now
+		alat.UpdatedAt = now
+		return catatPerubahan(ctx, h.audit, r, models.AuditAlatDelete, "alat", objID, sebelum, alat)
+	})
+	var dipakai *alatDipakaiError
+	switch {
+	case errors.As(err, &dipakai):
+		utils.WriteError(w, http.StatusConflict, dipakai.Error())
+		return
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+		return
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+		return
+	case err != nil:
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus alat")
 		return
 	}
@@ -181,5 +541,247 @@ func (h *AlatHandler) DeleteAlat(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
 		Message: "Alat berhasil dihapus",
+		Data: map[string]int{
+			"peminjaman_dibatalkan": dibatalkan,
+			"reservasi_dibatalkan":  reservasiDibatalkan,
+		},
+	})
+}
+
+// grupDibatalkan mengembalikan transaksi yang ikut dibatalkan bersama
+// trans. Seperti transisi, pembatalan peminjaman multi-item berlaku untuk
+// semua item grup yang berstatus sama, termasuk item alat lain. Item alat
+// di luar lingkup lab staff menolak seluruh penghapusan.
+func (h *AlatHandler) grupDibatalkan(ctx context.Context, r *http.Request, trans *models.Transaction) ([]models.Transaction, error) {
+	if trans.GrupID == nil || !models.BolehTransisi(trans.Status, models.StatusDibatalkan) {
+		return []models.Transaction{*trans}, nil
+	}
+	grup, _, err := h.transaksi.List(ctx, repository.TransactionFilter{
+		GrupID: trans.GrupID,
+		Status: []string{trans.Status},
+	}, repository.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range grup {
+		if item.AlatID == trans.AlatID {
+			continue
+		}
+		if err := h.akses.cekAlatTransaksi(ctx, r, item.AlatID); err != nil {
+			return nil, err
+		}
+	}
+	return grup, nil
+}
+
+// batalkanPeminjaman membatalkan pengajuan atau peminjaman yang belum
+// diambil karena alatnya dihapus, lalu melepas unit yang sudah dipesan.
+// Peminjaman yang sudah diambil dibiarkan supaya tetap bisa dikembalikan.
+func (h *AlatHandler) batalkanPeminjaman(ctx context.Context, r *http.Request, trans *models.Transaction, aktor primitive.ObjectID, now time.Time) error {
+	if !models.BolehTransisi(trans.Status, models.StatusDibatalkan) {
+		return nil
+	}
+	sebelum := potret(trans)
+	if models.MenahanStok(trans.Status) {
+		awal, err := h.buku.saldo(ctx, trans.AlatID)
+		if err != nil {
+			return err
+		}
+		if err := h.unit.Kembalikan(ctx, trans.UnitIDs); err != nil {
+			return err
+		}
+		_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+			Jenis:         models.MutasiKembali,
+			UnitIDs:       trans.UnitIDs,
+			TransactionID: &trans.ID,
+			Keterangan:    "Peminjaman dibatalkan karena alat dihapus",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	trans.UbahStatus(models.StatusDibatalkan, aktor, now, "Alat dihapus")
+	if err := h.transaksi.Update(ctx, trans); err != nil {
+		return err
+	}
+	return catatPerubahan(ctx, h.audit, r, models.AuditPeminjamanBatal, "transactions", trans.ID, sebelum, trans)
+}
+
+// ListAlatDihapus (admin) menampilkan alat yang sudah dihapus dan masih
+// bisa dipulihkan. Query: lab_id, page, limit, sort.
+func (h *AlatHandler) ListAlatDihapus(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "nama", "kategori", "created_at")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	labID, err := parseObjectIDQuery(r, "lab_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	l, err := h.akses.lingkup(ctx, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa lingkup lab")
+		return
+	}
+	filter := repository.AlatFilter{HanyaDihapus: true}
+	switch {
+	case labID != nil && !l.boleh(labID):
+		filter.LabIDs = []primitive.ObjectID{}
+	case labID != nil:
+		filter.LabIDs = []primitive.ObjectID{*labID}
+	case !l.semua:
+		filter.LabIDs = l.lab
+	}
+
+	alatList, total, err := h.alat.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+		return
+	}
+	writeList(w, alatList, opts, total)
+}
+
+// PulihkanAlat (admin) mengembalikan alat yang dihapus ke katalog
+func (h *AlatHandler) PulihkanAlat(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var alat *models.Alat
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		alat, err = h.alat.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		// cekAlat menolak alat yang dihapus, jadi lingkup lab dicek langsung
+		l, err := h.akses.lingkup(ctx, r)
+		if err != nil {
+			return err
+		}
+		if !l.boleh(alat.LabID) {
+			return errLuarLingkupLab
+		}
+		if !alat.Dihapus() {
+			return errAlatTidakDihapus
+		}
+		sebelum := potret(alat)
+		if err := h.alat.Pulihkan(ctx, objID); err != nil {
+			return err
+		}
+		alat.DeletedAt = nil
+		alat.UpdatedAt = time.Now()
+		return catatPerubahan(ctx, h.audit, r, models.AuditAlatRestore, "alat", objID, sebelum, alat)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan atau sudah dihapus permanen")
+		return
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+		return
+	case errors.Is(err, errAlatTidakDihapus):
+		utils.WriteError(w, http.StatusBadRequest, "Alat tidak sedang dihapus")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memulihkan alat")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Alat berhasil dipulihkan",
+		Data:    alat,
+	})
+}
+
+// Ketersediaan menampilkan kalender ketersediaan alat per slot waktu.
+// Query: from, to (RFC3339 / YYYY-MM-DD), slot (jam, default 24).
+func (h *AlatHandler) Ketersediaan(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	q := r.URL.Query()
+	dari := time.Now()
+	if s := q.Get("from"); s != "" {
+		if dari, err = utils.ParseWaktu(s, false); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "from tidak valid: "+err.Error())
+			return
+		}
+	}
+	sampai := dari.AddDate(0, 0, 7)
+	if s := q.Get("to"); s != "" {
+		if sampai, err = utils.ParseWaktu(s, true); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "to tidak valid: "+err.Error())
+			return
+		}
+	}
+	if !sampai.After(dari) {
+		utils.WriteError(w, http.StatusBadRequest, "to harus setelah from")
+		return
+	}
+
+	slotJam := 24
+	if s := q.Get("slot"); s != "" {
+		if slotJam, err = strconv.Atoi(s); err != nil || slotJam <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "slot harus bilangan jam > 0")
+			return
+		}
+	}
+	slot := time.Duration(slotJam) * time.Hour
+	if sampai.Sub(dari)/slot > maksSlotKetersediaan {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Maksimal %d slot per request", maksSlotKetersediaan))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alat, err := h.alat.FindByID(ctx, objID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && alat.Dihapus()) {
+		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+		return
+	}
+
+	pemakaian, err := h.ketersediaan.pemakaian(ctx, objID, dari, sampai, nil, nil)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghitung ketersediaan alat")
+		return
+	}
+
+	slots := []SlotKetersediaan{}
+	for mulai := dari; mulai.Before(sampai); mulai = mulai.Add(slot) {
+		selesai := mulai.Add(slot)
+		if selesai.After(sampai) {
+			selesai = sampai
+		}
+		tersedia := alat.StokTotal - models.PemakaianPuncak(pemakaian, mulai, selesai)
+		if tersedia < 0 {
+			tersedia = 0
+		}
+		slots = append(slots, SlotKetersediaan{Mulai: mulai, Selesai: selesai, Tersedia: tersedia})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    slots,
 	})
 }
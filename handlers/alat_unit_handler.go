@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errUnitDipinjam dikembalikan jika status unit diubah saat masih dipinjam
+var errUnitDipinjam = errors.New("unit sedang dipinjam")
+
+// Request body unit alat
+type unitRequest struct {
+	KodeAset  string `json:"kode_aset"`
+	NomorSeri string `json:"nomor_seri,omitempty"`
+	Kondisi   string `json:"kondisi,omitempty"`
+}
+
+// unit membuat AlatUnit TERSEDIA dari request
+func (req unitRequest) unit(alatID primitive.ObjectID, now time.Time) models.AlatUnit {
+	return models.AlatUnit{
+		ID:        primitive.NewObjectID(),
+		AlatID:    alatID,
+		KodeAset:  strings.TrimSpace(req.KodeAset),
+		NomorSeri: strings.TrimSpace(req.NomorSeri),
+		Kondisi:   req.Kondisi,
+		Status:    models.UnitTersedia,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Request body update unit. Field kosong tidak diubah.
+type updateUnitRequest struct {
+	KodeAset  string `json:"kode_aset,omitempty"`
+	NomorSeri string `json:"nomor_seri,omitempty"`
+	Kondisi   string `json:"kondisi,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+// ListUnit (admin) menampilkan semua unit fisik sebuah alat
+func (h *AlatHandler) ListUnit(w http.ResponseWriter, r *http.Request) {
+	alatID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.akses.cekAlat(ctx, r, alatID); err != nil {
+		writeUnitError(w, err, "Gagal mengambil data unit")
+		return
+	}
+
+	units, err := h.unit.ListByAlat(ctx, alatID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data unit")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    units,
+	})
+}
+
+// TambahUnit (admin) menambah unit fisik baru ke sebuah alat
+func (h *AlatHandler) TambahUnit(w http.ResponseWriter, r *http.Request) {
+	alatID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req unitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if strings.TrimSpace(req.KodeAset) == "" {
+		utils.WriteError(w, http.StatusBadRequest, "kode_aset wajib")
+		return
+	}
+	if req.Kondisi == "" {
+		req.Kondisi = models.KondisiBaik
+	}
+	if !models.KondisiValid(req.Kondisi) {
+		utils.WriteError(w, http.StatusBadRequest, "kondisi unit tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unit := req.unit(alatID, time.Now())
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		alat, err := h.akses.cekAlat(ctx, r, alatID)
+		if err != nil {
+			return err
+		}
+		if err := h.unit.Create(ctx, &unit); err != nil {
+			return err
+		}
+		awal := saldoStok{alatID: alatID, total: alat.StokTotal, tersedia: alat.StokTersedia}
+		_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+			Jenis:      models.MutasiPembelian,
+			UnitIDs:    []primitive.ObjectID{unit.ID},
+			Keterangan: "Unit baru " + unit.KodeAset,
+		})
+		if err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditUnitCreate, "alat_units", unit.ID, nil, unit)
+	})
+	if err != nil {
+		writeUnitError(w, err, "Gagal menambah unit")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Unit berhasil ditambahkan",
+		Data:    unit,
+	})
+}
+
+// UpdateUnit (admin) mengubah kode aset, nomor seri, kondisi atau status unit.
+// Unit yang sedang dipinjam tidak bisa diubah statusnya.
+func (h *AlatHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	unitID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID unit tidak valid")
+		return
+	}
+
+	var req updateUnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Kondisi != "" && !models.KondisiValid(req.Kondisi) {
+		utils.WriteError(w, http.StatusBadRequest, "kondisi unit tidak valid")
+		return
+	}
+	switch req.Status {
+	case "", models.UnitTersedia, models.UnitPerbaikan, models.UnitDihapus:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "status hanya boleh TERSEDIA, PERBAIKAN atau DIHAPUS")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unit *models.AlatUnit
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		unit, err = h.unit.FindByID(ctx, unitID)
+		if err != nil {
+			return err
+		}
+		alat, err := h.akses.cekAlat(ctx, r, unit.AlatID)
+		if err != nil {
+			return err
+		}
+		if req.Status != "" && unit.Status == models.UnitDipinjam {
+			return errUnitDipinjam
+		}
+		sebelum := potret(unit)
+		statusLama := unit.Status
+
+		if kode := strings.TrimSpace(req.KodeAset); kode != "" {
+			unit.KodeAset = kode
+		}
+		if seri := strings.TrimSpace(req.NomorSeri); seri != "" {
+			unit.NomorSeri = seri
+		}
+		if req.Kondisi != "" {
+			unit.Kondisi = req.Kondisi
+		}
+		if req.Status != "" {
+			unit.Status = req.Status
+		}
+		unit.UpdatedAt = time.Now()
+		if err := h.unit.Update(ctx, unit); err != nil {
+			return err
+		}
+		if unit.Status != statusLama {
+			awal := saldoStok{alatID: alat.ID, total: alat.StokTotal, tersedia: alat.StokTersedia}
+			if _, err := h.buku.catat(ctx, r, awal, mutasiUnit(unit, statusLama)); err != nil {
+				return err
+			}
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditUnitUpdate, "alat_units", unit.ID, sebelum, unit)
+	})
+	if err != nil {
+		writeUnitError(w, err, "Gagal mengupdate unit")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Unit berhasil diupdate",
+		Data:    unit,
+	})
+}
+
+// writeUnitError menerjemahkan error pengelolaan unit menjadi response HTTP
+func writeUnitError(w http.ResponseWriter, err error, pesanGagal string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Alat atau unit tidak ditemukan")
+	case errors.Is(err, repository.ErrDuplikat):
+		utils.WriteError(w, http.StatusConflict, "Kode aset sudah dipakai unit lain")
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+	case errors.Is(err, errUnitDipinjam):
+		utils.WriteError(w, http.StatusBadRequest, "Unit sedang dipinjam, kembalikan dulu sebelum mengubah status")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, pesanGagal)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// statusPeminjamanAktif adalah status transaksi yang dihitung kuota aturan
+// peminjaman, dari pengajuan sampai alat kembali seluruhnya
+var statusPeminjamanAktif = []string{
+	models.StatusDiajukan,
+	models.StatusDisetujui,
+	models.StatusDiambil,
+	models.StatusSebagianKembali,
+}
+
+// penolakan adalah alasan pengajuan ditolak oleh aturan peminjaman. Dipakai
+// sebagai error supaya bisa membatalkan transaksi database.
+type penolakan struct {
+	aturan *models.AturanPeminjaman
+	pesan  string
+}
+
+func (p *penolakan) Error() string {
+	return p.pesan
+}
+
+// aturanPeminjaman memeriksa pengajuan peminjaman terhadap aturan kuota dan
+// kelayakan yang diatur admin
+type aturanPeminjaman struct {
+	aturan    repository.AturanRepository
+	transaksi repository.TransactionRepository
+	users     repository.UserRepository
+}
+
+func newAturanPeminjaman(store *repository.Store) aturanPeminjaman {
+	return aturanPeminjaman{aturan: store.Aturan, transaksi: store.Transactions, users: store.Users}
+}
+
+// periksa mengembalikan *penolakan dari aturan pertama yang dilanggar, atau
+// nil jika peminjaman boleh dibuat. Harus dipanggil di dalam WithTransaction
+// yang juga menyimpan peminjamannya: user dikunci dulu supaya dua pengajuan
+// paralel tidak bisa sama-sama lolos kuota.
+func (a aturanPeminjaman) periksa(ctx context.Context, user *models.User, items []itemPeminjaman) error {
+	semua, err := a.aturan.List(ctx)
+	if err != nil {
+		return err
+	}
+	var berlaku []models.AturanPeminjaman
+	for _, aturan := range semua {
+		if aturan.BerlakuUntuk(user) {
+			berlaku = append(berlaku, aturan)
+		}
+	}
+	if len(berlaku) == 0 {
+		return nil
+	}
+
+	if err := a.users.Kunci(ctx, user.ID); err != nil {
+		return err
+	}
+	aktif, _, err := a.transaksi.List(ctx, repository.TransactionFilter{
+		UserID: &user.ID,
+		Status: statusPeminjamanAktif,
+	}, repository.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range berlaku {
+		aturan := &berlaku[i]
+		switch aturan.Jenis {
+		case models.AturanMaksPeminjamanAktif:
+			// Item satu peminjaman multi-item dihitung sebagai satu peminjaman
+			peminjaman := map[primitive.ObjectID]bool{}
+			for _, t := range aktif {
+				if t.GrupID != nil {
+					peminjaman[*t.GrupID] = true
+				} else {
+					peminjaman[t.ID] = true
+				}
+			}
+			if len(peminjaman)+1 > aturan.Batas {
+				return &penolakan{aturan, fmt.Sprintf(
+					"Maksimal %d peminjaman aktif, saat ini sudah %d", aturan.Batas, len(peminjaman))}
+			}
+
+		case models.AturanMaksJumlahAlat:
+			for _, item := range items {
+				if !aturan.CocokKategori(item.alat.Kategori) {
+					continue
+				}
+				jumlah := item.jumlah
+				for _, t := range aktif {
+					if t.AlatID == item.alat.ID {
+						jumlah += t.SisaPinjam()
+					}
+				}
+				if jumlah > aturan.Batas {
+					return &penolakan{aturan, fmt.Sprintf(
+						"Maksimal memegang %d unit %s sekaligus", aturan.Batas, item.alat.Nama)}
+				}
+			}
+
+		case models.AturanKategoriTerbatas:
+			for _, item := range items {
+				if item.alat.Kategori == aturan.Kategori && !aturan.Mengizinkan(user) {
+					return &penolakan{aturan, fmt.Sprintf(
+						"Alat kategori %s hanya boleh dipinjam role atau jurusan tertentu", aturan.Kategori)}
+				}
+			}
+
+		case models.AturanBlokirTerlambat:
+			for _, t := range aktif {
+				if t.Terlambat(now) {
+					return &penolakan{aturan,
+						"Masih ada peminjaman yang lewat jatuh tempo, kembalikan dulu sebelum meminjam lagi"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writePenolakan menulis response 403 dengan jenis aturan sebagai kode alasan
+func writePenolakan(w http.ResponseWriter, p *penolakan) {
+	utils.WriteJSON(w, http.StatusForbidden, utils.JSONResponse{
+		Success: false,
+		Message: p.pesan,
+		Kode:    p.aturan.Jenis,
+		Data: map[string]interface{}{
+			"aturan_id": p.aturan.ID,
+			"aturan":    p.aturan.Nama,
+		},
+	})
+}
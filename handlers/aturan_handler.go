@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AturanHandler mengelola aturan kuota & kelayakan peminjaman
+type AturanHandler struct {
+	aturan  repository.AturanRepository
+	roles   repository.RoleRepository
+	jurusan repository.JurusanRepository
+	audit   repository.AuditRepository
+	tx      repository.Transactor
+}
+
+// NewAturanHandler membuat AturanHandler dari repository di store
+func NewAturanHandler(store *repository.Store) *AturanHandler {
+	return &AturanHandler{
+		aturan:  store.Aturan,
+		roles:   store.Roles,
+		jurusan: store.Jurusan,
+		audit:   store.Audit,
+		tx:      store.Tx,
+	}
+}
+
+// Request body untuk membuat/mengganti aturan. Update mengganti seluruh isi
+// aturan, jadi field yang tidak dikirim ikut dikosongkan.
+type aturanRequest struct {
+	Nama             string   `json:"nama"`
+	Jenis            string   `json:"jenis"`
+	Role             []string `json:"role,omitempty"`
+	Batas            int      `json:"batas,omitempty"`
+	Kategori         string   `json:"kategori,omitempty"`
+	RoleDiizinkan    []string `json:"role_diizinkan,omitempty"`
+	JurusanDiizinkan []string `json:"jurusan_diizinkan,omitempty"`
+	// Aktif kosong berarti true
+	Aktif *bool `json:"aktif,omitempty"`
+}
+
+// bacaAturan memvalidasi request dan mengisi field aturan. Pesan tidak
+// kosong berarti request ditolak dengan 400.
+func (h *AturanHandler) bacaAturan(ctx context.Context, req aturanRequest, aturan *models.AturanPeminjaman) (string, error) {
+	req.Nama = strings.TrimSpace(req.Nama)
+	req.Kategori = strings.TrimSpace(req.Kategori)
+	if req.Nama == "" {
+		return "Nama aturan wajib diisi", nil
+	}
+	if !models.JenisAturanValid(req.Jenis) {
+		return "jenis harus MAKS_PEMINJAMAN_AKTIF, MAKS_JUMLAH_ALAT, KATEGORI_TERBATAS atau BLOKIR_TERLAMBAT", nil
+	}
+
+	switch req.Jenis {
+	case models.AturanMaksPeminjamanAktif, models.AturanMaksJumlahAlat:
+		if req.Batas <= 0 {
+			return "batas wajib diisi dan > 0", nil
+		}
+	case models.AturanKategoriTerbatas:
+		if req.Kategori == "" {
+			return "kategori wajib diisi untuk KATEGORI_TERBATAS", nil
+		}
+		if len(req.RoleDiizinkan) == 0 && len(req.JurusanDiizinkan) == 0 {
+			return "Isi role_diizinkan atau jurusan_diizinkan untuk KATEGORI_TERBATAS", nil
+		}
+	}
+
+	role, pesan, err := h.validasiRole(ctx, req.Role)
+	if pesan != "" || err != nil {
+		return pesan, err
+	}
+	roleDiizinkan, pesan, err := h.validasiRole(ctx, req.RoleDiizinkan)
+	if pesan != "" || err != nil {
+		return pesan, err
+	}
+	var jurusanDiizinkan []string
+	for _, nama := range req.JurusanDiizinkan {
+		jurusan, err := h.jurusan.FindByNama(ctx, strings.TrimSpace(nama))
+		if errors.Is(err, repository.ErrNotFound) {
+			return "Jurusan tidak terdaftar: " + nama, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		jurusanDiizinkan = append(jurusanDiizinkan, jurusan.Nama)
+	}
+
+	aturan.Nama = req.Nama
+	aturan.Jenis = req.Jenis
+	aturan.Role = role
+	aturan.Batas = 0
+	aturan.Kategori = ""
+	aturan.RoleDiizinkan = nil
+	aturan.JurusanDiizinkan = nil
+	// Field yang tidak dipakai jenis aturan tidak disimpan
+	switch req.Jenis {
+	case models.AturanMaksPeminjamanAktif:
+		aturan.Batas = req.Batas
+	case models.AturanMaksJumlahAlat:
+		aturan.Batas = req.Batas
+		aturan.Kategori = req.Kategori
+	case models.AturanKategoriTerbatas:
+		aturan.Kategori = req.Kategori
+		aturan.RoleDiizinkan = roleDiizinkan
+		aturan.JurusanDiizinkan = jurusanDiizinkan
+	}
+	aturan.Aktif = req.Aktif == nil || *req.Aktif
+	return "", nil
+}
+
+// validasiRole memastikan semua nama role terdaftar
+func (h *AturanHandler) validasiRole(ctx context.Context, nama []string) ([]string, string, error) {
+	var hasil []string
+	for _, n := range nama {
+		n = strings.TrimSpace(n)
+		if _, err := h.roles.FindByNama(ctx, n); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, "Role tidak dikenal: " + n, nil
+			}
+			return nil, "", err
+		}
+		hasil = append(hasil, n)
+	}
+	return hasil, "", nil
+}
+
+// ListAturan (admin) menampilkan semua aturan peminjaman
+func (h *AturanHandler) ListAturan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.aturan.List(ctx)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data aturan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// CreateAturan (admin) menambah aturan peminjaman baru
+func (h *AturanHandler) CreateAturan(w http.ResponseWriter, r *http.Request) {
+	var req aturanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	aturan := models.AturanPeminjaman{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	pesan, err := h.bacaAturan(ctx, req, &aturan)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memvalidasi aturan")
+		return
+	}
+	if pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
+		return
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.aturan.Create(ctx, &aturan); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditAturanCreate, "aturan_peminjaman", aturan.ID, nil, aturan)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan aturan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Aturan berhasil ditambahkan",
+		Data:    aturan,
+	})
+}
+
+// UpdateAturan (admin) mengganti isi aturan peminjaman
+func (h *AturanHandler) UpdateAturan(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req aturanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	aturan, err := h.aturan.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Aturan tidak ditemukan")
+		return
+	}
+	sebelum := potret(aturan)
+
+	pesan, err := h.bacaAturan(ctx, req, aturan)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memvalidasi aturan")
+		return
+	}
+	if pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
+		return
+	}
+	aturan.UpdatedAt = time.Now()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.aturan.Update(ctx, aturan); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditAturanUpdate, "aturan_peminjaman", aturan.ID, sebelum, aturan)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate aturan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Aturan berhasil diupdate",
+		Data:    aturan,
+	})
+}
+
+// DeleteAturan (admin) menghapus aturan peminjaman
+func (h *AturanHandler) DeleteAturan(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		aturan, err := h.aturan.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		if err := h.aturan.Delete(ctx, objID); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditAturanDelete, "aturan_peminjaman", objID, potret(aturan), nil)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Aturan tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus aturan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Aturan berhasil dihapus",
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// catatAudit menyimpan audit log untuk perubahan yang dilakukan user yang
+// sedang login. Panggil di dalam transaksi yang sama dengan perubahannya
+// supaya perubahan tanpa catatan tidak pernah tersimpan.
+func catatAudit(ctx context.Context, repo repository.AuditRepository, r *http.Request, aksi, target string, targetID primitive.ObjectID, sebelum, sesudah map[string]interface{}) error {
+	aktorID, _ := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	return catatAuditOleh(ctx, repo, r, aktorID, aksi, target, targetID, sebelum, sesudah)
+}
+
+// catatAuditOleh seperti catatAudit untuk endpoint tanpa login (registrasi,
+// verifikasi email, reset password), dengan aktor user pemilik akunnya
+func catatAuditOleh(ctx context.Context, repo repository.AuditRepository, r *http.Request, aktorID primitive.ObjectID, aksi, target string, targetID primitive.ObjectID, sebelum, sesudah map[string]interface{}) error {
+	return repo.Catat(ctx, &models.AuditLog{
+		ID:        primitive.NewObjectID(),
+		AktorID:   aktorID,
+		Aksi:      aksi,
+		Target:    target,
+		TargetID:  targetID,
+		Sebelum:   sebelum,
+		Sesudah:   sesudah,
+		IP:        r.RemoteAddr,
+		RequestID: chimw.GetReqID(r.Context()),
+		Waktu:     time.Now(),
+	})
+}
+
+// potret menyalin data menjadi map berisi field JSON-nya, dipakai untuk
+// menyimpan keadaan data sebelum diubah. Field rahasia (json:"-") tidak
+// ikut tersalin. nil menghasilkan nil.
+func potret(v interface{}) map[string]interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// selisih mengambil field yang berbeda antara dua potret. updated_at tidak
+// dibandingkan karena selalu berubah.
+func selisih(sebelum, sesudah map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	lama := map[string]interface{}{}
+	baru := map[string]interface{}{}
+	for k, v := range sebelum {
+		if k == "updated_at" {
+			continue
+		}
+		if w, ok := sesudah[k]; !ok || !reflect.DeepEqual(v, w) {
+			lama[k] = v
+		}
+	}
+	for k, v := range sesudah {
+		if k == "updated_at" {
+			continue
+		}
+		if w, ok := sebelum[k]; !ok || !reflect.DeepEqual(v, w) {
+			baru[k] = v
+		}
+	}
+	if len(lama) == 0 {
+		lama = nil
+	}
+	if len(baru) == 0 {
+		baru = nil
+	}
+	return lama, baru
+}
+
+// catatPerubahan mencatat audit log dari potret data sebelum diubah dan
+// data sesudahnya. sebelum nil berarti data baru dibuat, sesudah nil berarti
+// data dihapus.
+func catatPerubahan(ctx context.Context, repo repository.AuditRepository, r *http.Request, aksi, target string, targetID primitive.ObjectID, sebelum map[string]interface{}, sesudah interface{}) error {
+	lama, baru := selisih(sebelum, potret(sesudah))
+	return catatAudit(ctx, repo, r, aksi, target, targetID, lama, baru)
+}
+
+// AuditHandler menampilkan audit log untuk admin
+type AuditHandler struct {
+	audit repository.AuditRepository
+}
+
+// NewAuditHandler membuat AuditHandler dari repository di store
+func NewAuditHandler(store *repository.Store) *AuditHandler {
+	return &AuditHandler{audit: store.Audit}
+}
+
+// ListAudit (admin) menampilkan audit log dari yang terbaru.
+// Query: aktor_id, aksi, target, target_id, from, to, page, limit, sort.
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "waktu")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	aktorID, err := parseObjectIDQuery(r, "aktor_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	targetID, err := parseObjectIDQuery(r, "target_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dari, sampai, err := parseRentangQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.AuditFilter{
+		AktorID:  aktorID,
+		Aksi:     strings.TrimSpace(q.Get("aksi")),
+		Target:   strings.TrimSpace(q.Get("target")),
+		TargetID: targetID,
+		Dari:     dari,
+		Sampai:   sampai,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, total, err := h.audit.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil audit log")
+		return
+	}
+
+	writeList(w, list, opts, total)
+}
@@ -2,30 +2,60 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"SIPAK/config"
+	"SIPAK/mail"
+	"SIPAK/middleware"
 	"SIPAK/models"
+	"SIPAK/repository"
 	"SIPAK/utils"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// AuthHandler menampung method untuk auth (login, register)
-type AuthHandler struct{}
+// AuthHandler menampung method untuk auth (login, register, session)
+type AuthHandler struct {
+	users    repository.UserRepository
+	jurusan  repository.JurusanRepository
+	sessions repository.SessionRepository
+	resets   repository.PasswordResetRepository
+	audit    repository.AuditRepository
+	tx       repository.Transactor
+	mailer   mail.Mailer
+}
+
+// NewAuthHandler membuat AuthHandler dari repository di store. mailer
+// dipakai untuk mengirim token reset password.
+func NewAuthHandler(store *repository.Store, mailer mail.Mailer) *AuthHandler {
+	return &AuthHandler{
+		users:    store.Users,
+		jurusan:  store.Jurusan,
+		sessions: store.Sessions,
+		resets:   store.Resets,
+		audit:    store.Audit,
+		tx:       store.Tx,
+		mailer:   mailer,
+	}
+}
 
 // Request body untuk register
 type registerRequest struct {
 	Nama     string `json:"nama"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	NIM	  	 string `json:"nim,omitempty"`
-	Jurusan  string `json:"jurusan,omitempty"`
+	NIM      string `json:"nim"`
+	Jurusan  string `json:"jurusan"`
 }
 
 // Request body untuk login
@@ -34,6 +64,59 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+// Request body untuk refresh token
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenResponse adalah pasangan token yang dikirim saat login dan refresh
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn adalah umur access token dalam detik
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// buatTokenAcak membuat token acak 256 bit dalam bentuk hex
+func buatTokenAcak() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// buatRefreshToken membuat refresh token berformat <session_id>.<rahasia>
+// beserta hash rahasianya untuk disimpan di session
+func buatRefreshToken(sessionID primitive.ObjectID) (token, hash string, err error) {
+	rahasia, err := buatTokenAcak()
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID.Hex() + "." + rahasia, hashToken(rahasia), nil
+}
+
+// hashToken menghitung hash SHA-256 token acak. Cukup tanpa salt karena
+// token sudah 256 bit acak, berbeda dengan password.
+func hashToken(rahasia string) string {
+	sum := sha256.Sum256([]byte(rahasia))
+	return hex.EncodeToString(sum[:])
+}
+
+// terbitkanToken membuat access token baru dengan jti acak untuk user
+func terbitkanToken(user *models.User, refreshToken string) (tokenResponse, string, error) {
+	jti := primitive.NewObjectID().Hex()
+	token, err := utils.GenerateToken(user.ID.Hex(), user.Role, jti)
+	if err != nil {
+		return tokenResponse{}, "", err
+	}
+	return tokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.AppConfig.AccessTokenTTL / time.Second),
+	}, jti, nil
+}
+
 // Register membuat user baru (default role: mahasiswa)
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
@@ -44,22 +127,62 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	req.NIM = strings.ToUpper(strings.TrimSpace(req.NIM))
+	req.Jurusan = strings.TrimSpace(req.Jurusan)
 
 	if req.Email == "" || req.Password == "" || req.Nama == "" {
 		utils.WriteError(w, http.StatusBadRequest, "Nama, email, dan password wajib diisi")
 		return
 	}
+	if req.NIM != "" && config.AppConfig.FormatNIM != nil && !config.AppConfig.FormatNIM.MatchString(req.NIM) {
+		utils.WriteError(w, http.StatusBadRequest, "Format NIM tidak valid")
+		return
+	}
+	if !domainEmailDiizinkan(req.Email) {
+		utils.WriteError(w, http.StatusBadRequest, "Registrasi hanya untuk email kampus: @"+
+			strings.Join(config.AppConfig.DomainEmailRegistrasi, ", @"))
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
 	// Cek apakah email sudah digunakan
-	var existing models.User
-	err := config.UserCollection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&existing)
+	_, err := h.users.FindByEmail(ctx, req.Email)
 	if err == nil {
 		utils.WriteError(w, http.StatusBadRequest, "Email sudah terdaftar")
 		return
 	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa email")
+		return
+	}
+
+	// NIM dan jurusan opsional, tetapi jika diisi NIM harus unik dan
+	// jurusan harus salah satu dari daftar jurusan
+	if req.NIM != "" {
+		_, err = h.users.FindByNIM(ctx, req.NIM)
+		if err == nil {
+			utils.WriteError(w, http.StatusBadRequest, "NIM sudah terdaftar")
+			return
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa NIM")
+			return
+		}
+	}
+	if req.Jurusan != "" {
+		jurusan, err := h.jurusan.FindByNama(ctx, req.Jurusan)
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusBadRequest, "Jurusan tidak terdaftar")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa jurusan")
+			return
+		}
+		req.Jurusan = jurusan.Nama
+	}
 
 	// Hash password
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -68,27 +191,54 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	now := time.Now()
 	user := models.User{
 		ID:           primitive.NewObjectID(),
 		Nama:         req.Nama,
 		Email:        req.Email,
 		PasswordHash: string(hash),
-		Role:         "mahasiswa",
-		CreatedAt:    time.Now(),
+		Role:         models.RoleMahasiswa,
+		NIM:          req.NIM,
+		Jurusan:      req.Jurusan,
+		CreatedAt:    now,
+	}
+
+	// Akun baru belum terverifikasi sampai token dari email dipakai
+	token, err := siapkanVerifikasi(&user, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token verifikasi")
+		return
 	}
 
-	_, err = config.UserCollection.InsertOne(ctx, user)
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.users.Create(ctx, &user); err != nil {
+			return err
+		}
+		return catatAuditOleh(ctx, h.audit, r, user.ID, models.AuditRegister, "users", user.ID, nil, potret(&user))
+	})
+	if errors.Is(err, repository.ErrDuplikat) {
+		// NIM dipakai registrasi lain yang berjalan bersamaan
+		utils.WriteError(w, http.StatusBadRequest, "NIM sudah terdaftar")
+		return
+	}
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan user")
 		return
 	}
 
+	// Akun tetap dibuat walaupun email gagal terkirim, user bisa minta kirim ulang
+	message := "Registrasi berhasil, cek email untuk verifikasi akun"
+	if err := h.mailer.Kirim(ctx, isiEmailVerifikasi(&user, token)); err != nil {
+		log.Printf("gagal kirim email verifikasi ke %s: %v", user.Email, err)
+		message = "Registrasi berhasil, tetapi email verifikasi gagal dikirim. Silakan minta kirim ulang"
+	}
+
 	// Jangan kembalikan password hash
 	user.PasswordHash = ""
 
 	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
 		Success: true,
-		Message: "Registrasi berhasil",
+		Message: message,
 		Data:    user,
 	})
 }
@@ -112,8 +262,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := config.UserCollection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	user, err := h.users.FindByEmail(ctx, req.Email)
 	if err != nil {
 		utils.WriteError(w, http.StatusUnauthorized, "Email atau password salah")
 		return
@@ -125,24 +274,171 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT
-	token, err := utils.GenerateToken(user.ID.Hex(), user.Role)
+	// Buat session baru beserta access & refresh token
+	now := time.Now()
+	session := models.Session{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IP:        r.RemoteAddr,
+		ExpiresAt: now.Add(config.AppConfig.RefreshTokenTTL),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	refreshToken, refreshHash, err := buatRefreshToken(session.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
+		return
+	}
+	tokens, jti, err := terbitkanToken(user, refreshToken)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
 		return
 	}
+	session.RefreshHash = refreshHash
+	session.AccessJTI = jti
+	if err := h.sessions.Create(ctx, &session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan session")
+		return
+	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
 		Message: "Login berhasil",
 		Data: map[string]interface{}{
-			"token": token,
+			"token":         tokens.Token,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
 			"user": map[string]interface{}{
 				"id":    user.ID.Hex(),
 				"nama":  user.Nama,
 				"email": user.Email,
 				"role":  user.Role,
+
+				"email_terverifikasi": user.EmailTerverifikasi,
 			},
 		},
 	})
 }
+
+// Refresh menukar refresh token dengan access token dan refresh token baru.
+// Refresh token lama langsung tidak berlaku. Jika refresh token yang sudah
+// pernah ditukar dipakai lagi, token itu dianggap bocor dan session dicabut.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	idHex, rahasia, ok := strings.Cut(req.RefreshToken, ".")
+	sessionID, err := primitive.ObjectIDFromHex(idHex)
+	if !ok || err != nil || rahasia == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "Refresh token invalid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := h.sessions.FindByID(ctx, sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusUnauthorized, "Refresh token invalid")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil session")
+		return
+	}
+
+	now := time.Now()
+	if !session.Aktif(now) {
+		utils.WriteError(w, http.StatusUnauthorized, "Session sudah berakhir, silakan login ulang")
+		return
+	}
+	hashLama := hashToken(rahasia)
+	if subtle.ConstantTimeCompare([]byte(hashLama), []byte(session.RefreshHash)) != 1 {
+		// Refresh token bocor atau dipakai ulang, pencabutannya dicatat
+		// supaya bisa ditelusuri
+		err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := h.sessions.Revoke(ctx, session.ID, now); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			return catatAuditOleh(ctx, h.audit, r, session.UserID, models.AuditRevokeSession, "users", session.UserID,
+				nil, map[string]interface{}{"session_id": session.ID, "alasan": "refresh token dipakai ulang"})
+		})
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal mencabut session")
+			return
+		}
+		utils.WriteError(w, http.StatusUnauthorized, "Refresh token sudah pernah dipakai, session dicabut")
+		return
+	}
+
+	// Role diambil ulang supaya perubahan role oleh admin ikut berlaku
+	user, err := h.users.FindByID(ctx, session.UserID)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User tidak ditemukan")
+		return
+	}
+
+	refreshToken, refreshHash, err := buatRefreshToken(session.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
+		return
+	}
+	tokens, jti, err := terbitkanToken(user, refreshToken)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
+		return
+	}
+
+	err = h.sessions.Rotasi(ctx, session.ID, hashLama, refreshHash, jti, now.Add(config.AppConfig.RefreshTokenTTL), now)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Kalah balapan dengan refresh lain yang memakai token yang sama
+		utils.WriteError(w, http.StatusUnauthorized, "Refresh token sudah pernah dipakai")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan session")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Token berhasil diperbarui",
+		Data:    tokens,
+	})
+}
+
+// Logout mencabut session dari access token yang sedang dipakai. Access
+// token dan refresh token session ini langsung tidak berlaku.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := primitive.ObjectIDFromHex(middleware.GetSessionIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "Session invalid di token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID, _ := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.sessions.Revoke(ctx, sessionID, time.Now()); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditLogout, "users", userID,
+			nil, map[string]interface{}{"session_id": sessionID})
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal logout")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Logout berhasil",
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BerkasHandler menyajikan file upload (avatar dan foto pengembalian)
+// hanya untuk pemiliknya atau staff yang berwenang
+type BerkasHandler struct {
+	users     repository.UserRepository
+	transaksi repository.TransactionRepository
+	roles     repository.RoleRepository
+	alat      repository.AlatRepository
+	files     storage.Storage
+	baseURL   string
+
+	akses aksesLab
+}
+
+// NewBerkasHandler membuat BerkasHandler untuk file yang disajikan di baseURL
+func NewBerkasHandler(store *repository.Store, files storage.Storage, baseURL string) *BerkasHandler {
+	return &BerkasHandler{
+		users:     store.Users,
+		transaksi: store.Transactions,
+		roles:     store.Roles,
+		alat:      store.Alat,
+		files:     files,
+		baseURL:   baseURL,
+		akses:     newAksesLab(store),
+	}
+}
+
+// Permission staff yang boleh melihat file milik user lain
+var (
+	permLihatAvatar      = []string{models.PermUserManage, models.PermPeminjamanRead}
+	permLihatFotoKembali = []string{models.PermPeminjamanRead, models.PermPeminjamanHandover, models.PermKasusManage}
+)
+
+func punyaSalahSatu(role *models.Role, perms []string) bool {
+	for _, p := range perms {
+		if role.Punya(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// AmbilBerkas menyajikan satu file. File yang tidak dipakai data mana pun
+// atau tidak boleh dilihat user dijawab 404 yang sama, supaya keberadaan
+// file tidak bocor.
+func (h *BerkasHandler) AmbilBerkas(w http.ResponseWriter, r *http.Request) {
+	url := h.baseURL + "/" + chi.URLParam(r, "nama")
+
+	userID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	boleh, err := h.bolehLihat(ctx, r, userID, url)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa akses file")
+		return
+	}
+	if !boleh {
+		utils.WriteError(w, http.StatusNotFound, "File tidak ditemukan")
+		return
+	}
+
+	err = h.files.Sajikan(w, r, url)
+	if errors.Is(err, os.ErrNotExist) {
+		utils.WriteError(w, http.StatusNotFound, "File tidak ditemukan")
+		return
+	}
+	if err != nil {
+		log.Printf("gagal menyajikan file %s: %v", url, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membaca file")
+	}
+}
+
+// bolehLihat mencari data pemilik file lalu mengecek apakah user yang
+// login adalah pemiliknya atau staff dengan permission yang sesuai
+func (h *BerkasHandler) bolehLihat(ctx context.Context, r *http.Request, userID primitive.ObjectID, url string) (bool, error) {
+	var role *models.Role
+	staff := func(perms []string) (bool, error) {
+		if role == nil {
+			var err error
+			if role, err = roleAktor(ctx, h.roles, r); err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return false, nil
+				}
+				return false, err
+			}
+		}
+		return punyaSalahSatu(role, perms), nil
+	}
+
+	users, _, err := h.users.List(ctx, repository.UserFilter{AvatarURL: url}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(users) > 0 {
+		if users[0].ID == userID {
+			return true, nil
+		}
+		return staff(permLihatAvatar)
+	}
+
+	// Foto kasus kerusakan memakai foto pemeriksaan yang sama
+	transaksi, _, err := h.transaksi.List(ctx, repository.TransactionFilter{Foto: url}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	if len(transaksi) == 0 {
+		return false, nil
+	}
+	trans := transaksi[0]
+	if trans.UserID == userID {
+		return true, nil
+	}
+	if ok, err := staff(permLihatFotoKembali); !ok || err != nil {
+		return false, err
+	}
+
+	// Staff dengan lingkup lab hanya melihat foto alat labnya
+	l, err := h.akses.lingkup(ctx, r)
+	if err != nil {
+		return false, err
+	}
+	if l.semua {
+		return true, nil
+	}
+	alat, err := h.alat.FindByID(ctx, trans.AlatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return l.boleh(alat.LabID), nil
+}
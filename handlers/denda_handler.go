@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DendaHandler mengelola denda keterlambatan dan pembayarannya
+type DendaHandler struct {
+	denda repository.DendaRepository
+	audit repository.AuditRepository
+	tx    repository.Transactor
+}
+
+// NewDendaHandler membuat DendaHandler dari repository di store
+func NewDendaHandler(store *repository.Store) *DendaHandler {
+	return &DendaHandler{denda: store.Denda, audit: store.Audit, tx: store.Tx}
+}
+
+// Request body pembayaran denda
+type bayarDendaRequest struct {
+	Jumlah  int64  `json:"jumlah"`
+	Catatan string `json:"catatan,omitempty"`
+}
+
+// Request body penghapusan denda
+type hapuskanDendaRequest struct {
+	Alasan string `json:"alasan"`
+}
+
+// Error alur pembayaran denda
+var (
+	errDendaSudahDitutup     = errors.New("denda sudah lunas atau dihapuskan")
+	errJumlahBayarTidakValid = errors.New("jumlah pembayaran tidak valid")
+)
+
+// punyaDendaBelumLunas memeriksa apakah user masih punya denda BELUM_LUNAS
+func punyaDendaBelumLunas(ctx context.Context, repo repository.DendaRepository, userID primitive.ObjectID) (bool, error) {
+	_, total, err := repo.List(ctx, repository.DendaFilter{
+		UserID: &userID,
+		Status: models.DendaBelumLunas,
+	}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
+
+// DendaSaya menampilkan semua denda milik user yang sedang login
+func (h *DendaHandler) DendaSaya(w http.ResponseWriter, r *http.Request) {
+	userObjID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	h.listDenda(w, r, repository.DendaFilter{UserID: &userObjID})
+}
+
+// ListDenda (admin) menampilkan semua denda, bisa difilter ?status=, ?jenis= dan ?user_id=
+func (h *DendaHandler) ListDenda(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseObjectIDQuery(r, "user_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.listDenda(w, r, repository.DendaFilter{UserID: userID})
+}
+
+// listDenda menambahkan filter status/jenis dari query lalu mengirim satu halaman denda
+func (h *DendaHandler) listDenda(w http.ResponseWriter, r *http.Request, filter repository.DendaFilter) {
+	opts, err := parseListOptions(r, "created_at", "jumlah")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Status = strings.ToUpper(r.URL.Query().Get("status"))
+	filter.Jenis = strings.ToUpper(r.URL.Query().Get("jenis"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, total, err := h.denda.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data denda")
+		return
+	}
+
+	writeList(w, list, opts, total)
+}
+
+// BayarDenda (admin) mencatat pembayaran denda. Denda otomatis LUNAS
+// jika total pembayaran sudah mencapai jumlah denda.
+func (h *DendaHandler) BayarDenda(w http.ResponseWriter, r *http.Request) {
+	dendaID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID denda tidak valid")
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	var req bayarDendaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var denda *models.Denda
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		denda, err = h.denda.FindByID(ctx, dendaID)
+		if err != nil {
+			return err
+		}
+		if denda.Status != models.DendaBelumLunas {
+			return errDendaSudahDitutup
+		}
+		sebelum := potret(denda)
+
+		// Jumlah kosong berarti melunasi seluruh sisa denda
+		jumlah := req.Jumlah
+		if jumlah == 0 {
+			jumlah = denda.Sisa()
+		}
+		if jumlah < 0 || jumlah > denda.Sisa() {
+			return errJumlahBayarTidakValid
+		}
+
+		now := time.Now()
+		denda.Pembayaran = append(denda.Pembayaran, models.PembayaranDenda{
+			Jumlah:      jumlah,
+			Catatan:     req.Catatan,
+			DicatatOleh: adminID,
+			Waktu:       now,
+		})
+		denda.TotalDibayar += jumlah
+		if denda.Sisa() == 0 {
+			denda.Status = models.DendaLunas
+		}
+		denda.UpdatedAt = now
+		if err := h.denda.Update(ctx, denda); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditDendaBayar, "denda", denda.ID, sebelum, denda)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Denda tidak ditemukan")
+		return
+	case errors.Is(err, errDendaSudahDitutup):
+		utils.WriteError(w, http.StatusBadRequest, "Denda sudah lunas atau dihapuskan")
+		return
+	case errors.Is(err, errJumlahBayarTidakValid):
+		utils.WriteError(w, http.StatusBadRequest, "Jumlah pembayaran harus > 0 dan tidak melebihi sisa denda")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mencatat pembayaran denda")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Pembayaran denda berhasil dicatat",
+		Data:    denda,
+	})
+}
+
+// HapuskanDenda (admin) membebaskan sisa denda user dengan alasan tertentu
+func (h *DendaHandler) HapuskanDenda(w http.ResponseWriter, r *http.Request) {
+	dendaID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID denda tidak valid")
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	var req hapuskanDendaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if strings.TrimSpace(req.Alasan) == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Alasan penghapusan wajib diisi")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var denda *models.Denda
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		denda, err = h.denda.FindByID(ctx, dendaID)
+		if err != nil {
+			return err
+		}
+		if denda.Status != models.DendaBelumLunas {
+			return errDendaSudahDitutup
+		}
+		sebelum := potret(denda)
+
+		denda.Status = models.DendaDihapuskan
+		denda.AlasanHapus = strings.TrimSpace(req.Alasan)
+		denda.DihapuskanOleh = &adminID
+		denda.UpdatedAt = time.Now()
+		if err := h.denda.Update(ctx, denda); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditDendaHapuskan, "denda", denda.ID, sebelum, denda)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Denda tidak ditemukan")
+		return
+	case errors.Is(err, errDendaSudahDitutup):
+		utils.WriteError(w, http.StatusBadRequest, "Denda sudah lunas atau dihapuskan")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapuskan denda")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Denda berhasil dihapuskan",
+		Data:    denda,
+	})
+}
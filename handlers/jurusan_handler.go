@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JurusanHandler mengelola daftar jurusan untuk registrasi mahasiswa
+type JurusanHandler struct {
+	jurusan repository.JurusanRepository
+	users   repository.UserRepository
+	audit   repository.AuditRepository
+	tx      repository.Transactor
+}
+
+// NewJurusanHandler membuat JurusanHandler dari repository di store
+func NewJurusanHandler(store *repository.Store) *JurusanHandler {
+	return &JurusanHandler{jurusan: store.Jurusan, users: store.Users, audit: store.Audit, tx: store.Tx}
+}
+
+// Request body untuk membuat/mengupdate jurusan
+type jurusanRequest struct {
+	Nama     string `json:"nama"`
+	Fakultas string `json:"fakultas"`
+}
+
+// errJurusanDipakai dikembalikan saat jurusan yang masih punya mahasiswa akan dihapus
+var errJurusanDipakai = errors.New("jurusan masih dipakai user")
+
+// ListJurusan menampilkan semua jurusan yang bisa dipilih saat registrasi
+func (h *JurusanHandler) ListJurusan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.jurusan.List(ctx)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data jurusan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// CreateJurusan (admin) menambah jurusan baru
+func (h *JurusanHandler) CreateJurusan(w http.ResponseWriter, r *http.Request) {
+	var req jurusanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Nama jurusan wajib diisi")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := h.jurusan.FindByNama(ctx, req.Nama)
+	if err == nil {
+		utils.WriteError(w, http.StatusBadRequest, "Jurusan sudah ada")
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa jurusan")
+		return
+	}
+
+	now := time.Now()
+	jurusan := models.Jurusan{
+		ID:        primitive.NewObjectID(),
+		Nama:      req.Nama,
+		Fakultas:  strings.TrimSpace(req.Fakultas),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.jurusan.Create(ctx, &jurusan); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditJurusanCreate, "jurusan", jurusan.ID, nil, jurusan)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan jurusan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Jurusan berhasil ditambahkan",
+		Data:    jurusan,
+	})
+}
+
+// UpdateJurusan (admin) mengubah jurusan. Jika nama diganti, jurusan semua
+// user yang memakai nama lama ikut diganti.
+func (h *JurusanHandler) UpdateJurusan(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req jurusanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	jurusan, err := h.jurusan.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Jurusan tidak ditemukan")
+		return
+	}
+
+	namaLama := jurusan.Nama
+	sebelum := potret(jurusan)
+	if nama := strings.TrimSpace(req.Nama); nama != "" && nama != jurusan.Nama {
+		if _, err := h.jurusan.FindByNama(ctx, nama); err == nil {
+			utils.WriteError(w, http.StatusBadRequest, "Jurusan sudah ada")
+			return
+		}
+		jurusan.Nama = nama
+	}
+	if fakultas := strings.TrimSpace(req.Fakultas); fakultas != "" {
+		jurusan.Fakultas = fakultas
+	}
+	jurusan.UpdatedAt = time.Now()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.jurusan.Update(ctx, jurusan); err != nil {
+			return err
+		}
+		if err := catatPerubahan(ctx, h.audit, r, models.AuditJurusanUpdate, "jurusan", jurusan.ID, sebelum, jurusan); err != nil {
+			return err
+		}
+		if jurusan.Nama == namaLama {
+			return nil
+		}
+		return h.users.GantiJurusan(ctx, namaLama, jurusan.Nama)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate jurusan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Jurusan berhasil diupdate",
+		Data:    jurusan,
+	})
+}
+
+// DeleteJurusan (admin) menghapus jurusan yang tidak dipakai user mana pun
+func (h *JurusanHandler) DeleteJurusan(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		jurusan, err := h.jurusan.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		_, dipakai, err := h.users.List(ctx, repository.UserFilter{Jurusan: jurusan.Nama}, repository.ListOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if dipakai > 0 {
+			return errJurusanDipakai
+		}
+		if err := h.jurusan.Delete(ctx, objID); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditJurusanDelete, "jurusan", objID, potret(jurusan), nil)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Jurusan tidak ditemukan")
+		return
+	case errors.Is(err, errJurusanDipakai):
+		utils.WriteError(w, http.StatusConflict, "Jurusan masih dipakai mahasiswa, ganti nama jurusan jika perlu")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus jurusan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Jurusan berhasil dihapus",
+	})
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KasusHandler mengelola kasus kerusakan / kehilangan unit alat
+type KasusHandler struct {
+	kasus repository.KasusRepository
+	unit  repository.AlatUnitRepository
+	alat  repository.AlatRepository
+	denda repository.DendaRepository
+	audit repository.AuditRepository
+	tx    repository.Transactor
+	akses aksesLab
+	buku  bukuStok
+}
+
+// NewKasusHandler membuat KasusHandler dari repository di store
+func NewKasusHandler(store *repository.Store) *KasusHandler {
+	return &KasusHandler{
+		kasus: store.Kasus,
+		unit:  store.Unit,
+		alat:  store.Alat,
+		denda: store.Denda,
+		audit: store.Audit,
+		tx:    store.Tx,
+		akses: newAksesLab(store),
+		buku:  newBukuStok(store),
+	}
+}
+
+// Request body penyelesaian kasus
+type selesaikanKasusRequest struct {
+	Penyelesaian string `json:"penyelesaian"`
+	// Biaya ditagihkan ke peminjam sebagai denda KERUSAKAN. Untuk GANTI_RUGI
+	// default-nya nilai_barang alat.
+	Biaya   int64  `json:"biaya,omitempty"`
+	Catatan string `json:"catatan,omitempty"`
+}
+
+// Error alur penyelesaian kasus
+var (
+	errKasusSudahSelesai   = errors.New("kasus sudah diselesaikan")
+	errTidakBisaDiperbaiki = errors.New("unit hilang tidak bisa diperbaiki")
+	errBiayaTidakValid     = errors.New("biaya tidak valid")
+)
+
+// KasusSaya menampilkan kasus kerusakan milik user yang sedang login
+func (h *KasusHandler) KasusSaya(w http.ResponseWriter, r *http.Request) {
+	userObjID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	h.listKasus(w, r, repository.KasusFilter{UserID: &userObjID})
+}
+
+// ListKasus (admin) menampilkan semua kasus kerusakan, bisa difilter
+// ?status=, ?user_id=, ?alat_id= dan ?lab_id=. Role dengan lingkup lab
+// hanya melihat kasus alat di labnya.
+func (h *KasusHandler) ListKasus(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseObjectIDQuery(r, "user_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	alatID, err := parseObjectIDQuery(r, "alat_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := repository.KasusFilter{UserID: userID, AlatID: alatID}
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
+		return
+	}
+	h.listKasus(w, r, filter)
+}
+
+// listKasus menambahkan filter status dari query lalu mengirim satu halaman kasus
+func (h *KasusHandler) listKasus(w http.ResponseWriter, r *http.Request, filter repository.KasusFilter) {
+	opts, err := parseListOptions(r, "created_at", "status")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Status = strings.ToUpper(r.URL.Query().Get("status"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, total, err := h.kasus.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data kasus kerusakan")
+		return
+	}
+
+	writeList(w, list, opts, total)
+}
+
+// SelesaikanKasus (admin) menutup kasus dengan perbaikan, ganti rugi atau
+// penghapusan unit dari inventaris
+func (h *KasusHandler) SelesaikanKasus(w http.ResponseWriter, r *http.Request) {
+	kasusID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID kasus tidak valid")
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	var req selesaikanKasusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	switch req.Penyelesaian {
+	case models.PenyelesaianDiperbaiki, models.PenyelesaianGantiRugi, models.PenyelesaianDihapuskan:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, "penyelesaian harus DIPERBAIKI, GANTI_RUGI atau DIHAPUSKAN")
+		return
+	}
+	if req.Biaya < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "biaya tidak boleh negatif")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		kasus *models.KasusKerusakan
+		denda *models.Denda
+	)
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		denda = nil
+		kasus, err = h.kasus.FindByID(ctx, kasusID)
+		if err != nil {
+			return err
+		}
+		if err := h.akses.cekAlatTransaksi(ctx, r, kasus.AlatID); err != nil {
+			return err
+		}
+		if kasus.Status != models.KasusTerbuka {
+			return errKasusSudahSelesai
+		}
+		sebelum := potret(kasus)
+
+		biaya := req.Biaya
+		statusUnit := models.UnitDihapus
+		switch req.Penyelesaian {
+		case models.PenyelesaianDiperbaiki:
+			if kasus.Kondisi == models.KondisiHilang {
+				return errTidakBisaDiperbaiki
+			}
+			statusUnit = models.UnitTersedia
+		case models.PenyelesaianGantiRugi:
+			if biaya == 0 {
+				alat, err := h.alat.FindByID(ctx, kasus.AlatID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				if alat != nil {
+					biaya = alat.NilaiBarang
+				}
+			}
+			if biaya <= 0 {
+				return errBiayaTidakValid
+			}
+		case models.PenyelesaianDihapuskan:
+			if biaya != 0 {
+				return errBiayaTidakValid
+			}
+		}
+
+		now := time.Now()
+		unit, err := h.unit.FindByID(ctx, kasus.UnitID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if unit != nil {
+			awal, err := h.buku.saldo(ctx, unit.AlatID)
+			if err != nil {
+				return err
+			}
+			sebelumUnit := potret(unit)
+			unit.Status = statusUnit
+			if statusUnit == models.UnitTersedia {
+				unit.Kondisi = models.KondisiBaik
+			}
+			unit.UpdatedAt = now
+			if err := h.unit.Update(ctx, unit); err != nil {
+				return err
+			}
+			jenis := models.MutasiPenghapusan
+			if statusUnit == models.UnitTersedia {
+				jenis = models.MutasiPerbaikan
+			}
+			_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+				Jenis:      jenis,
+				UnitIDs:    []primitive.ObjectID{unit.ID},
+				KasusID:    &kasus.ID,
+				Keterangan: "Kasus " + kasus.Kondisi + " diselesaikan: " + req.Penyelesaian,
+			})
+			if err != nil {
+				return err
+			}
+			if err := catatPerubahan(ctx, h.audit, r, models.AuditUnitUpdate, "alat_units", unit.ID, sebelumUnit, unit); err != nil {
+				return err
+			}
+		}
+
+		if biaya > 0 {
+			denda = &models.Denda{
+				ID:            primitive.NewObjectID(),
+				Jenis:         models.DendaJenisKerusakan,
+				TransactionID: kasus.TransactionID,
+				KasusID:       &kasus.ID,
+				UserID:        kasus.UserID,
+				AlatID:        kasus.AlatID,
+				Jumlah:        biaya,
+				Pembayaran:    []models.PembayaranDenda{},
+				Status:        models.DendaBelumLunas,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			}
+			if err := h.denda.Create(ctx, denda); err != nil {
+				return err
+			}
+			kasus.DendaID = &denda.ID
+		}
+
+		kasus.Status = models.KasusSelesai
+		kasus.Penyelesaian = req.Penyelesaian
+		kasus.Biaya = biaya
+		kasus.CatatanPenyelesaian = strings.TrimSpace(req.Catatan)
+		kasus.DiselesaikanOleh = &adminID
+		kasus.DiselesaikanPada = &now
+		kasus.UpdatedAt = now
+		if err := h.kasus.Update(ctx, kasus); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditKasusSelesaikan, "kasus_kerusakan", kasus.ID, sebelum, kasus)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Kasus tidak ditemukan")
+		return
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+		return
+	case errors.Is(err, errKasusSudahSelesai):
+		utils.WriteError(w, http.StatusBadRequest, "Kasus sudah diselesaikan")
+		return
+	case errors.Is(err, errTidakBisaDiperbaiki):
+		utils.WriteError(w, http.StatusBadRequest, "Unit yang hilang tidak bisa diselesaikan dengan perbaikan")
+		return
+	case errors.Is(err, errBiayaTidakValid):
+		utils.WriteError(w, http.StatusBadRequest, "GANTI_RUGI butuh biaya > 0 (atau nilai_barang alat), DIHAPUSKAN tidak boleh ada biaya")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyelesaikan kasus")
+		return
+	}
+
+	data := map[string]interface{}{"kasus": kasus}
+	if denda != nil {
+		data["denda"] = denda
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Kasus kerusakan diselesaikan",
+		Data:    data,
+	})
+}
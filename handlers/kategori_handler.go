@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KategoriHandler mengelola aturan peminjaman per kategori alat
+type KategoriHandler struct {
+	kategori repository.KategoriRepository
+	audit    repository.AuditRepository
+	tx       repository.Transactor
+}
+
+// NewKategoriHandler membuat KategoriHandler dari repository di store
+func NewKategoriHandler(store *repository.Store) *KategoriHandler {
+	return &KategoriHandler{kategori: store.Kategori, audit: store.Audit, tx: store.Tx}
+}
+
+// Request body untuk membuat/mengupdate kategori
+type kategoriRequest struct {
+	Nama           string `json:"nama"`
+	MaksHariPinjam int    `json:"maks_hari_pinjam"`
+	// MaksPerpanjangan kosong berarti memakai DEFAULT_MAKS_PERPANJANGAN
+	MaksPerpanjangan *int `json:"maks_perpanjangan,omitempty"`
+}
+
+// ListKategori menampilkan semua kategori beserta aturannya
+func (h *KategoriHandler) ListKategori(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.kategori.List(ctx)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data kategori")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// CreateKategori (admin) menambah aturan kategori baru
+func (h *KategoriHandler) CreateKategori(w http.ResponseWriter, r *http.Request) {
+	var req kategoriRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" || req.MaksHariPinjam <= 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Nama dan maks_hari_pinjam wajib, maks_hari_pinjam > 0")
+		return
+	}
+	if req.MaksPerpanjangan != nil && *req.MaksPerpanjangan < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "maks_perpanjangan tidak boleh negatif")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := h.kategori.FindByNama(ctx, req.Nama)
+	if err == nil {
+		utils.WriteError(w, http.StatusBadRequest, "Kategori sudah ada")
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa kategori")
+		return
+	}
+
+	now := time.Now()
+	kategori := models.Kategori{
+		ID:               primitive.NewObjectID(),
+		Nama:             req.Nama,
+		MaksHariPinjam:   req.MaksHariPinjam,
+		MaksPerpanjangan: req.MaksPerpanjangan,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.kategori.Create(ctx, &kategori); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditKategoriCreate, "kategori", kategori.ID, nil, kategori)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan kategori")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Kategori berhasil ditambahkan",
+		Data:    kategori,
+	})
+}
+
+// UpdateKategori (admin) mengubah aturan kategori
+func (h *KategoriHandler) UpdateKategori(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req kategoriRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+	if req.MaksPerpanjangan != nil && *req.MaksPerpanjangan < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "maks_perpanjangan tidak boleh negatif")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	kategori, err := h.kategori.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Kategori tidak ditemukan")
+		return
+	}
+	sebelum := potret(kategori)
+
+	if nama := strings.TrimSpace(req.Nama); nama != "" && nama != kategori.Nama {
+		if _, err := h.kategori.FindByNama(ctx, nama); err == nil {
+			utils.WriteError(w, http.StatusBadRequest, "Kategori sudah ada")
+			return
+		}
+		kategori.Nama = nama
+	}
+	if req.MaksHariPinjam > 0 {
+		kategori.MaksHariPinjam = req.MaksHariPinjam
+	}
+	if req.MaksPerpanjangan != nil {
+		kategori.MaksPerpanjangan = req.MaksPerpanjangan
+	}
+	kategori.UpdatedAt = time.Now()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.kategori.Update(ctx, kategori); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditKategoriUpdate, "kategori", kategori.ID, sebelum, kategori)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate kategori")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Kategori berhasil diupdate",
+		Data:    kategori,
+	})
+}
+
+// DeleteKategori (admin) menghapus aturan kategori. Alat dengan kategori
+// ini kembali memakai aturan default.
+func (h *KategoriHandler) DeleteKategori(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		kategori, err := h.kategori.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		if err := h.kategori.Delete(ctx, objID); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditKategoriDelete, "kategori", objID, potret(kategori), nil)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "Kategori tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus kategori")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Kategori berhasil dihapus",
+	})
+}
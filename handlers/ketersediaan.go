@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// akhirWaktu dipakai sebagai batas atas pemakaian yang belum jelas selesainya
+// (misalnya peminjaman yang sudah lewat jatuh tempo)
+var akhirWaktu = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ketersediaan menghitung pemakaian alat dari peminjaman yang menahan stok
+// dan reservasi aktif, dipakai bersama oleh beberapa handler
+type ketersediaan struct {
+	transaksi repository.TransactionRepository
+	reservasi repository.ReservasiRepository
+}
+
+func newKetersediaan(store *repository.Store) ketersediaan {
+	return ketersediaan{transaksi: store.Transactions, reservasi: store.Reservasi}
+}
+
+// pemakaian mengumpulkan semua pemakaian alat yang beririsan dengan [dari, sampai).
+// kecualiTrans dan kecualiReservasi dilewati, misalnya saat transaksi itu
+// sendiri sedang diperiksa ulang.
+func (k ketersediaan) pemakaian(ctx context.Context, alatID primitive.ObjectID, dari, sampai time.Time, kecualiTrans, kecualiReservasi *primitive.ObjectID) ([]models.Pemakaian, error) {
+	now := time.Now()
+
+	transaksi, _, err := k.transaksi.List(ctx, repository.TransactionFilter{
+		AlatID: &alatID,
+		Status: []string{models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali},
+	}, repository.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []models.Pemakaian
+	for _, t := range transaksi {
+		if kecualiTrans != nil && t.ID == *kecualiTrans {
+			continue
+		}
+		// Stok sudah ditahan sejak disetujui sampai jatuh tempo. Peminjaman
+		// yang terlambat dianggap menahan stok tanpa batas waktu. Unit yang
+		// sudah dikembalikan sebagian tidak lagi ditahan.
+		selesai := t.JatuhTempo
+		if selesai.IsZero() || !selesai.After(now) {
+			selesai = akhirWaktu
+		}
+		list = append(list, models.Pemakaian{Mulai: time.Time{}, Selesai: selesai, Jumlah: t.SisaPinjam()})
+	}
+
+	reservasi, _, err := k.reservasi.List(ctx, repository.ReservasiFilter{
+		AlatID: &alatID,
+		Status: models.ReservasiAktif,
+		Dari:   dari,
+		Sampai: sampai,
+	}, repository.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range reservasi {
+		if kecualiReservasi != nil && res.ID == *kecualiReservasi {
+			continue
+		}
+		list = append(list, models.Pemakaian{Mulai: res.Mulai, Selesai: res.Selesai, Jumlah: res.Jumlah})
+	}
+
+	return list, nil
+}
+
+// cukup memeriksa apakah jumlah unit tambahan masih muat di stok total
+// selama rentang [dari, sampai)
+func (k ketersediaan) cukup(ctx context.Context, alat *models.Alat, jumlah int, dari, sampai time.Time, kecualiTrans, kecualiReservasi *primitive.ObjectID) (bool, error) {
+	list, err := k.pemakaian(ctx, alat.ID, dari, sampai, kecualiTrans, kecualiReservasi)
+	if err != nil {
+		return false, err
+	}
+	return models.PemakaianPuncak(list, dari, sampai)+jumlah <= alat.StokTotal, nil
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LabHandler mengelola data laboratorium / lokasi penyimpanan alat
+type LabHandler struct {
+	labs  repository.LabRepository
+	alat  repository.AlatRepository
+	users repository.UserRepository
+	audit repository.AuditRepository
+	tx    repository.Transactor
+}
+
+// NewLabHandler membuat LabHandler dari repository di store
+func NewLabHandler(store *repository.Store) *LabHandler {
+	return &LabHandler{labs: store.Lab, alat: store.Alat, users: store.Users, audit: store.Audit, tx: store.Tx}
+}
+
+// Request body untuk membuat/mengupdate lab
+type labRequest struct {
+	Kode           string                  `json:"kode"`
+	Nama           string                  `json:"nama"`
+	Alamat         string                  `json:"alamat"`
+	JamOperasional []models.JamOperasional `json:"jam_operasional"`
+	StaffIDs       []primitive.ObjectID    `json:"staff_ids"`
+}
+
+// errLabDipakai dikembalikan saat lab yang masih menyimpan alat akan dihapus
+var errLabDipakai = errors.New("lab masih menyimpan alat")
+
+// validasi merapikan isi request lalu mengembalikan pesan error pertama,
+// atau string kosong jika valid
+func (req *labRequest) validasi() string {
+	req.Kode = strings.ToUpper(strings.TrimSpace(req.Kode))
+	req.Nama = strings.TrimSpace(req.Nama)
+	req.Alamat = strings.TrimSpace(req.Alamat)
+	if req.Kode == "" || req.Nama == "" {
+		return "Kode dan nama lab wajib diisi"
+	}
+	if req.JamOperasional == nil {
+		req.JamOperasional = []models.JamOperasional{}
+	}
+	for i := range req.JamOperasional {
+		j := &req.JamOperasional[i]
+		j.Hari = strings.ToUpper(strings.TrimSpace(j.Hari))
+		if !j.Valid() {
+			return "jam_operasional tidak valid: hari harus SENIN-MINGGU, buka & tutup format HH:MM dengan buka sebelum tutup"
+		}
+	}
+
+	staff := make([]primitive.ObjectID, 0, len(req.StaffIDs))
+	for _, id := range req.StaffIDs {
+		if !slices.Contains(staff, id) {
+			staff = append(staff, id)
+		}
+	}
+	req.StaffIDs = staff
+	return ""
+}
+
+// cekStaff memastikan semua staff_ids adalah user yang terdaftar
+func (h *LabHandler) cekStaff(ctx context.Context, ids []primitive.ObjectID) error {
+	for _, id := range ids {
+		if _, err := h.users.FindByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLabError menerjemahkan error penyimpanan lab menjadi response HTTP
+func writeLabError(w http.ResponseWriter, err error, pesanGagal string) {
+	switch {
+	case errors.Is(err, repository.ErrDuplikat):
+		utils.WriteError(w, http.StatusConflict, "Kode lab sudah dipakai")
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusBadRequest, "Ada staff_ids yang bukan user terdaftar")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, pesanGagal)
+	}
+}
+
+// ListLab menampilkan semua lab urut nama
+func (h *LabHandler) ListLab(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.labs.List(ctx, repository.LabFilter{})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data lab")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// GetLab menampilkan detail satu lab
+func (h *LabHandler) GetLab(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lab, err := h.labs.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Lab tidak ditemukan")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    lab,
+	})
+}
+
+// CreateLab (admin) menambah lab baru
+func (h *LabHandler) CreateLab(w http.ResponseWriter, r *http.Request) {
+	var req labRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if pesan := req.validasi(); pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	lab := models.Lab{
+		ID:             primitive.NewObjectID(),
+		Kode:           req.Kode,
+		Nama:           req.Nama,
+		Alamat:         req.Alamat,
+		JamOperasional: req.JamOperasional,
+		StaffIDs:       req.StaffIDs,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.cekStaff(ctx, lab.StaffIDs); err != nil {
+			return err
+		}
+		if err := h.labs.Create(ctx, &lab); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditLabCreate, "lab", lab.ID, nil, lab)
+	})
+	if err != nil {
+		writeLabError(w, err, "Gagal menyimpan lab")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Lab berhasil ditambahkan",
+		Data:    lab,
+	})
+}
+
+// UpdateLab (admin) mengganti data lab, termasuk daftar staff-nya
+func (h *LabHandler) UpdateLab(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req labRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if pesan := req.validasi(); pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lab, err := h.labs.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Lab tidak ditemukan")
+		return
+	}
+	sebelum := potret(lab)
+
+	lab.Kode = req.Kode
+	lab.Nama = req.Nama
+	lab.Alamat = req.Alamat
+	lab.JamOperasional = req.JamOperasional
+	lab.StaffIDs = req.StaffIDs
+	lab.UpdatedAt = time.Now()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.cekStaff(ctx, lab.StaffIDs); err != nil {
+			return err
+		}
+		if err := h.labs.Update(ctx, lab); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditLabUpdate, "lab", lab.ID, sebelum, lab)
+	})
+	if err != nil {
+		writeLabError(w, err, "Gagal mengupdate lab")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Lab berhasil diupdate",
+		Data:    lab,
+	})
+}
+
+// DeleteLab (admin) menghapus lab yang sudah tidak menyimpan alat
+func (h *LabHandler) DeleteLab(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		lab, err := h.labs.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		// Alat yang dihapus tapi belum lewat masa retensi masih bisa dipulihkan
+		filter := repository.AlatFilter{LabIDs: []primitive.ObjectID{objID}, TermasukDihapus: true}
+		_, dipakai, err := h.alat.List(ctx, filter, repository.ListOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if dipakai > 0 {
+			return errLabDipakai
+		}
+		if err := h.labs.Delete(ctx, objID); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditLabDelete, "lab", objID, potret(lab), nil)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Lab tidak ditemukan")
+		return
+	case errors.Is(err, errLabDipakai):
+		utils.WriteError(w, http.StatusConflict, "Lab masih menyimpan alat, pindahkan alat ke lab lain dulu")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus lab")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Lab berhasil dihapus",
+	})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errLuarLingkupLab dikembalikan saat user mengelola data milik lab lain
+var errLuarLingkupLab = errors.New("data dikelola lab lain")
+
+// pesanLuarLingkupLab adalah pesan 403 untuk errLuarLingkupLab
+const pesanLuarLingkupLab = "Alat ini dikelola lab lain"
+
+// lingkupLab adalah lab yang boleh dikelola user yang sedang login.
+// semua bernilai true untuk role tanpa lingkup lab.
+type lingkupLab struct {
+	semua bool
+	lab   []primitive.ObjectID
+}
+
+// boleh mengecek apakah alat milik labID boleh dikelola. Alat tanpa lab
+// hanya boleh dikelola role tanpa lingkup lab.
+func (l lingkupLab) boleh(labID *primitive.ObjectID) bool {
+	if l.semua {
+		return true
+	}
+	return labID != nil && slices.Contains(l.lab, *labID)
+}
+
+// aksesLab menentukan lab yang boleh dikelola admin berdasarkan role dan
+// keanggotaan staff lab, dipakai bersama oleh handler admin
+type aksesLab struct {
+	roles repository.RoleRepository
+	labs  repository.LabRepository
+	alat  repository.AlatRepository
+}
+
+func newAksesLab(store *repository.Store) aksesLab {
+	return aksesLab{roles: store.Roles, labs: store.Lab, alat: store.Alat}
+}
+
+// lingkup menghitung lab yang boleh dikelola user yang sedang login
+func (a aksesLab) lingkup(ctx context.Context, r *http.Request) (lingkupLab, error) {
+	role, err := roleAktor(ctx, a.roles, r)
+	if err != nil {
+		return lingkupLab{}, err
+	}
+	if !role.LingkupLab {
+		return lingkupLab{semua: true}, nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		return lingkupLab{}, err
+	}
+	labs, err := a.labs.List(ctx, repository.LabFilter{StaffID: &userID})
+	if err != nil {
+		return lingkupLab{}, err
+	}
+	l := lingkupLab{lab: make([]primitive.ObjectID, len(labs))}
+	for i, lab := range labs {
+		l.lab[i] = lab.ID
+	}
+	return l, nil
+}
+
+// cekAlat memastikan alat ada dan boleh dikelola user yang sedang login.
+// Mengembalikan ErrNotFound jika alat tidak ada atau sudah dihapus, atau
+// errLuarLingkupLab jika alat milik lab lain.
+func (a aksesLab) cekAlat(ctx context.Context, r *http.Request, alatID primitive.ObjectID) (*models.Alat, error) {
+	alat, err := a.alat.FindByID(ctx, alatID)
+	if err != nil {
+		return nil, err
+	}
+	if alat.Dihapus() {
+		return nil, repository.ErrNotFound
+	}
+	l, err := a.lingkup(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if !l.boleh(alat.LabID) {
+		return nil, errLuarLingkupLab
+	}
+	return alat, nil
+}
+
+// alatTerlihat mengembalikan ID alat yang boleh dilihat di list admin,
+// dibatasi filter labID (opsional) dan lingkup lab user. nil berarti tanpa
+// batasan, slice kosong berarti tidak ada alat yang boleh dilihat.
+func (a aksesLab) alatTerlihat(ctx context.Context, r *http.Request, labID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	l, err := a.lingkup(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var labIDs []primitive.ObjectID
+	switch {
+	case labID != nil && l.boleh(labID):
+		labIDs = []primitive.ObjectID{*labID}
+	case labID != nil:
+		return []primitive.ObjectID{}, nil
+	case l.semua:
+		return nil, nil
+	default:
+		labIDs = l.lab
+	}
+
+	// Alat yang dihapus tetap disertakan supaya riwayatnya masih terlihat
+	filter := repository.AlatFilter{LabIDs: labIDs, TermasukDihapus: true}
+	alat, _, err := a.alat.List(ctx, filter, repository.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(alat))
+	for i, al := range alat {
+		ids[i] = al.ID
+	}
+	return ids, nil
+}
+
+// batasiAlat mengisi alatIDs dengan alat yang boleh dilihat di list admin
+// berdasarkan query ?lab_id= dan lingkup lab user. Jika gagal, response
+// error sudah ditulis dan hasilnya false.
+func (a aksesLab) batasiAlat(w http.ResponseWriter, r *http.Request, alatIDs *[]primitive.ObjectID) bool {
+	labID, err := parseObjectIDQuery(r, "lab_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids, err := a.alatTerlihat(ctx, r, labID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa lingkup lab")
+		return false
+	}
+	*alatIDs = ids
+	return true
+}
+
+// cekAlatTransaksi seperti cekAlat, tetapi untuk alat yang dirujuk
+// transaksi, reservasi atau kasus. Alat yang sudah dihapus hanya boleh
+// diurus role tanpa lingkup lab.
+func (a aksesLab) cekAlatTransaksi(ctx context.Context, r *http.Request, alatID primitive.ObjectID) error {
+	l, err := a.lingkup(ctx, r)
+	if err != nil {
+		return err
+	}
+	if l.semua {
+		return nil
+	}
+	alat, err := a.alat.FindByID(ctx, alatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errLuarLingkupLab
+	}
+	if err != nil {
+		return err
+	}
+	if !l.boleh(alat.LabID) {
+		return errLuarLingkupLab
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batas paginasi list
+const (
+	defaultLimit = 20
+	maksLimit    = 100
+)
+
+// parseListOptions membaca ?page=, ?limit= dan ?sort= dari query. sort memakai
+// nama field, awalan "-" untuk urutan menurun (misalnya sort=-created_at).
+// kolom adalah field yang boleh dipakai untuk sort.
+func parseListOptions(r *http.Request, kolom ...string) (repository.ListOptions, error) {
+	q := r.URL.Query()
+	opts := repository.ListOptions{Page: 1, Limit: defaultLimit}
+
+	if s := q.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return opts, errors.New("page harus angka >= 1")
+		}
+		opts.Page = n
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maksLimit {
+			return opts, fmt.Errorf("limit harus angka 1-%d", maksLimit)
+		}
+		opts.Limit = n
+	}
+	if s := q.Get("sort"); s != "" {
+		opts.SortBy = strings.TrimPrefix(s, "-")
+		opts.Desc = strings.HasPrefix(s, "-")
+		if !slices.Contains(kolom, opts.SortBy) {
+			return opts, fmt.Errorf("sort hanya bisa memakai: %s", strings.Join(kolom, ", "))
+		}
+	}
+	return opts, nil
+}
+
+// parseObjectIDQuery membaca filter ID opsional dari query
+func parseObjectIDQuery(r *http.Request, nama string) (*primitive.ObjectID, error) {
+	s := r.URL.Query().Get(nama)
+	if s == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s tidak valid", nama)
+	}
+	return &id, nil
+}
+
+// parseRentangQuery membaca filter rentang tanggal ?from= dan ?to=
+func parseRentangQuery(r *http.Request) (dari, sampai time.Time, err error) {
+	q := r.URL.Query()
+	if s := q.Get("from"); s != "" {
+		if dari, err = utils.ParseWaktu(s, false); err != nil {
+			return dari, sampai, fmt.Errorf("from tidak valid: %w", err)
+		}
+	}
+	if s := q.Get("to"); s != "" {
+		if sampai, err = utils.ParseWaktu(s, true); err != nil {
+			return dari, sampai, fmt.Errorf("to tidak valid: %w", err)
+		}
+	}
+	return dari, sampai, nil
+}
+
+// writeList mengirim satu halaman data beserta metadata paginasinya
+func writeList(w http.ResponseWriter, data interface{}, opts repository.ListOptions, total int64) {
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    data,
+		Meta:    utils.NewMeta(opts.Page, opts.Limit, total),
+	})
+}
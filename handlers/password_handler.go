@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"SIPAK/config"
+	"SIPAK/mail"
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPanjangPassword adalah panjang minimal password baru
+const minPanjangPassword = 6
+
+// Request body untuk lupa password
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// Request body untuk reset password dengan token dari email
+type resetPasswordRequest struct {
+	Token        string `json:"token"`
+	PasswordBaru string `json:"password_baru"`
+}
+
+// Request body untuk ganti password user yang sedang login
+type gantiPasswordRequest struct {
+	PasswordLama string `json:"password_lama"`
+	PasswordBaru string `json:"password_baru"`
+}
+
+// validasiPassword memeriksa syarat password baru
+func validasiPassword(password string) error {
+	if len([]rune(password)) < minPanjangPassword {
+		return fmt.Errorf("Password baru minimal %d karakter", minPanjangPassword)
+	}
+	return nil
+}
+
+// isiEmailReset menyusun email berisi token reset password
+func isiEmailReset(user *models.User, token string) mail.Pesan {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Halo %s,\n\n", user.Nama)
+	b.WriteString("Kami menerima permintaan reset password akun SIPAK Anda.\n\n")
+	if config.AppConfig.ResetPasswordURL != "" {
+		fmt.Fprintf(&b, "Buka link berikut untuk membuat password baru:\n%s?token=%s\n\n",
+			config.AppConfig.ResetPasswordURL, url.QueryEscape(token))
+	} else {
+		fmt.Fprintf(&b, "Token reset password Anda:\n%s\n\n", token)
+	}
+	fmt.Fprintf(&b, "Token berlaku %d menit dan hanya bisa dipakai sekali.\n",
+		int(config.AppConfig.ResetPasswordTTL/time.Minute))
+	b.WriteString("Abaikan email ini jika Anda tidak meminta reset password.\n")
+	return mail.Pesan{Ke: user.Email, Subjek: "Reset password SIPAK", Isi: b.String()}
+}
+
+// kirimTanpaBocor mengirim email untuk endpoint publik yang responsenya
+// selalu sama. Kegagalan kirim hanya dicatat di log supaya tidak membocorkan
+// bahwa akun dengan email ini terdaftar.
+func (h *AuthHandler) kirimTanpaBocor(ctx context.Context, jenis string, pesan mail.Pesan) {
+	if err := h.mailer.Kirim(ctx, pesan); err != nil {
+		log.Printf("gagal kirim email %s ke %s: %v", jenis, pesan.Ke, err)
+	}
+}
+
+// ForgotPassword mengirim token reset password ke email user. Response
+// selalu sama walaupun email tidak terdaftar supaya tidak bisa dipakai
+// untuk menebak email yang ada.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Email wajib diisi")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	respon := utils.JSONResponse{
+		Success: true,
+		Message: "Jika email terdaftar, token reset password sudah dikirim",
+	}
+
+	user, err := h.users.FindByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusOK, respon)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa email")
+		return
+	}
+
+	token, err := buatTokenAcak()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
+		return
+	}
+
+	now := time.Now()
+	reset := models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(config.AppConfig.ResetPasswordTTL),
+		CreatedAt: now,
+	}
+	if err := h.resets.Create(ctx, &reset); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan token reset")
+		return
+	}
+
+	h.kirimTanpaBocor(ctx, "reset password", isiEmailReset(user, token))
+
+	utils.WriteJSON(w, http.StatusOK, respon)
+}
+
+// ResetPassword mengganti password dengan token dari email. Semua token
+// reset lain dan semua session login user ikut dicabut.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Token wajib diisi")
+		return
+	}
+	if err := validasiPassword(req.PasswordBaru); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.PasswordBaru), bcrypt.DefaultCost)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal hash password")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		reset, err := h.resets.Pakai(ctx, hashToken(req.Token), now)
+		if err != nil {
+			return err
+		}
+		if err := h.users.UpdatePassword(ctx, reset.UserID, string(hash)); err != nil {
+			return err
+		}
+		if err := h.resets.HapusUser(ctx, reset.UserID); err != nil {
+			return err
+		}
+		n, err := h.sessions.RevokeSemuaUser(ctx, reset.UserID, primitive.NilObjectID, now)
+		if err != nil {
+			return err
+		}
+		return catatAuditOleh(ctx, h.audit, r, reset.UserID, models.AuditResetPassword, "users", reset.UserID,
+			nil, map[string]interface{}{"jumlah_session": n})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusBadRequest, "Token reset tidak valid, sudah dipakai atau kadaluarsa")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal reset password")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Password berhasil direset, silakan login ulang",
+	})
+}
+
+// GantiPassword mengganti password user yang sedang login. Session lain
+// milik user dicabut, session yang dipakai sekarang tetap berlaku.
+func (h *AuthHandler) GantiPassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+	sessionID, err := primitive.ObjectIDFromHex(middleware.GetSessionIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "Session invalid di token")
+		return
+	}
+
+	var req gantiPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.PasswordLama == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Password lama wajib diisi")
+		return
+	}
+	if err := validasiPassword(req.PasswordBaru); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.PasswordLama)); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Password lama salah")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.PasswordBaru), bcrypt.DefaultCost)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal hash password")
+		return
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.users.UpdatePassword(ctx, userID, string(hash)); err != nil {
+			return err
+		}
+		if err := h.resets.HapusUser(ctx, userID); err != nil {
+			return err
+		}
+		n, err := h.sessions.RevokeSemuaUser(ctx, userID, sessionID, time.Now())
+		if err != nil {
+			return err
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditGantiPassword, "users", userID,
+			nil, map[string]interface{}{"jumlah_session": n})
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengganti password")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Password berhasil diganti",
+	})
+}
@@ -3,40 +3,214 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"SIPAK/config"
 	"SIPAK/middleware"
 	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
 	"SIPAK/utils"
 
-	"github.com/go-chi/chi/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // PeminjamanHandler mengelola peminjaman dan pengembalian
-type PeminjamanHandler struct{}
+type PeminjamanHandler struct {
+	alat      repository.AlatRepository
+	unit      repository.AlatUnitRepository
+	transaksi repository.TransactionRepository
+	kategori  repository.KategoriRepository
+	denda     repository.DendaRepository
+	users     repository.UserRepository
+	kasus     repository.KasusRepository
+	labs      repository.LabRepository
+	tx        repository.Transactor
+	audit     repository.AuditRepository
+	files     storage.Storage
+
+	ketersediaan ketersediaan
+	akses        aksesLab
+	buku         bukuStok
+	aturan       aturanPeminjaman
+}
 
-// Request body peminjaman
+// NewPeminjamanHandler membuat PeminjamanHandler dari repository di store.
+// files dipakai untuk menyimpan foto kondisi alat saat pengembalian.
+func NewPeminjamanHandler(store *repository.Store, files storage.Storage) *PeminjamanHandler {
+	return &PeminjamanHandler{
+		alat:      store.Alat,
+		unit:      store.Unit,
+		transaksi: store.Transactions,
+		kategori:  store.Kategori,
+		denda:     store.Denda,
+		users:     store.Users,
+		kasus:     store.Kasus,
+		labs:      store.Lab,
+		audit:     store.Audit,
+		tx:        store.Tx,
+		files:     files,
+
+		ketersediaan: newKetersediaan(store),
+		akses:        newAksesLab(store),
+		buku:         newBukuStok(store),
+		aturan:       newAturanPeminjaman(store),
+	}
+}
+
+// Request body peminjaman. Isi alat_id dan jumlah untuk satu alat, atau
+// item untuk meminjam beberapa alat sekaligus.
 type peminjamanRequest struct {
+	AlatID string                  `json:"alat_id,omitempty"`
+	Jumlah int                     `json:"jumlah,omitempty"`
+	Item   []itemPeminjamanRequest `json:"item,omitempty"`
+	// JatuhTempo opsional (RFC3339 / YYYY-MM-DD). Jika kosong dihitung dari
+	// batas maksimal hari pinjam kategori alat (terpendek untuk multi-item).
+	JatuhTempo string `json:"jatuh_tempo,omitempty"`
+}
+
+// itemPeminjamanRequest adalah satu alat dalam peminjaman multi-item
+type itemPeminjamanRequest struct {
 	AlatID string `json:"alat_id"`
 	Jumlah int    `json:"jumlah"`
+
+	alatID primitive.ObjectID
+}
+
+// RiwayatPeminjamanResponse adalah item riwayat peminjaman yang dikirim ke client
+type RiwayatPeminjamanResponse = models.RiwayatPeminjaman
+
+// maksHariPinjam mengambil batas lama peminjaman dari aturan kategori,
+// atau default dari konfigurasi jika kategori belum diatur
+func maksHariPinjam(ctx context.Context, repo repository.KategoriRepository, kategori string) (int, error) {
+	k, err := repo.FindByNama(ctx, kategori)
+	if errors.Is(err, repository.ErrNotFound) {
+		return config.AppConfig.DefaultMaksHariPinjam, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return k.MaksHariPinjam, nil
+}
+
+// maksPerpanjangan mengambil jumlah perpanjangan otomatis dari aturan
+// kategori, atau default dari konfigurasi jika kategori belum mengaturnya
+func maksPerpanjangan(ctx context.Context, repo repository.KategoriRepository, kategori string) (int, error) {
+	k, err := repo.FindByNama(ctx, kategori)
+	if errors.Is(err, repository.ErrNotFound) {
+		return config.AppConfig.DefaultMaksPerpanjangan, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if k.MaksPerpanjangan == nil {
+		return config.AppConfig.DefaultMaksPerpanjangan, nil
+	}
+	return *k.MaksPerpanjangan, nil
+}
+
+// hitungDenda membuat catatan denda jika transaksi dikembalikan setelah jatuh
+// tempo. Mengembalikan nil jika tidak terlambat atau dendanya nol.
+func hitungDenda(trans *models.Transaction, alat *models.Alat, jumlahUnit int, kembali time.Time) *models.Denda {
+	hari := models.HariTerlambat(trans.JatuhTempo, kembali)
+	if hari == 0 {
+		return nil
+	}
+
+	kebijakan := models.KebijakanDenda{
+		Tipe:         models.DendaPerHari,
+		TarifPerHari: config.AppConfig.DefaultDendaPerHari,
+	}
+	if alat.KebijakanDenda != nil {
+		kebijakan = *alat.KebijakanDenda
+	}
+
+	jumlah := kebijakan.Hitung(hari, jumlahUnit, alat.NilaiBarang)
+	if jumlah <= 0 {
+		return nil
+	}
+
+	return &models.Denda{
+		ID:            primitive.NewObjectID(),
+		Jenis:         models.DendaJenisTerlambat,
+		TransactionID: trans.ID,
+		UserID:        trans.UserID,
+		AlatID:        trans.AlatID,
+		HariTerlambat: hari,
+		Jumlah:        jumlah,
+		Pembayaran:    []models.PembayaranDenda{},
+		Status:        models.DendaBelumLunas,
+		CreatedAt:     kembali,
+		UpdatedAt:     kembali,
+	}
+}
+
+// tandaiTerlambat mengganti status transaksi yang lewat jatuh tempo menjadi TERLAMBAT
+func tandaiTerlambat(list []models.Transaction) {
+	now := time.Now()
+	for i := range list {
+		list[i].Status = list[i].StatusEfektif(now)
+	}
+}
+
+// tandaiRiwayatTerlambat sama seperti tandaiTerlambat untuk data riwayat
+func tandaiRiwayatTerlambat(list []models.RiwayatPeminjaman) {
+	now := time.Now()
+	for i := range list {
+		list[i].Status = list[i].StatusEfektif(now)
+	}
 }
 
-type RiwayatPeminjamanResponse struct {
-	ID			   primitive.ObjectID `json:"id"`
-	AlatID 	       primitive.ObjectID `json:"alat_id"`
-	NamaAlat       string             `json:"nama_alat"`
-	Jumlah         int                `json:"jumlah"`
-	TanggalPinjam  time.Time       `json:"tanggal_pinjam"`
-	TanggalKembali *time.Time      `json:"tanggal_kembali,omitempty"`
-	Status         string            `json:"status"`
+// maksItemPeminjaman membatasi jumlah item dalam satu peminjaman multi-item
+const maksItemPeminjaman = 20
+
+// itemPeminjaman adalah satu item pengajuan yang sudah divalidasi
+type itemPeminjaman struct {
+	alat   *models.Alat
+	jumlah int
+}
+
+// parseItemPeminjaman menyatukan bentuk tunggal (alat_id + jumlah) dan
+// multi-item (item) menjadi daftar item. Mengembalikan pesan error untuk
+// client jika request tidak valid.
+func parseItemPeminjaman(req peminjamanRequest) ([]itemPeminjamanRequest, string) {
+	items := req.Item
+	if len(items) == 0 {
+		items = []itemPeminjamanRequest{{AlatID: req.AlatID, Jumlah: req.Jumlah}}
+	} else if req.AlatID != "" || req.Jumlah != 0 {
+		return nil, "Isi alat_id dan jumlah, atau item, tidak keduanya"
+	}
+	if len(items) > maksItemPeminjaman {
+		return nil, fmt.Sprintf("Maksimal %d item per peminjaman", maksItemPeminjaman)
+	}
+
+	dipakai := make(map[primitive.ObjectID]bool, len(items))
+	for i := range items {
+		if items[i].AlatID == "" || items[i].Jumlah <= 0 {
+			return nil, "alat_id dan jumlah wajib, jumlah > 0"
+		}
+		id, err := primitive.ObjectIDFromHex(items[i].AlatID)
+		if err != nil {
+			return nil, "alat_id tidak valid"
+		}
+		if dipakai[id] {
+			return nil, "alat_id " + items[i].AlatID + " disebut lebih dari sekali, gabungkan jumlahnya"
+		}
+		dipakai[id] = true
+		items[i].alatID = id
+	}
+	return items, ""
 }
 
-// PinjamAlat membuat transaksi peminjaman untuk user yg login
+// PinjamAlat membuat pengajuan peminjaman (status DIAJUKAN) untuk user yg
+// login. Pengajuan multi-item (field item) dibuat sebagai satu grup: semua
+// item divalidasi dulu dan hanya disimpan jika semuanya lolos, berbagi satu
+// jatuh tempo, lalu disetujui, ditolak, diambil atau dibatalkan bersama.
 func (h *PeminjamanHandler) PinjamAlat(w http.ResponseWriter, r *http.Request) {
 	var req peminjamanRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -45,8 +219,9 @@ func (h *PeminjamanHandler) PinjamAlat(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	if req.AlatID == "" || req.Jumlah <= 0 {
-		utils.WriteError(w, http.StatusBadRequest, "alat_id dan jumlah wajib, jumlah > 0")
+	reqItems, pesan := parseItemPeminjaman(req)
+	if pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
 		return
 	}
 
@@ -57,116 +232,195 @@ func (h *PeminjamanHandler) PinjamAlat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alatID, err := primitive.ObjectIDFromHex(req.AlatID)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "alat_id tidak valid")
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Ambil alat dulu
-	var alat models.Alat
-	if err := config.AlatCollection.FindOne(ctx, bson.M{"_id": alatID}).Decode(&alat); err != nil {
-		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+	user, err := h.users.FindByID(ctx, userObjID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa data user")
 		return
 	}
-
-	// Cek stok tersedia
-	if alat.StokTersedia < req.Jumlah {
-		utils.WriteError(w, http.StatusBadRequest, "Stok alat tidak mencukupi")
+	if !user.EmailTerverifikasi {
+		utils.WriteErrorKode(w, http.StatusForbidden, models.KodeEmailBelumTerverifikasi,
+			"Email belum diverifikasi, peminjaman belum bisa dibuat")
 		return
 	}
 
-	// Kurangi stok tersedia
-	_, err = config.AlatCollection.UpdateByID(ctx, alatID, bson.M{
-		"$inc": bson.M{"stok_tersedia": -req.Jumlah},
-		"$set": bson.M{"updated_at": time.Now()},
-	})
+	adaTunggakan, err := punyaDendaBelumLunas(ctx, h.denda, userObjID)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate stok alat")
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa denda user")
 		return
 	}
+	if adaTunggakan {
+		utils.WriteErrorKode(w, http.StatusForbidden, models.KodeDendaBelumLunas,
+			"Masih ada denda yang belum dibayar, peminjaman baru diblokir")
+		return
+	}
+
+	// Jatuh tempo bersama dibatasi kategori dengan lama pinjam paling pendek
+	items := make([]itemPeminjaman, len(reqItems))
+	maksHari := 0
+	for i, item := range reqItems {
+		alat, err := h.alat.FindByID(ctx, item.alatID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && alat.Dihapus()) {
+			utils.WriteError(w, http.StatusNotFound, "Alat "+item.AlatID+" tidak ditemukan")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+			return
+		}
+		// Satu persetujuan berarti satu lab yang memproses seluruh item
+		if i > 0 && !samaLab(alat.LabID, items[0].alat.LabID) {
+			utils.WriteError(w, http.StatusBadRequest, "Semua item harus dari lab yang sama, ajukan terpisah untuk lab lain")
+			return
+		}
+		hari, err := maksHariPinjam(ctx, h.kategori, alat.Kategori)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil aturan kategori")
+			return
+		}
+		if i == 0 || hari < maksHari {
+			maksHari = hari
+		}
+
+		// Cek stok awal supaya pengajuan yang jelas tidak mungkin dipenuhi
+		// langsung ditolak. Stok baru benar-benar dipotong saat disetujui admin.
+		if alat.StokTersedia < item.Jumlah {
+			utils.WriteError(w, http.StatusBadRequest, "Stok "+alat.Nama+" tidak mencukupi")
+			return
+		}
+		items[i] = itemPeminjaman{alat: alat, jumlah: item.Jumlah}
+	}
 
 	now := time.Now()
-	trans := models.Transaction{
-		ID:             primitive.NewObjectID(),
-		UserID:         userObjID,
-		AlatID:         alatID,
-		Jumlah:         req.Jumlah,
-		TanggalPinjam:  now,
-		Status:         "PINJAM",
-		CreatedAt:      now,
-		UpdatedAt:      now,
-	}
-
-	_, err = config.TransactionCollection.InsertOne(ctx, trans)
+	batasJatuhTempo := now.AddDate(0, 0, maksHari)
+
+	jatuhTempo := batasJatuhTempo
+	if req.JatuhTempo != "" {
+		jatuhTempo, err = utils.ParseWaktu(req.JatuhTempo, true)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "jatuh_tempo tidak valid: "+err.Error())
+			return
+		}
+		if !jatuhTempo.After(now) {
+			utils.WriteError(w, http.StatusBadRequest, "jatuh_tempo harus setelah waktu sekarang")
+			return
+		}
+		if jatuhTempo.After(batasJatuhTempo) {
+			utils.WriteError(w, http.StatusBadRequest,
+				fmt.Sprintf("Maksimal lama peminjaman kategori ini %d hari", maksHari))
+			return
+		}
+	}
+
+	var grupID *primitive.ObjectID
+	if len(items) > 1 {
+		id := primitive.NewObjectID()
+		grupID = &id
+	}
+	list := make([]models.Transaction, len(items))
+	for i, item := range items {
+		list[i] = models.Transaction{
+			ID:         primitive.NewObjectID(),
+			UserID:     userObjID,
+			AlatID:     item.alat.ID,
+			GrupID:     grupID,
+			Jumlah:     item.jumlah,
+			JatuhTempo: jatuhTempo,
+			Status:     models.StatusDiajukan,
+			RiwayatStatus: []models.PerubahanStatus{{
+				Ke:    models.StatusDiajukan,
+				Oleh:  userObjID,
+				Waktu: now,
+			}},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.aturan.periksa(ctx, user, items); err != nil {
+			return err
+		}
+		for i := range list {
+			if err := h.transaksi.Create(ctx, &list[i]); err != nil {
+				return err
+			}
+			if err := catatPerubahan(ctx, h.audit, r, models.AuditPeminjamanAjukan, "transactions", list[i].ID, nil, list[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var tolak *penolakan
+	if errors.As(err, &tolak) {
+		writePenolakan(w, tolak)
+		return
+	}
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat transaksi peminjaman")
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat pengajuan peminjaman")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
 		Success: true,
-		Message: "Peminjaman berhasil",
-		Data:    trans,
+		Message: "Pengajuan peminjaman berhasil, menunggu persetujuan admin",
+		Data:    dataTransisi(list),
 	})
 }
 
-// KembalikanAlat mengubah status transaksi menjadi KEMBALI dan menambah stok alat
-func (h *PeminjamanHandler) KembalikanAlat(w http.ResponseWriter, r *http.Request) {
-	transIDParam := chi.URLParam(r, "id")
-	transID, err := primitive.ObjectIDFromHex(transIDParam)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "ID transaksi tidak valid")
-		return
+// samaLab mengecek apakah dua alat disimpan di lab yang sama
+func samaLab(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// kolomUrutTransaksi adalah field yang boleh dipakai untuk ?sort= pada list transaksi
+var kolomUrutTransaksi = []string{"created_at", "jatuh_tempo", "tanggal_pinjam", "status", "jumlah"}
 
-	var trans models.Transaction
-	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": transID}).Decode(&trans); err != nil {
-		utils.WriteError(w, http.StatusNotFound, "Transaksi tidak ditemukan")
-		return
+// parseTransactionFilter membaca filter ?status=, ?alat_id=, ?grup_id= dan rentang
+// ?from= / ?to= dari query. status=TERLAMBAT dipetakan ke transaksi DIAMBIL
+// yang sudah lewat jatuh tempo karena status itu tidak disimpan di database.
+func parseTransactionFilter(r *http.Request, filter repository.TransactionFilter) (repository.TransactionFilter, error) {
+	alatID, err := parseObjectIDQuery(r, "alat_id")
+	if err != nil {
+		return filter, err
 	}
-
-	if trans.Status == "KEMBALI" {
-		utils.WriteError(w, http.StatusBadRequest, "Transaksi sudah dikembalikan")
-		return
+	filter.AlatID = alatID
+	if filter.GrupID, err = parseObjectIDQuery(r, "grup_id"); err != nil {
+		return filter, err
 	}
 
-	// Tambahkan stok alat kembali
-	_, err = config.AlatCollection.UpdateByID(ctx, trans.AlatID, bson.M{
-		"$inc": bson.M{"stok_tersedia": trans.Jumlah},
-		"$set": bson.M{"updated_at": time.Now()},
-	})
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal menambah stok alat")
-		return
+	if filter.Dari, filter.Sampai, err = parseRentangQuery(r); err != nil {
+		return filter, err
 	}
 
-	now := time.Now()
-	update := bson.M{
-		"status":          "KEMBALI",
-		"tanggal_kembali": &now,
-		"updated_at":      now,
+	switch status := strings.ToUpper(r.URL.Query().Get("status")); status {
+	case "":
+	case models.StatusTerlambat:
+		filter.Status = []string{models.StatusDiambil, models.StatusSebagianKembali}
+		filter.JatuhTempoSebelum = time.Now()
+	default:
+		filter.Status = []string{status}
 	}
+	return filter, nil
+}
 
-	_, err = config.TransactionCollection.UpdateByID(ctx, trans.ID, bson.M{"$set": update})
+// filterTransaksiAdmin menyusun filter list transaksi untuk admin, yang juga
+// boleh memfilter ?user_id=
+func filterTransaksiAdmin(r *http.Request) (repository.TransactionFilter, error) {
+	userID, err := parseObjectIDQuery(r, "user_id")
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal update status transaksi")
-		return
+		return repository.TransactionFilter{}, err
 	}
-
-	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
-		Success: true,
-		Message: "Pengembalian berhasil",
-	})
+	return parseTransactionFilter(r, repository.TransactionFilter{UserID: userID})
 }
 
-// ListTransaksiUser menampilkan semua transaksi milik user yg login
+// ListTransaksiUser menampilkan semua transaksi milik user yg login.
+// Query: status, alat_id, grup_id, from, to, page, limit, sort.
 func (h *PeminjamanHandler) ListTransaksiUser(w http.ResponseWriter, r *http.Request) {
 	userIDHex := middleware.GetUserIDFromContext(r)
 	userObjID, err := primitive.ObjectIDFromHex(userIDHex)
@@ -175,54 +429,53 @@ func (h *PeminjamanHandler) ListTransaksiUser(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	cursor, err := config.TransactionCollection.Find(ctx, bson.M{"user_id": userObjID})
+	filter, err := parseTransactionFilter(r, repository.TransactionFilter{UserID: &userObjID})
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data transaksi")
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer cursor.Close(ctx)
+	h.listTransaksi(w, r, filter)
+}
 
-	var list []models.Transaction
-	if err := cursor.All(ctx, &list); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data transaksi")
+// ListSemuaTransaksi (admin) menampilkan semua transaksi.
+// Query: status, user_id, alat_id, grup_id, lab_id, from, to, page, limit, sort.
+// Role dengan lingkup lab hanya melihat transaksi alat di labnya.
+func (h *PeminjamanHandler) ListSemuaTransaksi(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterTransaksiAdmin(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
-		Success: true,
-		Data:    list,
-	})
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
+		return
+	}
+	h.listTransaksi(w, r, filter)
 }
 
-// ListSemuaTransaksi (admin) menampilkan semua transaksi
-func (h *PeminjamanHandler) ListSemuaTransaksi(w http.ResponseWriter, r *http.Request) {
+// listTransaksi mengirim satu halaman transaksi yang cocok dengan filter
+func (h *PeminjamanHandler) listTransaksi(w http.ResponseWriter, r *http.Request, filter repository.TransactionFilter) {
+	opts, err := parseListOptions(r, kolomUrutTransaksi...)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := config.TransactionCollection.Find(ctx, bson.M{})
+	list, total, err := h.transaksi.List(ctx, filter, opts)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data transaksi")
 		return
 	}
-	defer cursor.Close(ctx)
+	tandaiTerlambat(list)
 
-	var list []models.Transaction
-	if err := cursor.All(ctx, &list); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data transaksi")
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
-		Success: true,
-		Data:    list,
-	})
+	writeList(w, list, opts, total)
 }
 
 // RiwayatSaya menampilkan riwayat peminjaman milik user yang sedang login,
-// lengkap dengan nama alat (join ke koleksi alat)
+// lengkap dengan nama alat (join ke koleksi alat).
+// Query: status, alat_id, grup_id, from, to, page, limit, sort.
 func (h *PeminjamanHandler) RiwayatSaya(w http.ResponseWriter, r *http.Request) {
 	userIDHex := middleware.GetUserIDFromContext(r)
 	userObjID, err := primitive.ObjectIDFromHex(userIDHex)
@@ -231,109 +484,74 @@ func (h *PeminjamanHandler) RiwayatSaya(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	pipeline := mongo.Pipeline{
-		bson.D{{Key: "$match", Value: bson.M{"user_id": userObjID}}},
-		bson.D{
-			{Key: "$lookup", Value: bson.M{
-				"from":         "alat",
-				"localField":   "alat_id",
-				"foreignField": "_id",
-				"as":           "alat",
-			}},
-		},
-		bson.D{
-			{Key: "$unwind", Value: bson.M{
-				"path":                       "$alat",
-				"preserveNullAndEmptyArrays": true,
-			}},
-		},
-		bson.D{
-			{Key: "$project", Value: bson.M{
-				"_id":             1,
-				"alat_id":         "$alat_id",
-				"jumlah":          1,
-				"tanggal_pinjam":  1,
-				"tanggal_kembali": 1,
-				"status":          1,
-				"nama_alat":       "$alat.nama",
-			}},
-		},
-		bson.D{
-			{Key: "$sort", Value: bson.M{"tanggal_pinjam": -1}},
-		},
+	filter, err := parseTransactionFilter(r, repository.TransactionFilter{UserID: &userObjID})
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	h.listRiwayat(w, r, filter)
+}
 
-	cursor, err := config.TransactionCollection.Aggregate(ctx, pipeline)
+// RiwayatSemua menampilkan riwayat semua transaksi (hanya admin).
+// Query: status, user_id, alat_id, grup_id, lab_id, from, to, page, limit, sort.
+// Role dengan lingkup lab hanya melihat transaksi alat di labnya.
+func (h *PeminjamanHandler) RiwayatSemua(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterTransaksiAdmin(r)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data riwayat peminjaman")
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer cursor.Close(ctx)
-
-	var riwayat []RiwayatPeminjamanResponse
-	if err := cursor.All(ctx, &riwayat); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data riwayat peminjaman")
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
 		return
 	}
-
-	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
-		Success: true,
-		Data:    riwayat,
-	})
+	h.listRiwayat(w, r, filter)
 }
 
-// RiwayatSemua menampilkan riwayat semua transaksi (hanya admin)
-func (h *PeminjamanHandler) RiwayatSemua(w http.ResponseWriter, r *http.Request) {
+// listRiwayat mengirim satu halaman riwayat yang cocok dengan filter
+func (h *PeminjamanHandler) listRiwayat(w http.ResponseWriter, r *http.Request, filter repository.TransactionFilter) {
+	opts, err := parseListOptions(r, kolomUrutTransaksi...)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	pipeline := mongo.Pipeline{
-		bson.D{
-			{Key: "$lookup", Value: bson.M{
-				"from":         "alat",
-				"localField":   "alat_id",
-				"foreignField": "_id",
-				"as":           "alat",
-			}},
-		},
-		bson.D{
-			{Key: "$unwind", Value: bson.M{
-				"path":                       "$alat",
-				"preserveNullAndEmptyArrays": true,
-			}},
-		},
-		bson.D{
-			{Key: "$project", Value: bson.M{
-				"_id":             1,
-				"alat_id":         "$alat_id",
-				"jumlah":          1,
-				"tanggal_pinjam":  1,
-				"tanggal_kembali": 1,
-				"status":          1,
-				"nama_alat":       "$alat.nama",
-			}},
-		},
-		bson.D{
-			{Key: "$sort", Value: bson.M{"tanggal_pinjam": -1}},
-		},
-	}
-
-	cursor, err := config.TransactionCollection.Aggregate(ctx, pipeline)
+	riwayat, total, err := h.transaksi.Riwayat(ctx, filter, opts)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data riwayat peminjaman")
 		return
 	}
-	defer cursor.Close(ctx)
-	var riwayat []RiwayatPeminjamanResponse
-	if err := cursor.All(ctx, &riwayat); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data riwayat peminjaman")
+	tandaiRiwayatTerlambat(riwayat)
+
+	writeList(w, riwayat, opts, total)
+}
+
+// ListTerlambat (admin) menampilkan peminjaman yang melewati jatuh tempo
+// beserta data peminjam dan alat. Query: lab_id.
+func (h *PeminjamanHandler) ListTerlambat(w http.ResponseWriter, r *http.Request) {
+	var alatIDs []primitive.ObjectID
+	if !h.akses.batasiAlat(w, r, &alatIDs) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.transaksi.ListTerlambat(ctx, time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data peminjaman terlambat")
 		return
 	}
+	if alatIDs != nil {
+		list = slices.DeleteFunc(list, func(p models.PeminjamanTerlambat) bool {
+			return !slices.Contains(alatIDs, p.AlatID)
+		})
+	}
+
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
-		Data:    riwayat,
+		Data:    list,
 	})
 }
-
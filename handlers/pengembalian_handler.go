@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"SIPAK/config"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batas ukuran upload foto pengembalian
+const (
+	maksUkuranFoto        = 5 << 20
+	maksUkuranFormKembali = 20 << 20
+)
+
+// ekstensiFoto memetakan tipe gambar yang diterima ke ekstensi file
+var ekstensiFoto = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Error validasi unit pengembalian, dipetakan ke 400 oleh kembalikan
+var (
+	// errUnitBukanTransaksi dikembalikan jika laporan kondisi menyebut unit lain
+	errUnitBukanTransaksi = errors.New("unit bukan bagian dari transaksi")
+	// errUnitSudahKembali dikembalikan jika unit sudah diperiksa di
+	// pengembalian sebagian sebelumnya
+	errUnitSudahKembali = errors.New("unit sudah dikembalikan")
+	// errJumlahMelebihiSisa dikembalikan jika jumlah yang dikembalikan lebih
+	// dari unit yang masih dipinjam
+	errJumlahMelebihiSisa = errors.New("jumlah melebihi sisa peminjaman")
+)
+
+// errLokasiKosong dikembalikan saat check-in alat tanpa lab tanpa lokasi
+var errLokasiKosong = errors.New("lokasi penerimaan wajib diisi")
+
+// Request body pengembalian. Jumlah kosong berarti semua unit yang masih
+// dipinjam dikembalikan. Jika jumlah diisi, unit yang disebutkan dipilih
+// lebih dulu lalu sisanya diambil dari unit yang belum kembali. Unit yang
+// tidak disebutkan dianggap BAIK. Lokasi hanya dipakai saat check-in oleh
+// staff, default nama lab alat.
+type pengembalianRequest struct {
+	Jumlah int                  `json:"jumlah,omitempty"`
+	Unit   []pemeriksaanRequest `json:"unit"`
+	Lokasi string               `json:"lokasi,omitempty"`
+}
+
+// laporanPengembalian adalah isi request pengembalian yang sudah divalidasi
+type laporanPengembalian struct {
+	jumlah int
+	unit   map[primitive.ObjectID]pemeriksaanRequest
+	lokasi string
+}
+
+// pemeriksaanRequest adalah laporan kondisi satu unit
+type pemeriksaanRequest struct {
+	UnitID  string `json:"unit_id"`
+	Kondisi string `json:"kondisi"`
+	Catatan string `json:"catatan,omitempty"`
+
+	unitID primitive.ObjectID
+	foto   string
+}
+
+// parsePengembalian membaca laporan kondisi dari body JSON, atau dari form
+// multipart berisi field "data" (JSON yang sama) dan file foto_<unit_id>.
+// Body kosong berarti semua unit kembali dalam kondisi BAIK.
+func (h *PeminjamanHandler) parsePengembalian(w http.ResponseWriter, r *http.Request) (laporanPengembalian, bool) {
+	var req pengembalianRequest
+	defer r.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	multipartForm := mediaType == "multipart/form-data"
+	if multipartForm {
+		r.Body = http.MaxBytesReader(w, r.Body, maksUkuranFormKembali)
+		if err := r.ParseMultipartForm(maksUkuranFoto); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Form upload tidak valid atau terlalu besar")
+			return laporanPengembalian{}, false
+		}
+		if data := r.FormValue("data"); data != "" {
+			if err := json.Unmarshal([]byte(data), &req); err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "Field data tidak valid")
+				return laporanPengembalian{}, false
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return laporanPengembalian{}, false
+	}
+
+	if req.Jumlah < 0 {
+		utils.WriteError(w, http.StatusBadRequest, "jumlah tidak boleh negatif")
+		return laporanPengembalian{}, false
+	}
+	if req.Jumlah > 0 && len(req.Unit) > req.Jumlah {
+		utils.WriteError(w, http.StatusBadRequest, "Unit yang dilaporkan melebihi jumlah yang dikembalikan")
+		return laporanPengembalian{}, false
+	}
+
+	laporan := make(map[primitive.ObjectID]pemeriksaanRequest, len(req.Unit))
+	for _, item := range req.Unit {
+		unitID, err := primitive.ObjectIDFromHex(item.UnitID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "unit_id tidak valid")
+			return laporanPengembalian{}, false
+		}
+		if _, dobel := laporan[unitID]; dobel {
+			utils.WriteError(w, http.StatusBadRequest, "unit_id "+item.UnitID+" dilaporkan lebih dari sekali")
+			return laporanPengembalian{}, false
+		}
+		if item.Kondisi == "" {
+			item.Kondisi = models.KondisiBaik
+		}
+		if !models.KondisiPengembalianValid(item.Kondisi) {
+			utils.WriteError(w, http.StatusBadRequest, "kondisi harus BAIK, RUSAK_RINGAN, RUSAK_BERAT atau HILANG")
+			return laporanPengembalian{}, false
+		}
+		item.unitID = unitID
+		item.Catatan = strings.TrimSpace(item.Catatan)
+		laporan[unitID] = item
+	}
+
+	hasil := laporanPengembalian{jumlah: req.Jumlah, unit: laporan, lokasi: strings.TrimSpace(req.Lokasi)}
+	if !multipartForm {
+		return hasil, true
+	}
+
+	// Foto disimpan sebelum transaksi database. Jika pengembalian gagal,
+	// file yang sudah tersimpan dihapus lagi lewat hapusFoto.
+	for unitID, item := range laporan {
+		file, header, err := r.FormFile("foto_" + unitID.Hex())
+		if errors.Is(err, http.ErrMissingFile) {
+			continue
+		}
+		if err != nil {
+			hasil.hapusFoto(h.files)
+			utils.WriteError(w, http.StatusBadRequest, "Foto tidak valid")
+			return laporanPengembalian{}, false
+		}
+		url, pesan := simpanFoto(r.Context(), h.files, file, header.Size)
+		file.Close()
+		if pesan != "" {
+			hasil.hapusFoto(h.files)
+			utils.WriteError(w, http.StatusBadRequest, pesan)
+			return laporanPengembalian{}, false
+		}
+		item.foto = url
+		laporan[unitID] = item
+	}
+	return hasil, true
+}
+
+// hapusFoto menghapus foto laporan yang sudah tersimpan, dipakai saat
+// pengembalian gagal supaya tidak ada file yatim. Kegagalan hanya dicatat
+// di log.
+func (l laporanPengembalian) hapusFoto(files storage.Storage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, item := range l.unit {
+		if item.foto == "" {
+			continue
+		}
+		if err := files.Hapus(ctx, item.foto); err != nil {
+			log.Printf("gagal menghapus foto %s: %v", item.foto, err)
+		}
+	}
+}
+
+// simpanFoto memeriksa tipe dan ukuran foto lalu menyimpannya ke storage.
+// Mengembalikan pesan error untuk client jika foto ditolak.
+func simpanFoto(ctx context.Context, files storage.Storage, file io.ReadSeeker, ukuran int64) (string, string) {
+	if ukuran > maksUkuranFoto {
+		return "", fmt.Sprintf("Ukuran foto maksimal %d MB", maksUkuranFoto>>20)
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	ext, ok := ekstensiFoto[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", "Foto harus berformat JPEG, PNG atau WebP"
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "Foto tidak valid"
+	}
+
+	url, err := files.Simpan(ctx, ext, file)
+	if err != nil {
+		return "", "Gagal menyimpan foto"
+	}
+	return url, ""
+}
+
+// pilihUnit menentukan unit yang dikembalikan kali ini beserta jumlahnya.
+// Unit yang dilaporkan selalu ikut, sisanya diambil dari unit yang belum
+// kembali sesuai urutan. Jumlah bisa lebih besar dari unit yang dipilih
+// untuk transaksi lama yang unitnya tidak lengkap.
+func pilihUnit(trans *models.Transaction, laporan laporanPengembalian) ([]primitive.ObjectID, int, error) {
+	sisa := trans.UnitBelumKembali()
+	belumKembali := make(map[primitive.ObjectID]bool, len(sisa))
+	for _, id := range sisa {
+		belumKembali[id] = true
+	}
+	for id := range laporan.unit {
+		if !belumKembali[id] {
+			if slices.Contains(trans.UnitIDs, id) {
+				return nil, 0, errUnitSudahKembali
+			}
+			return nil, 0, errUnitBukanTransaksi
+		}
+	}
+
+	jumlah := laporan.jumlah
+	if jumlah == 0 {
+		return sisa, trans.SisaPinjam(), nil
+	}
+	if jumlah > trans.SisaPinjam() {
+		return nil, 0, errJumlahMelebihiSisa
+	}
+
+	dipilih := make([]primitive.ObjectID, 0, jumlah)
+	for _, id := range sisa {
+		if _, ok := laporan.unit[id]; ok {
+			dipilih = append(dipilih, id)
+		}
+	}
+	for _, id := range sisa {
+		if len(dipilih) == jumlah {
+			break
+		}
+		if _, ok := laporan.unit[id]; !ok {
+			dipilih = append(dipilih, id)
+		}
+	}
+	return dipilih, jumlah, nil
+}
+
+// periksaUnit memeriksa unit yang dikembalikan kali ini, mengembalikan unit
+// yang BAIK ke stok dan membuka kasus kerusakan untuk unit yang rusak atau
+// hilang. Hasil pemeriksaan ditambahkan ke pemeriksaan sebelumnya dan
+// JumlahDikembalikan dinaikkan. Mengembalikan jumlah unit yang dikembalikan.
+func (h *PeminjamanHandler) periksaUnit(ctx context.Context, r *http.Request, trans *models.Transaction, laporan laporanPengembalian, now time.Time) (int, []models.KasusKerusakan, error) {
+	dipilih, jumlah, err := pilihUnit(trans, laporan)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	awal, err := h.buku.saldo(ctx, trans.AlatID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var (
+		baik  []primitive.ObjectID
+		rusak []primitive.ObjectID
+		kasus []models.KasusKerusakan
+	)
+	for _, id := range dipilih {
+		unit, err := h.unit.FindByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Unit ikut terhapus bersama alatnya
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		item, ok := laporan.unit[id]
+		if !ok {
+			item = pemeriksaanRequest{Kondisi: models.KondisiBaik}
+		}
+		hasil := models.PemeriksaanUnit{
+			UnitID:   id,
+			KodeAset: unit.KodeAset,
+			Kondisi:  item.Kondisi,
+			Catatan:  item.Catatan,
+			Foto:     item.foto,
+		}
+
+		if item.Kondisi == models.KondisiBaik {
+			baik = append(baik, id)
+			trans.Pemeriksaan = append(trans.Pemeriksaan, hasil)
+			continue
+		}
+
+		// Unit rusak / hilang ditahan di luar stok sampai kasusnya selesai
+		unit.Kondisi = item.Kondisi
+		unit.Status = models.UnitPerbaikan
+		if item.Kondisi == models.KondisiHilang {
+			unit.Status = models.UnitHilang
+		}
+		unit.TransactionID = nil
+		unit.UpdatedAt = now
+		if err := h.unit.Update(ctx, unit); err != nil {
+			return 0, nil, err
+		}
+
+		k := models.KasusKerusakan{
+			ID:            primitive.NewObjectID(),
+			TransactionID: trans.ID,
+			UserID:        trans.UserID,
+			AlatID:        trans.AlatID,
+			UnitID:        id,
+			KodeAset:      unit.KodeAset,
+			Kondisi:       item.Kondisi,
+			Catatan:       item.Catatan,
+			Foto:          item.foto,
+			Status:        models.KasusTerbuka,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := h.kasus.Create(ctx, &k); err != nil {
+			return 0, nil, err
+		}
+		hasil.KasusID = &k.ID
+		trans.Pemeriksaan = append(trans.Pemeriksaan, hasil)
+		kasus = append(kasus, k)
+		rusak = append(rusak, id)
+	}
+
+	trans.JumlahDikembalikan += jumlah
+
+	awal, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+		Jenis:         models.MutasiRusak,
+		UnitIDs:       rusak,
+		TransactionID: &trans.ID,
+		Keterangan:    "Rusak atau hilang saat pengembalian",
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := h.unit.Kembalikan(ctx, baik); err != nil {
+		return 0, nil, err
+	}
+	_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+		Jenis:         models.MutasiKembali,
+		UnitIDs:       baik,
+		TransactionID: &trans.ID,
+		Keterangan:    "Pengembalian peminjaman",
+	})
+	return jumlah, kasus, err
+}
+
+// KembalikanAlatSaya mengembalikan peminjaman milik user yang sedang login.
+// Ditolak jika pengembalian mandiri dinonaktifkan lewat PENGEMBALIAN_MANDIRI.
+func (h *PeminjamanHandler) KembalikanAlatSaya(w http.ResponseWriter, r *http.Request) {
+	if !config.AppConfig.PengembalianMandiri {
+		utils.WriteError(w, http.StatusForbidden, "Pengembalian mandiri dinonaktifkan, serahkan alat ke petugas lab")
+		return
+	}
+	h.kembalikan(w, r, false)
+}
+
+// TerimaPengembalian (admin) adalah check-in alat oleh staff lab. Staff yang
+// menerima, waktu dan lokasi penerimaan dicatat di transaksi.
+func (h *PeminjamanHandler) TerimaPengembalian(w http.ResponseWriter, r *http.Request) {
+	h.kembalikan(w, r, true)
+}
+
+// kembalikan memeriksa kondisi unit yang dikembalikan, mengembalikan unit
+// yang baik ke stok dan mencatat denda keterlambatan untuk jumlah tersebut.
+// Status menjadi DIKEMBALIKAN jika semua unit sudah kembali, atau
+// SEBAGIAN_KEMBALI jika masih ada yang dipinjam. Tanpa staff, hanya
+// peminjamnya sendiri yang boleh.
+func (h *PeminjamanHandler) kembalikan(w http.ResponseWriter, r *http.Request, staff bool) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+	laporan, ok := h.parsePengembalian(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Status dibaca ulang di dalam transaksi sehingga transaksi yang sama
+	// tidak bisa dikembalikan dua kali
+	var (
+		denda   *models.Denda
+		kasus   []models.KasusKerusakan
+		kembali int
+	)
+	efek := func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+		denda = nil
+
+		alat, err := h.alat.FindByID(ctx, trans.AlatID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		var terima *models.PenerimaanPengembalian
+		if staff {
+			terima, err = h.penerimaan(ctx, alat, aktor, laporan.lokasi, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		sebelum := len(trans.Pemeriksaan)
+		kembali, kasus, err = h.periksaUnit(ctx, r, trans, laporan, now)
+		if err != nil {
+			return err
+		}
+		if terima != nil {
+			// Setiap pengembalian sebagian punya check-in sendiri
+			terima.Jumlah = kembali
+			for _, p := range trans.Pemeriksaan[sebelum:] {
+				terima.UnitIDs = append(terima.UnitIDs, p.UnitID)
+			}
+			trans.Penerimaan = append(slices.Clone(trans.Penerimaan), *terima)
+		}
+		if trans.SisaPinjam() == 0 {
+			trans.TanggalKembali = &now
+		}
+
+		// Alat sudah dihapus permanen, transaksi tetap boleh ditutup
+		if alat == nil {
+			return nil
+		}
+		denda = hitungDenda(trans, alat, kembali, now)
+		if denda == nil {
+			return nil
+		}
+		return h.denda.Create(ctx, denda)
+	}
+	if staff {
+		efek = h.dalamLingkup(r, efek)
+	}
+	list, err := h.transisi(ctx, r, transID, aktor, !staff, models.StatusDikembalikan, "", efek)
+	if err != nil {
+		laporan.hapusFoto(h.files)
+	}
+	switch {
+	case errors.Is(err, errUnitBukanTransaksi):
+		utils.WriteError(w, http.StatusBadRequest, "Ada unit_id yang bukan bagian dari peminjaman ini")
+		return
+	case errors.Is(err, errUnitSudahKembali):
+		utils.WriteError(w, http.StatusBadRequest, "Ada unit_id yang sudah dikembalikan sebelumnya")
+		return
+	case errors.Is(err, errJumlahMelebihiSisa):
+		utils.WriteError(w, http.StatusBadRequest, "jumlah melebihi unit yang masih dipinjam")
+		return
+	case errors.Is(err, errLokasiKosong):
+		utils.WriteError(w, http.StatusBadRequest, "lokasi wajib diisi untuk alat tanpa lab")
+		return
+	case err != nil:
+		writeTransisiError(w, err, "Gagal memproses pengembalian")
+		return
+	}
+
+	trans := list[0]
+	pesan := []string{"Pengembalian berhasil"}
+	if trans.Status == models.StatusSebagianKembali {
+		pesan = []string{fmt.Sprintf("Pengembalian sebagian berhasil, %d unit masih dipinjam", trans.SisaPinjam())}
+	}
+	if denda != nil {
+		pesan = append(pesan, fmt.Sprintf("terlambat %d hari dan dikenakan denda", denda.HariTerlambat))
+	}
+	if len(kasus) > 0 {
+		pesan = append(pesan, fmt.Sprintf("%d unit rusak/hilang dan dibuatkan kasus kerusakan", len(kasus)))
+	}
+
+	data := map[string]interface{}{
+		"status":              trans.Status,
+		"jumlah_kembali":      kembali,
+		"jumlah_dikembalikan": trans.JumlahDikembalikan,
+		"sisa":                trans.SisaPinjam(),
+		"pemeriksaan":         trans.Pemeriksaan,
+	}
+	if staff {
+		data["penerimaan"] = trans.Penerimaan[len(trans.Penerimaan)-1]
+	}
+	if denda != nil {
+		data["denda"] = denda
+	}
+	if len(kasus) > 0 {
+		data["kasus"] = kasus
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: strings.Join(pesan, ", "),
+		Data:    data,
+	})
+}
+
+// penerimaan menyusun catatan check-in. Lokasi kosong diisi nama lab alat,
+// alat tanpa lab wajib menyebut lokasi.
+func (h *PeminjamanHandler) penerimaan(ctx context.Context, alat *models.Alat, staffID primitive.ObjectID, lokasi string, now time.Time) (*models.PenerimaanPengembalian, error) {
+	if lokasi == "" && alat != nil && alat.LabID != nil {
+		lab, err := h.labs.FindByID(ctx, *alat.LabID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if lab != nil {
+			lokasi = lab.Nama
+		}
+	}
+	if lokasi == "" {
+		return nil, errLokasiKosong
+	}
+	return &models.PenerimaanPengembalian{StaffID: staffID, Waktu: now, Lokasi: lokasi}, nil
+}
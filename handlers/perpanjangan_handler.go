@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Error pengajuan perpanjangan, dipetakan ke status HTTP oleh writePerpanjanganError
+var (
+	errTidakDipinjam           = errors.New("transaksi tidak sedang dipinjam")
+	errPerpanjanganMenunggu    = errors.New("masih ada perpanjangan yang menunggu")
+	errTidakAdaPerpanjangan    = errors.New("tidak ada perpanjangan yang menunggu")
+	errJatuhTempoBaru          = errors.New("jatuh tempo baru tidak setelah jatuh tempo lama")
+	errPerpanjanganTerlaluLama = errors.New("perpanjangan melebihi lama pinjam kategori")
+)
+
+// Keterangan pengajuan yang masuk antrian admin
+const (
+	ketBatasPerpanjangan = "Batas perpanjangan otomatis kategori alat sudah tercapai"
+	ketBentrokReservasi  = "Stok alat sudah direservasi sebelum jatuh tempo baru"
+	ketAlatTidakAda      = "Alat sudah dihapus"
+)
+
+// Request body pengajuan perpanjangan
+type perpanjanganRequest struct {
+	JatuhTempo string `json:"jatuh_tempo"`
+	Alasan     string `json:"alasan,omitempty"`
+}
+
+// AjukanPerpanjangan mengajukan jatuh tempo baru untuk peminjaman milik user
+// yang sedang login. Perpanjangan langsung disetujui jika tidak bentrok
+// dengan reservasi dan batas perpanjangan kategori belum tercapai, selain
+// itu masuk antrian admin. Peminjaman multi-item diperpanjang bersama untuk
+// semua item yang masih dipinjam.
+func (h *PeminjamanHandler) AjukanPerpanjangan(w http.ResponseWriter, r *http.Request) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req perpanjanganRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.JatuhTempo == "" {
+		utils.WriteError(w, http.StatusBadRequest, "jatuh_tempo wajib diisi")
+		return
+	}
+	jatuhTempo, err := utils.ParseWaktu(req.JatuhTempo, true)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "jatuh_tempo tidak valid: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		hasil      []models.Transaction
+		keterangan string
+	)
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		trans, err := h.transaksi.FindByID(ctx, transID)
+		if err != nil {
+			return err
+		}
+		if trans.UserID != aktor {
+			return errBukanPemilik
+		}
+		if !models.Dipinjam(trans.Status) {
+			return errTidakDipinjam
+		}
+		daftar, err := h.itemPerpanjangan(ctx, trans)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		keterangan = ""
+		for i := range daftar {
+			ket, err := h.periksaPerpanjangan(ctx, &daftar[i], jatuhTempo, now)
+			if err != nil {
+				return err
+			}
+			if keterangan == "" {
+				keterangan = ket
+			}
+		}
+
+		// Semua item grup mendapat pengajuan yang sama, disetujui atau
+		// menunggu bersama
+		p := models.Perpanjangan{
+			ID:             primitive.NewObjectID(),
+			JatuhTempoBaru: jatuhTempo,
+			Alasan:         strings.TrimSpace(req.Alasan),
+			Status:         models.PerpanjanganMenunggu,
+			Keterangan:     keterangan,
+			DiajukanPada:   now,
+		}
+		if keterangan == "" {
+			p.Status = models.PerpanjanganDisetujui
+			p.Otomatis = true
+			p.DiputuskanPada = &now
+		}
+		for i := range daftar {
+			t := &daftar[i]
+			sebelum := potret(t)
+			item := p
+			item.JatuhTempoLama = t.JatuhTempo
+			t.Perpanjangan = append(slices.Clone(t.Perpanjangan), item)
+			if p.Otomatis {
+				t.JatuhTempo = jatuhTempo
+			}
+			t.UpdatedAt = now
+			if err := h.transaksi.Update(ctx, t); err != nil {
+				return err
+			}
+			if err := catatPerubahan(ctx, h.audit, r, models.AuditPerpanjanganAjukan, "transactions", t.ID, sebelum, t); err != nil {
+				return err
+			}
+		}
+		hasil = daftar
+		return nil
+	})
+	if err != nil {
+		writePerpanjanganError(w, err, "Gagal mengajukan perpanjangan")
+		return
+	}
+
+	if keterangan != "" {
+		utils.WriteJSON(w, http.StatusAccepted, utils.JSONResponse{
+			Success: true,
+			Message: "Perpanjangan menunggu persetujuan admin: " + keterangan,
+			Data:    dataTransisi(hasil),
+		})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Perpanjangan disetujui, jatuh tempo sudah diperbarui",
+		Data:    dataTransisi(hasil),
+	})
+}
+
+// itemPerpanjangan mengembalikan transaksi yang ikut diperpanjang: transaksi
+// itu sendiri, atau semua item grup yang alatnya masih dipinjam
+func (h *PeminjamanHandler) itemPerpanjangan(ctx context.Context, trans *models.Transaction) ([]models.Transaction, error) {
+	if trans.GrupID == nil {
+		return []models.Transaction{*trans}, nil
+	}
+	list, _, err := h.transaksi.List(ctx, repository.TransactionFilter{
+		GrupID: trans.GrupID,
+		Status: []string{models.StatusDiambil, models.StatusSebagianKembali},
+	}, repository.ListOptions{})
+	return list, err
+}
+
+// periksaPerpanjangan memvalidasi jatuh tempo baru untuk satu transaksi.
+// Keterangan kosong berarti perpanjangan boleh disetujui otomatis, selain
+// itu berisi alasan pengajuan masuk antrian admin.
+func (h *PeminjamanHandler) periksaPerpanjangan(ctx context.Context, trans *models.Transaction, jatuhTempo, now time.Time) (string, error) {
+	if trans.PerpanjanganMenunggu() != nil {
+		return "", errPerpanjanganMenunggu
+	}
+	if trans.Terlambat(now) {
+		return "", errJatuhTempoLewat
+	}
+	if !jatuhTempo.After(trans.JatuhTempo) {
+		return "", errJatuhTempoBaru
+	}
+
+	alat, err := h.alat.FindByID(ctx, trans.AlatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ketAlatTidakAda, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Satu perpanjangan paling lama sama dengan lama pinjam kategori
+	hari, err := maksHariPinjam(ctx, h.kategori, alat.Kategori)
+	if err != nil {
+		return "", err
+	}
+	if jatuhTempo.After(trans.JatuhTempo.AddDate(0, 0, hari)) {
+		return "", errPerpanjanganTerlaluLama
+	}
+
+	maks, err := maksPerpanjangan(ctx, h.kategori, alat.Kategori)
+	if err != nil {
+		return "", err
+	}
+	if trans.JumlahPerpanjangan() >= maks {
+		return ketBatasPerpanjangan, nil
+	}
+
+	bentrok, err := h.bentrokPerpanjangan(ctx, trans, jatuhTempo)
+	if err != nil {
+		return "", err
+	}
+	if bentrok {
+		return ketBentrokReservasi, nil
+	}
+	return "", nil
+}
+
+// ListPerpanjangan (admin) menampilkan antrian peminjaman dengan pengajuan
+// perpanjangan yang menunggu keputusan.
+// Query: user_id, alat_id, grup_id, lab_id, from, to, page, limit, sort.
+// Role dengan lingkup lab hanya melihat transaksi alat di labnya.
+func (h *PeminjamanHandler) ListPerpanjangan(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterTransaksiAdmin(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
+		return
+	}
+	filter.Status = []string{models.StatusDiambil, models.StatusSebagianKembali}
+	filter.PerpanjanganMenunggu = true
+	h.listTransaksi(w, r, filter)
+}
+
+// Request body persetujuan perpanjangan oleh admin
+type setujuiPerpanjanganRequest struct {
+	AbaikanBentrok bool `json:"abaikan_bentrok"`
+}
+
+// SetujuiPerpanjangan (admin) menyetujui pengajuan perpanjangan yang
+// menunggu dan memperbarui jatuh tempo. Jatuh tempo baru yang bentrok
+// reservasi ditolak kecuali abaikan_bentrok diisi true.
+func (h *PeminjamanHandler) SetujuiPerpanjangan(w http.ResponseWriter, r *http.Request) {
+	var req setujuiPerpanjanganRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+	h.putuskanPerpanjangan(w, r, models.PerpanjanganDisetujui, "", req.AbaikanBentrok)
+}
+
+// TolakPerpanjangan (admin) menolak pengajuan perpanjangan dengan alasan
+// opsional. Jatuh tempo tidak berubah.
+func (h *PeminjamanHandler) TolakPerpanjangan(w http.ResponseWriter, r *http.Request) {
+	alasan, ok := decodeAlasan(w, r)
+	if !ok {
+		return
+	}
+	h.putuskanPerpanjangan(w, r, models.PerpanjanganDitolak, alasan, false)
+}
+
+// putuskanPerpanjangan menyimpan keputusan admin untuk pengajuan yang
+// menunggu, berlaku untuk semua item grup dengan pengajuan yang sama.
+// Ketersediaan diperiksa ulang saat menyetujui karena reservasi bisa dibuat
+// setelah pengajuan masuk antrian. Persetujuan yang mengabaikan bentrok
+// ditandai abaikan_bentrok sehingga tercatat di audit log.
+func (h *PeminjamanHandler) putuskanPerpanjangan(w http.ResponseWriter, r *http.Request, status, alasan string, abaikanBentrok bool) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var hasil []models.Transaction
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		trans, err := h.transaksi.FindByID(ctx, transID)
+		if err != nil {
+			return err
+		}
+		if err := h.akses.cekAlatTransaksi(ctx, r, trans.AlatID); err != nil {
+			return err
+		}
+		menunggu := trans.PerpanjanganMenunggu()
+		if menunggu == nil || !models.Dipinjam(trans.Status) {
+			return errTidakAdaPerpanjangan
+		}
+
+		daftar, err := h.itemPerpanjangan(ctx, trans)
+		if err != nil {
+			return err
+		}
+		daftar = slices.DeleteFunc(daftar, func(t models.Transaction) bool {
+			p := t.PerpanjanganMenunggu()
+			return p == nil || p.ID != menunggu.ID
+		})
+
+		now := time.Now()
+		for i := range daftar {
+			t := &daftar[i]
+			bentrok := false
+			if status == models.PerpanjanganDisetujui {
+				if bentrok, err = h.bentrokPerpanjangan(ctx, t, menunggu.JatuhTempoBaru); err != nil {
+					return err
+				}
+				if bentrok && !abaikanBentrok {
+					return errBentrokReservasi
+				}
+			}
+			sebelum := potret(t)
+			t.PutuskanPerpanjangan(status, aktor, now, alasan, bentrok)
+			if err := h.transaksi.Update(ctx, t); err != nil {
+				return err
+			}
+			aksi := models.AuditPerpanjanganSetujui
+			if status == models.PerpanjanganDitolak {
+				aksi = models.AuditPerpanjanganTolak
+			}
+			if err := catatPerubahan(ctx, h.audit, r, aksi, "transactions", t.ID, sebelum, t); err != nil {
+				return err
+			}
+		}
+		hasil = daftar
+		return nil
+	})
+	if err != nil {
+		writePerpanjanganError(w, err, "Gagal memproses perpanjangan")
+		return
+	}
+
+	pesan := "Perpanjangan disetujui, jatuh tempo sudah diperbarui"
+	if status == models.PerpanjanganDitolak {
+		pesan = "Perpanjangan ditolak"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: pesan,
+		Data:    dataTransisi(hasil),
+	})
+}
+
+// bentrokPerpanjangan memeriksa apakah sisa unit transaksi masih tersedia
+// sampai jatuh tempo baru, untuk persetujuan otomatis maupun oleh admin.
+// Alat dikunci seperti saat membuat reservasi, supaya reservasi paralel
+// tidak lolos bersamaan dengan perpanjangan. Alat yang sudah dihapus tidak
+// bisa bentrok.
+func (h *PeminjamanHandler) bentrokPerpanjangan(ctx context.Context, trans *models.Transaction, jatuhTempo time.Time) (bool, error) {
+	err := h.alat.Kunci(ctx, trans.AlatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	alat, err := h.alat.FindByID(ctx, trans.AlatID)
+	if err != nil {
+		return false, err
+	}
+	ok, err := h.ketersediaan.cukup(ctx, alat, trans.SisaPinjam(), trans.JatuhTempo, jatuhTempo, &trans.ID, nil)
+	return !ok, err
+}
+
+// writePerpanjanganError menerjemahkan error perpanjangan menjadi response
+// HTTP, error lain diteruskan ke writeTransisiError
+func writePerpanjanganError(w http.ResponseWriter, err error, pesanGagal string) {
+	switch {
+	case errors.Is(err, errTidakDipinjam):
+		utils.WriteError(w, http.StatusBadRequest, "Hanya peminjaman yang alatnya sedang dipinjam yang bisa diperpanjang")
+	case errors.Is(err, errPerpanjanganMenunggu):
+		utils.WriteError(w, http.StatusConflict, "Masih ada pengajuan perpanjangan yang menunggu persetujuan admin")
+	case errors.Is(err, errTidakAdaPerpanjangan):
+		utils.WriteError(w, http.StatusNotFound, "Tidak ada pengajuan perpanjangan yang menunggu")
+	case errors.Is(err, errJatuhTempoLewat):
+		utils.WriteError(w, http.StatusBadRequest, "Peminjaman sudah lewat jatuh tempo, kembalikan alat ke lab")
+	case errors.Is(err, errJatuhTempoBaru):
+		utils.WriteError(w, http.StatusBadRequest, "jatuh_tempo harus setelah jatuh tempo sekarang")
+	case errors.Is(err, errPerpanjanganTerlaluLama):
+		utils.WriteError(w, http.StatusBadRequest, "Perpanjangan melebihi maks_hari_pinjam kategori alat")
+	default:
+		writeTransisiError(w, err, pesanGagal)
+	}
+}
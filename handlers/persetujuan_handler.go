@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Error alur status transaksi, dipetakan ke status HTTP oleh writeTransisiError
+var (
+	errBukanPemilik     = errors.New("transaksi bukan milik user")
+	errJatuhTempoLewat  = errors.New("jatuh tempo sudah lewat")
+	errBentrokReservasi = errors.New("bentrok dengan reservasi")
+)
+
+// transisiError dikembalikan jika perubahan status tidak diizinkan state machine
+type transisiError struct {
+	dari, ke string
+}
+
+func (e *transisiError) Error() string {
+	return fmt.Sprintf("Transaksi berstatus %s tidak bisa diubah menjadi %s", e.dari, e.ke)
+}
+
+// Request body penolakan / pembatalan
+type alasanRequest struct {
+	Alasan string `json:"alasan,omitempty"`
+}
+
+// efekTransisi dijalankan di dalam transaksi database yang sama dengan
+// perubahan status, misalnya untuk memotong atau mengembalikan stok
+type efekTransisi func(ctx context.Context, trans *models.Transaction, now time.Time) error
+
+// aksiAuditTransisi memetakan status tujuan ke aksi di audit log
+var aksiAuditTransisi = map[string]string{
+	models.StatusDisetujui:    models.AuditPeminjamanSetujui,
+	models.StatusDitolak:      models.AuditPeminjamanTolak,
+	models.StatusDiambil:      models.AuditPeminjamanAmbil,
+	models.StatusDikembalikan: models.AuditPeminjamanKembalikan,
+	models.StatusDibatalkan:   models.AuditPeminjamanBatal,
+
+	models.StatusSebagianKembali: models.AuditPeminjamanKembalikan,
+}
+
+// grupTransisi adalah status tujuan yang berlaku untuk semua item
+// peminjaman multi-item sekaligus. Pengembalian tetap per item.
+var grupTransisi = map[string]bool{
+	models.StatusDisetujui:  true,
+	models.StatusDitolak:    true,
+	models.StatusDiambil:    true,
+	models.StatusDibatalkan: true,
+}
+
+// transisi memindahkan status transaksi secara atomik setelah memastikan
+// perubahan tersebut diizinkan, lalu mencatat aktor dan waktunya di
+// riwayat status dan audit log. Untuk peminjaman multi-item, semua item
+// yang berstatus sama ikut dipindahkan dan efek dijalankan per item,
+// sehingga satu item gagal membatalkan seluruh grup. Pengembalian yang
+// masih menyisakan unit dipinjam berakhir di SEBAGIAN_KEMBALI. Dengan
+// hanyaPemilik, transaksi milik user lain ditolak sebelum statusnya
+// diperiksa sehingga statusnya tidak bocor.
+func (h *PeminjamanHandler) transisi(ctx context.Context, r *http.Request, transID, aktor primitive.ObjectID, hanyaPemilik bool, ke, catatan string, efek efekTransisi) ([]models.Transaction, error) {
+	var hasil []models.Transaction
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		trans, err := h.transaksi.FindByID(ctx, transID)
+		if err != nil {
+			return err
+		}
+		if hanyaPemilik && trans.UserID != aktor {
+			return errBukanPemilik
+		}
+		if !models.BolehTransisi(trans.Status, ke) {
+			return &transisiError{dari: trans.Status, ke: ke}
+		}
+
+		daftar := []models.Transaction{*trans}
+		if trans.GrupID != nil && grupTransisi[ke] {
+			daftar, _, err = h.transaksi.List(ctx, repository.TransactionFilter{
+				GrupID: trans.GrupID,
+				Status: []string{trans.Status},
+			}, repository.ListOptions{})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		for i := range daftar {
+			t := &daftar[i]
+			sebelum := potret(t)
+			if efek != nil {
+				if err := efek(ctx, t, now); err != nil {
+					return err
+				}
+			}
+
+			tujuan := ke
+			if ke == models.StatusDikembalikan && t.SisaPinjam() > 0 {
+				tujuan = models.StatusSebagianKembali
+			}
+			t.UbahStatus(tujuan, aktor, now, catatan)
+			if err := h.transaksi.Update(ctx, t); err != nil {
+				return err
+			}
+			if err := catatPerubahan(ctx, h.audit, r, aksiAuditTransisi[tujuan], "transactions", t.ID, sebelum, t); err != nil {
+				return err
+			}
+		}
+		hasil = daftar
+		return nil
+	})
+	return hasil, err
+}
+
+// dataTransisi menyusun data response transisi: transaksi itu sendiri, atau
+// grup beserta semua itemnya untuk peminjaman multi-item
+func dataTransisi(list []models.Transaction) interface{} {
+	if len(list) == 1 && list[0].GrupID == nil {
+		return list[0]
+	}
+	var grupID *primitive.ObjectID
+	if len(list) > 0 {
+		grupID = list[0].GrupID
+	}
+	return map[string]interface{}{"grup_id": grupID, "item": list}
+}
+
+// dalamLingkup membungkus efek supaya transisi ditolak jika alat transaksi
+// dikelola lab lain dari lab tempat admin bertugas
+func (h *PeminjamanHandler) dalamLingkup(r *http.Request, efek efekTransisi) efekTransisi {
+	return func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+		if err := h.akses.cekAlatTransaksi(ctx, r, trans.AlatID); err != nil {
+			return err
+		}
+		return efek(ctx, trans, now)
+	}
+}
+
+// writeTransisiError menerjemahkan error transisi menjadi response HTTP
+func writeTransisiError(w http.ResponseWriter, err error, pesanGagal string) {
+	var te *transisiError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Transaksi tidak ditemukan")
+	case errors.As(err, &te):
+		utils.WriteError(w, http.StatusBadRequest, te.Error())
+	case errors.Is(err, repository.ErrStokTidakCukup):
+		utils.WriteError(w, http.StatusBadRequest, "Stok alat tidak mencukupi")
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+	case errors.Is(err, errBukanPemilik):
+		utils.WriteError(w, http.StatusForbidden, "Transaksi ini bukan milik Anda")
+	case errors.Is(err, errBentrokReservasi):
+		utils.WriteError(w, http.StatusConflict, "Stok alat sudah direservasi orang lain sebelum jatuh tempo peminjaman ini")
+	case errors.Is(err, errJatuhTempoLewat):
+		utils.WriteError(w, http.StatusBadRequest, "Jatuh tempo sudah lewat, batalkan lalu ajukan ulang")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, pesanGagal)
+	}
+}
+
+// parseTransisiRequest mengambil ID transaksi dari URL dan ID aktor dari token
+func parseTransisiRequest(w http.ResponseWriter, r *http.Request) (transID, aktor primitive.ObjectID, ok bool) {
+	transID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID transaksi tidak valid")
+		return transID, aktor, false
+	}
+	aktor, err = primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return transID, aktor, false
+	}
+	return transID, aktor, true
+}
+
+// decodeAlasan membaca body opsional berisi alasan
+func decodeAlasan(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req alasanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return "", false
+	}
+	defer r.Body.Close()
+	return strings.TrimSpace(req.Alasan), true
+}
+
+// unitIDs mengambil ID dari daftar unit
+func unitIDs(units []models.AlatUnit) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(units))
+	for i, u := range units {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+// SetujuiPeminjaman (admin) menyetujui pengajuan dan memesan unit alat
+func (h *PeminjamanHandler) SetujuiPeminjaman(w http.ResponseWriter, r *http.Request) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Unit dipesan dengan update bersyarat (status TERSEDIA) di dalam
+	// transaksi, sehingga dua persetujuan paralel tidak bisa memakai unit yang
+	// sama. Peminjaman juga tidak boleh memakai unit yang sudah direservasi
+	// orang lain sebelum jatuh temponya.
+	list, err := h.transisi(ctx, r, transID, aktor, false, models.StatusDisetujui, "",
+		h.dalamLingkup(r, func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+			awal, err := h.buku.saldo(ctx, trans.AlatID)
+			if err != nil {
+				return err
+			}
+			units, err := h.unit.Pinjamkan(ctx, trans.AlatID, trans.ID, trans.Jumlah)
+			if err != nil {
+				return err
+			}
+			trans.UnitIDs = unitIDs(units)
+			_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+				Jenis:         models.MutasiPinjam,
+				UnitIDs:       trans.UnitIDs,
+				TransactionID: &trans.ID,
+				Keterangan:    "Peminjaman disetujui",
+			})
+			if err != nil {
+				return err
+			}
+
+			alat, err := h.alat.FindByID(ctx, trans.AlatID)
+			if err != nil {
+				return err
+			}
+			ok, err := h.ketersediaan.cukup(ctx, alat, trans.Jumlah, now, trans.JatuhTempo, &trans.ID, nil)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errBentrokReservasi
+			}
+			return nil
+		}))
+	if err != nil {
+		writeTransisiError(w, err, "Gagal menyetujui peminjaman")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Peminjaman disetujui, unit alat sudah dipesan",
+		Data:    dataTransisi(list),
+	})
+}
+
+// TolakPeminjaman (admin) menolak pengajuan dengan alasan opsional
+func (h *PeminjamanHandler) TolakPeminjaman(w http.ResponseWriter, r *http.Request) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+	alasan, ok := decodeAlasan(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.transisi(ctx, r, transID, aktor, false, models.StatusDitolak, alasan,
+		h.dalamLingkup(r, func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+			trans.AlasanPenolakan = alasan
+			return nil
+		}))
+	if err != nil {
+		writeTransisiError(w, err, "Gagal menolak peminjaman")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Peminjaman ditolak",
+		Data:    dataTransisi(list),
+	})
+}
+
+// AmbilPeminjaman (admin) mencatat bahwa alat sudah diserahkan ke peminjam
+func (h *PeminjamanHandler) AmbilPeminjaman(w http.ResponseWriter, r *http.Request) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.transisi(ctx, r, transID, aktor, false, models.StatusDiambil, "",
+		h.dalamLingkup(r, func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+			if !trans.JatuhTempo.IsZero() && now.After(trans.JatuhTempo) {
+				return errJatuhTempoLewat
+			}
+			trans.TanggalPinjam = now
+			return nil
+		}))
+	if err != nil {
+		writeTransisiError(w, err, "Gagal mencatat pengambilan alat")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Alat sudah diambil peminjam",
+		Data:    dataTransisi(list),
+	})
+}
+
+// BatalkanPeminjamanSaya membatalkan pengajuan milik user yang sedang login
+func (h *PeminjamanHandler) BatalkanPeminjamanSaya(w http.ResponseWriter, r *http.Request) {
+	h.batalkan(w, r, true)
+}
+
+// BatalkanPeminjaman (admin) membatalkan pengajuan milik siapa pun
+func (h *PeminjamanHandler) BatalkanPeminjaman(w http.ResponseWriter, r *http.Request) {
+	h.batalkan(w, r, false)
+}
+
+// batalkan mengubah status menjadi DIBATALKAN dan melepas unit jika sudah disetujui
+func (h *PeminjamanHandler) batalkan(w http.ResponseWriter, r *http.Request, hanyaPemilik bool) {
+	transID, aktor, ok := parseTransisiRequest(w, r)
+	if !ok {
+		return
+	}
+	alasan, ok := decodeAlasan(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	efek := func(ctx context.Context, trans *models.Transaction, now time.Time) error {
+		if !models.MenahanStok(trans.Status) {
+			return nil
+		}
+		awal, err := h.buku.saldo(ctx, trans.AlatID)
+		if err != nil {
+			return err
+		}
+		if err := h.unit.Kembalikan(ctx, trans.UnitIDs); err != nil {
+			return err
+		}
+		_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+			Jenis:         models.MutasiKembali,
+			UnitIDs:       trans.UnitIDs,
+			TransactionID: &trans.ID,
+			Keterangan:    "Peminjaman dibatalkan",
+		})
+		return err
+	}
+	if !hanyaPemilik {
+		efek = h.dalamLingkup(r, efek)
+	}
+	list, err := h.transisi(ctx, r, transID, aktor, hanyaPemilik, models.StatusDibatalkan, alasan, efek)
+	if err != nil {
+		writeTransisiError(w, err, "Gagal membatalkan peminjaman")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Peminjaman dibatalkan",
+		Data:    dataTransisi(list),
+	})
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// formatPhone menerima nomor telepon 8-15 digit, boleh diawali "+"
+var formatPhone = regexp.MustCompile(`^\+?[0-9]{8,15}$`)
+
+// ProfilHandler mengelola endpoint akun milik user yang sedang login
+type ProfilHandler struct {
+	users        repository.UserRepository
+	jurusan      repository.JurusanRepository
+	transactions repository.TransactionRepository
+	denda        repository.DendaRepository
+	audit        repository.AuditRepository
+	tx           repository.Transactor
+	files        storage.Storage
+}
+
+// NewProfilHandler membuat ProfilHandler dari repository di store.
+// files dipakai untuk menyimpan foto profil.
+func NewProfilHandler(store *repository.Store, files storage.Storage) *ProfilHandler {
+	return &ProfilHandler{
+		users:        store.Users,
+		jurusan:      store.Jurusan,
+		transactions: store.Transactions,
+		denda:        store.Denda,
+		audit:        store.Audit,
+		tx:           store.Tx,
+		files:        files,
+	}
+}
+
+// Request body untuk mengubah profil. Field yang tidak dikirim tidak diubah.
+type updateProfilRequest struct {
+	Nama    *string `json:"nama"`
+	Phone   *string `json:"phone"`
+	Jurusan *string `json:"jurusan"`
+}
+
+// statistikPeminjaman merangkum peminjaman dan denda user
+type statistikPeminjaman struct {
+	// PeminjamanAktif menghitung transaksi yang disetujui atau sedang dibawa
+	PeminjamanAktif int64 `json:"peminjaman_aktif"`
+	Terlambat       int64 `json:"terlambat"`
+	DendaBelumLunas int64 `json:"denda_belum_lunas"`
+	// TotalDendaBelumLunas adalah sisa tagihan semua denda yang belum lunas
+	TotalDendaBelumLunas int64 `json:"total_denda_belum_lunas"`
+}
+
+// profilResponse adalah data user beserta statistik peminjamannya
+type profilResponse struct {
+	*models.User
+	Statistik statistikPeminjaman `json:"statistik"`
+}
+
+// userSaya mengambil user yang sedang login. Jika gagal, response error
+// sudah ditulis dan nilai kembaliannya nil.
+func (h *ProfilHandler) userSaya(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.User {
+	userID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return nil
+	}
+
+	user, err := h.users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+		return nil
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data user")
+		return nil
+	}
+	user.PasswordHash = ""
+	return user
+}
+
+// hitungStatistik menghitung peminjaman aktif, keterlambatan dan denda user
+func (h *ProfilHandler) hitungStatistik(ctx context.Context, userID primitive.ObjectID, now time.Time) (statistikPeminjaman, error) {
+	var stat statistikPeminjaman
+	var err error
+
+	_, stat.PeminjamanAktif, err = h.transactions.List(ctx, repository.TransactionFilter{
+		UserID: &userID,
+		Status: []string{models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali},
+	}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return stat, err
+	}
+
+	_, stat.Terlambat, err = h.transactions.List(ctx, repository.TransactionFilter{
+		UserID:            &userID,
+		Status:            []string{models.StatusDiambil, models.StatusSebagianKembali},
+		JatuhTempoSebelum: now,
+	}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return stat, err
+	}
+
+	denda, total, err := h.denda.List(ctx, repository.DendaFilter{
+		UserID: &userID,
+		Status: models.DendaBelumLunas,
+	}, repository.ListOptions{})
+	if err != nil {
+		return stat, err
+	}
+	stat.DendaBelumLunas = total
+	for _, d := range denda {
+		stat.TotalDendaBelumLunas += d.Sisa()
+	}
+	return stat, nil
+}
+
+// ProfilSaya menampilkan data akun user yang sedang login beserta
+// statistik peminjaman dan dendanya
+func (h *ProfilHandler) ProfilSaya(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := h.userSaya(ctx, w, r)
+	if user == nil {
+		return
+	}
+
+	stat, err := h.hitungStatistik(ctx, user.ID, time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghitung statistik peminjaman")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    profilResponse{User: user, Statistik: stat},
+	})
+}
+
+// UpdateProfilSaya mengubah nama, phone dan jurusan user yang sedang login.
+// Setiap perubahan dicatat di audit log.
+func (h *ProfilHandler) UpdateProfilSaya(w http.ResponseWriter, r *http.Request) {
+	var req updateProfilRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user := h.userSaya(ctx, w, r)
+	if user == nil {
+		return
+	}
+
+	sebelum := map[string]interface{}{}
+	sesudah := map[string]interface{}{}
+	ubah := func(field string, lama *string, baru string) {
+		if *lama == baru {
+			return
+		}
+		sebelum[field] = *lama
+		sesudah[field] = baru
+		*lama = baru
+	}
+
+	if req.Nama != nil {
+		nama := strings.TrimSpace(*req.Nama)
+		if nama == "" {
+			utils.WriteError(w, http.StatusBadRequest, "Nama tidak boleh kosong")
+			return
+		}
+		ubah("nama", &user.Nama, nama)
+	}
+	if req.Phone != nil {
+		phone := strings.NewReplacer(" ", "", "-", "").Replace(*req.Phone)
+		if phone != "" && !formatPhone.MatchString(phone) {
+			utils.WriteError(w, http.StatusBadRequest, "Nomor telepon harus 8-15 digit, boleh diawali +")
+			return
+		}
+		ubah("phone", &user.Phone, phone)
+	}
+	if req.Jurusan != nil {
+		jurusan, err := h.jurusan.FindByNama(ctx, strings.TrimSpace(*req.Jurusan))
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusBadRequest, "Jurusan tidak terdaftar")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa jurusan")
+			return
+		}
+		ubah("jurusan", &user.Jurusan, jurusan.Nama)
+	}
+
+	if len(sesudah) == 0 {
+		utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+			Success: true,
+			Message: "Tidak ada perubahan profil",
+			Data:    user,
+		})
+		return
+	}
+
+	user.UpdatedAt = time.Now()
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.users.UpdateProfil(ctx, user); err != nil {
+			return err
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditUpdateProfil, "users", user.ID, sebelum, sesudah)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate profil")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Profil berhasil diupdate",
+		Data:    user,
+	})
+}
+
+// UploadAvatar mengganti foto profil user yang sedang login. Foto dikirim
+// sebagai form multipart dengan field "avatar".
+func (h *ProfilHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maksUkuranFoto+1<<20)
+	if err := r.ParseMultipartForm(maksUkuranFoto); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Form upload tidak valid atau terlalu besar")
+		return
+	}
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "File avatar wajib diupload")
+		return
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	user := h.userSaya(ctx, w, r)
+	if user == nil {
+		return
+	}
+
+	url, pesan := simpanFoto(ctx, h.files, file, header.Size)
+	if pesan != "" {
+		utils.WriteError(w, http.StatusBadRequest, pesan)
+		return
+	}
+
+	lama := user.AvatarURL
+	user.AvatarURL = url
+	user.UpdatedAt = time.Now()
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.users.UpdateProfil(ctx, user); err != nil {
+			return err
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditUpdateAvatar, "users", user.ID,
+			map[string]interface{}{"avatar_url": lama},
+			map[string]interface{}{"avatar_url": url})
+	})
+	if err != nil {
+		h.hapusFile(ctx, url)
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan avatar")
+		return
+	}
+
+	// Foto lama tidak dipakai lagi
+	if lama != "" {
+		h.hapusFile(ctx, lama)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Avatar berhasil diupdate",
+		Data:    user,
+	})
+}
+
+// hapusFile menghapus file di storage. Kegagalan hanya dicatat di log
+// karena data user sudah tersimpan.
+func (h *ProfilHandler) hapusFile(ctx context.Context, url string) {
+	if err := h.files.Hapus(ctx, url); err != nil {
+		log.Printf("gagal menghapus file %s: %v", url, err)
+	}
+}
@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReservasiHandler mengelola reservasi alat untuk waktu mendatang
+type ReservasiHandler struct {
+	alat         repository.AlatRepository
+	unit         repository.AlatUnitRepository
+	transaksi    repository.TransactionRepository
+	reservasi    repository.ReservasiRepository
+	kategori     repository.KategoriRepository
+	denda        repository.DendaRepository
+	users        repository.UserRepository
+	audit        repository.AuditRepository
+	tx           repository.Transactor
+	ketersediaan ketersediaan
+	akses        aksesLab
+	buku         bukuStok
+	aturan       aturanPeminjaman
+}
+
+// NewReservasiHandler membuat ReservasiHandler dari repository di store
+func NewReservasiHandler(store *repository.Store) *ReservasiHandler {
+	return &ReservasiHandler{
+		alat:         store.Alat,
+		unit:         store.Unit,
+		transaksi:    store.Transactions,
+		reservasi:    store.Reservasi,
+		kategori:     store.Kategori,
+		denda:        store.Denda,
+		users:        store.Users,
+		audit:        store.Audit,
+		tx:           store.Tx,
+		ketersediaan: newKetersediaan(store),
+		akses:        newAksesLab(store),
+		buku:         newBukuStok(store),
+		aturan:       newAturanPeminjaman(store),
+	}
+}
+
+// Request body reservasi
+type reservasiRequest struct {
+	AlatID  string `json:"alat_id"`
+	Jumlah  int    `json:"jumlah"`
+	Mulai   string `json:"mulai"`
+	Selesai string `json:"selesai"`
+}
+
+// Error alur reservasi
+var (
+	errReservasiBentrok      = errors.New("jumlah alat tidak tersedia pada rentang waktu tersebut")
+	errReservasiTidakAktif   = errors.New("reservasi tidak aktif")
+	errDiluarWaktuReservasi  = errors.New("di luar waktu reservasi")
+	errBukanPemilikReservasi = errors.New("reservasi bukan milik user")
+)
+
+// writeReservasiError menerjemahkan error alur reservasi menjadi response HTTP
+func writeReservasiError(w http.ResponseWriter, err error, pesanGagal string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Reservasi atau alat tidak ditemukan")
+	case errors.Is(err, errLuarLingkupLab):
+		utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+	case errors.Is(err, errReservasiBentrok):
+		utils.WriteError(w, http.StatusConflict, "Jumlah alat tidak tersedia pada rentang waktu tersebut")
+	case errors.Is(err, errReservasiTidakAktif):
+		utils.WriteError(w, http.StatusBadRequest, "Reservasi sudah diambil atau dibatalkan")
+	case errors.Is(err, errDiluarWaktuReservasi):
+		utils.WriteError(w, http.StatusBadRequest, "Alat hanya bisa diambil di dalam rentang waktu reservasi")
+	case errors.Is(err, errBukanPemilikReservasi):
+		utils.WriteError(w, http.StatusForbidden, "Reservasi ini bukan milik Anda")
+	case errors.Is(err, repository.ErrStokTidakCukup):
+		utils.WriteError(w, http.StatusBadRequest, "Stok alat tidak mencukupi")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, pesanGagal)
+	}
+}
+
+// BuatReservasi memesan alat untuk rentang waktu tertentu
+func (h *ReservasiHandler) BuatReservasi(w http.ResponseWriter, r *http.Request) {
+	var req reservasiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.AlatID == "" || req.Jumlah <= 0 || req.Mulai == "" || req.Selesai == "" {
+		utils.WriteError(w, http.StatusBadRequest, "alat_id, jumlah, mulai dan selesai wajib, jumlah > 0")
+		return
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	alatID, err := primitive.ObjectIDFromHex(req.AlatID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "alat_id tidak valid")
+		return
+	}
+
+	mulai, err := utils.ParseWaktu(req.Mulai, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "mulai tidak valid: "+err.Error())
+		return
+	}
+	selesai, err := utils.ParseWaktu(req.Selesai, true)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "selesai tidak valid: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	if !mulai.After(now) || !selesai.After(mulai) {
+		utils.WriteError(w, http.StatusBadRequest, "mulai harus di masa depan dan selesai setelah mulai")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByID(ctx, userObjID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa data user")
+		return
+	}
+	if !user.EmailTerverifikasi {
+		utils.WriteErrorKode(w, http.StatusForbidden, models.KodeEmailBelumTerverifikasi,
+			"Email belum diverifikasi, reservasi belum bisa dibuat")
+		return
+	}
+
+	adaTunggakan, err := punyaDendaBelumLunas(ctx, h.denda, userObjID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa denda user")
+		return
+	}
+	if adaTunggakan {
+		utils.WriteErrorKode(w, http.StatusForbidden, models.KodeDendaBelumLunas,
+			"Masih ada denda yang belum dibayar, reservasi baru diblokir")
+		return
+	}
+
+	alat, err := h.alat.FindByID(ctx, alatID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && alat.Dihapus()) {
+		utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+		return
+	}
+
+	maksHari, err := maksHariPinjam(ctx, h.kategori, alat.Kategori)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil aturan kategori")
+		return
+	}
+	if selesai.After(mulai.AddDate(0, 0, maksHari)) {
+		utils.WriteError(w, http.StatusBadRequest,
+			fmt.Sprintf("Maksimal lama peminjaman kategori ini %d hari", maksHari))
+		return
+	}
+
+	reservasi := models.Reservasi{
+		ID:        primitive.NewObjectID(),
+		UserID:    userObjID,
+		AlatID:    alatID,
+		Jumlah:    req.Jumlah,
+		Mulai:     mulai,
+		Selesai:   selesai,
+		Status:    models.ReservasiAktif,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// Kunci alat dulu supaya dua reservasi paralel untuk alat yang sama
+	// tidak bisa sama-sama lolos cek ketersediaan
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.alat.Kunci(ctx, alatID); err != nil {
+			return err
+		}
+		alat, err := h.alat.FindByID(ctx, alatID)
+		if err != nil {
+			return err
+		}
+		if alat.Dihapus() {
+			return repository.ErrNotFound
+		}
+		// Aturan diperiksa saat reservasi dibuat dan diulang saat diambil
+		if err := h.aturan.periksa(ctx, user, []itemPeminjaman{{alat: alat, jumlah: req.Jumlah}}); err != nil {
+			return err
+		}
+		ok, err := h.ketersediaan.cukup(ctx, alat, req.Jumlah, mulai, selesai, nil, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errReservasiBentrok
+		}
+		if err := h.reservasi.Create(ctx, &reservasi); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditReservasiBuat, "reservasi", reservasi.ID, nil, reservasi)
+	})
+	var tolak *penolakan
+	if errors.As(err, &tolak) {
+		writePenolakan(w, tolak)
+		return
+	}
+	if err != nil {
+		writeReservasiError(w, err, "Gagal membuat reservasi")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Reservasi berhasil dibuat",
+		Data:    reservasi,
+	})
+}
+
+// ReservasiSaya menampilkan reservasi milik user yang sedang login
+func (h *ReservasiHandler) ReservasiSaya(w http.ResponseWriter, r *http.Request) {
+	userObjID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	h.listReservasi(w, r, repository.ReservasiFilter{UserID: &userObjID})
+}
+
+// ListReservasi (admin) menampilkan semua reservasi, bisa difilter
+// ?status=, ?user_id=, ?alat_id=, ?lab_id= dan rentang ?from= / ?to=. Role
+// dengan lingkup lab hanya melihat reservasi alat di labnya.
+func (h *ReservasiHandler) ListReservasi(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseObjectIDQuery(r, "user_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	alatID, err := parseObjectIDQuery(r, "alat_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := repository.ReservasiFilter{UserID: userID, AlatID: alatID}
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
+		return
+	}
+	h.listReservasi(w, r, filter)
+}
+
+// listReservasi menambahkan filter status dan rentang waktu dari query lalu
+// mengirim satu halaman reservasi
+func (h *ReservasiHandler) listReservasi(w http.ResponseWriter, r *http.Request, filter repository.ReservasiFilter) {
+	opts, err := parseListOptions(r, "mulai", "created_at")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dari, sampai, err := parseRentangQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Status = strings.ToUpper(r.URL.Query().Get("status"))
+	filter.Dari, filter.Sampai = dari, sampai
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, total, err := h.reservasi.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data reservasi")
+		return
+	}
+
+	writeList(w, list, opts, total)
+}
+
+// BatalkanReservasiSaya membatalkan reservasi milik user yang sedang login
+func (h *ReservasiHandler) BatalkanReservasiSaya(w http.ResponseWriter, r *http.Request) {
+	resID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID reservasi tidak valid")
+		return
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reservasi *models.Reservasi
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		reservasi, err = h.reservasi.FindByID(ctx, resID)
+		if err != nil {
+			return err
+		}
+		if reservasi.UserID != userObjID {
+			return errBukanPemilikReservasi
+		}
+		if reservasi.Status != models.ReservasiAktif {
+			return errReservasiTidakAktif
+		}
+		sebelum := potret(reservasi)
+		reservasi.Status = models.ReservasiDibatalkan
+		reservasi.UpdatedAt = time.Now()
+		if err := h.reservasi.Update(ctx, reservasi); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditReservasiBatal, "reservasi", reservasi.ID, sebelum, reservasi)
+	})
+	if err != nil {
+		writeReservasiError(w, err, "Gagal membatalkan reservasi")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Reservasi dibatalkan",
+		Data:    reservasi,
+	})
+}
+
+// AmbilReservasi (admin) mengubah reservasi menjadi transaksi DIAMBIL saat
+// peminjam datang mengambil alat
+func (h *ReservasiHandler) AmbilReservasi(w http.ResponseWriter, r *http.Request) {
+	resID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID reservasi tidak valid")
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "User ID invalid di token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var trans models.Transaction
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		reservasi, err := h.reservasi.FindByID(ctx, resID)
+		if err != nil {
+			return err
+		}
+		if err := h.akses.cekAlatTransaksi(ctx, r, reservasi.AlatID); err != nil {
+			return err
+		}
+		if reservasi.Status != models.ReservasiAktif {
+			return errReservasiTidakAktif
+		}
+		sebelum := potret(reservasi)
+
+		now := time.Now()
+		if now.Before(reservasi.Mulai) || !now.Before(reservasi.Selesai) {
+			return errDiluarWaktuReservasi
+		}
+
+		// Reservasi yang diambil menjadi peminjaman, jadi aturan peminjaman
+		// diperiksa ulang dengan kondisi peminjam saat ini
+		peminjam, err := h.users.FindByID(ctx, reservasi.UserID)
+		if err != nil {
+			return err
+		}
+		alat, err := h.alat.FindByID(ctx, reservasi.AlatID)
+		if err != nil {
+			return err
+		}
+		if err := h.aturan.periksa(ctx, peminjam, []itemPeminjaman{{alat: alat, jumlah: reservasi.Jumlah}}); err != nil {
+			return err
+		}
+
+		transID := primitive.NewObjectID()
+		awal, err := h.buku.saldo(ctx, reservasi.AlatID)
+		if err != nil {
+			return err
+		}
+		units, err := h.unit.Pinjamkan(ctx, reservasi.AlatID, transID, reservasi.Jumlah)
+		if err != nil {
+			return err
+		}
+		_, err = h.buku.catat(ctx, r, awal, models.MutasiStok{
+			Jenis:         models.MutasiPinjam,
+			UnitIDs:       unitIDs(units),
+			TransactionID: &transID,
+			Keterangan:    "Diambil dari reservasi " + reservasi.ID.Hex(),
+		})
+		if err != nil {
+			return err
+		}
+
+		trans = models.Transaction{
+			ID:            transID,
+			UserID:        reservasi.UserID,
+			AlatID:        reservasi.AlatID,
+			Jumlah:        reservasi.Jumlah,
+			UnitIDs:       unitIDs(units),
+			TanggalPinjam: now,
+			JatuhTempo:    reservasi.Selesai,
+			Status:        models.StatusDiambil,
+			RiwayatStatus: []models.PerubahanStatus{{
+				Ke:      models.StatusDiambil,
+				Oleh:    adminID,
+				Waktu:   now,
+				Catatan: "Diambil dari reservasi " + reservasi.ID.Hex(),
+			}},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := h.transaksi.Create(ctx, &trans); err != nil {
+			return err
+		}
+
+		reservasi.Status = models.ReservasiDiambil
+		reservasi.TransactionID = &trans.ID
+		reservasi.UpdatedAt = now
+		if err := h.reservasi.Update(ctx, reservasi); err != nil {
+			return err
+		}
+		if err := catatPerubahan(ctx, h.audit, r, models.AuditReservasiAmbil, "reservasi", reservasi.ID, sebelum, reservasi); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditPeminjamanAmbil, "transactions", trans.ID, nil, trans)
+	})
+	var tolak *penolakan
+	if errors.As(err, &tolak) {
+		writePenolakan(w, tolak)
+		return
+	}
+	if err != nil {
+		writeReservasiError(w, err, "Gagal memproses pengambilan reservasi")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Reservasi diambil, transaksi peminjaman dibuat",
+		Data:    trans,
+	})
+}
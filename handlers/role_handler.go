@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// formatNamaRole membatasi nama role ke huruf kecil, angka dan garis bawah
+var formatNamaRole = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// Error penghapusan role
+var (
+	errRoleDipakai = errors.New("role masih dipakai user")
+	errRoleBawaan  = errors.New("role bawaan tidak bisa dihapus")
+)
+
+// RoleHandler mengelola role dan permission-nya (super admin)
+type RoleHandler struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+	audit repository.AuditRepository
+	tx    repository.Transactor
+}
+
+// NewRoleHandler membuat RoleHandler dari repository di store
+func NewRoleHandler(store *repository.Store) *RoleHandler {
+	return &RoleHandler{roles: store.Roles, users: store.Users, audit: store.Audit, tx: store.Tx}
+}
+
+// Request body untuk membuat/mengupdate role. Nama tidak bisa diubah
+// karena dipakai sebagai role di data user.
+type roleRequest struct {
+	Nama        string   `json:"nama"`
+	Deskripsi   string   `json:"deskripsi"`
+	Permissions []string `json:"permissions"`
+	// LingkupLab opsional, jika true permission role hanya berlaku di lab
+	// tempat user menjadi staff
+	LingkupLab *bool `json:"lingkup_lab,omitempty"`
+}
+
+// validasiPermissions membuang permission ganda dan mengembalikan
+// permission pertama yang tidak dikenal
+func validasiPermissions(perms []string) ([]string, string) {
+	hasil := make([]string, 0, len(perms))
+	ada := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		p = strings.TrimSpace(p)
+		if !models.PermissionValid(p) {
+			return nil, p
+		}
+		if !ada[p] {
+			ada[p] = true
+			hasil = append(hasil, p)
+		}
+	}
+	return hasil, ""
+}
+
+// roleAktor mengambil role user yang sedang login
+func roleAktor(ctx context.Context, roles repository.RoleRepository, r *http.Request) (*models.Role, error) {
+	return roles.FindByNama(ctx, middleware.GetRoleFromContext(r))
+}
+
+// ListPermission menampilkan semua permission yang bisa diberikan ke role
+func (h *RoleHandler) ListPermission(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    models.SemuaPermission,
+	})
+}
+
+// ListRole menampilkan semua role beserta permission-nya
+func (h *RoleHandler) ListRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := h.roles.List(ctx)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// CreateRole membuat role baru
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Nama = strings.TrimSpace(req.Nama)
+	if !formatNamaRole.MatchString(req.Nama) {
+		utils.WriteError(w, http.StatusBadRequest, "Nama role 2-32 karakter huruf kecil, angka atau _, diawali huruf")
+		return
+	}
+	perms, salah := validasiPermissions(req.Permissions)
+	if salah != "" {
+		utils.WriteError(w, http.StatusBadRequest, "Permission tidak dikenal: "+salah)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	role := models.Role{
+		ID:          primitive.NewObjectID(),
+		Nama:        req.Nama,
+		Deskripsi:   strings.TrimSpace(req.Deskripsi),
+		Permissions: perms,
+		LingkupLab:  req.LingkupLab != nil && *req.LingkupLab,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.roles.Create(ctx, &role); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditRoleCreate, "roles", role.ID, nil, role)
+	})
+	if errors.Is(err, repository.ErrDuplikat) {
+		utils.WriteError(w, http.StatusBadRequest, "Role sudah ada")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Role berhasil ditambahkan",
+		Data:    role,
+	})
+}
+
+// UpdateRole mengganti deskripsi dan permission role. Role super_admin
+// tidak bisa diubah supaya selalu ada yang bisa mengatur role.
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	perms, salah := validasiPermissions(req.Permissions)
+	if salah != "" {
+		utils.WriteError(w, http.StatusBadRequest, "Permission tidak dikenal: "+salah)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	role, err := h.roles.FindByID(ctx, objID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, "Role tidak ditemukan")
+		return
+	}
+	if role.Nama == models.RoleSuperAdmin {
+		utils.WriteError(w, http.StatusBadRequest, "Role super_admin tidak bisa diubah")
+		return
+	}
+
+	sebelum := potret(role)
+	if req.Deskripsi != "" {
+		role.Deskripsi = strings.TrimSpace(req.Deskripsi)
+	}
+	role.Permissions = perms
+	if req.LingkupLab != nil {
+		role.LingkupLab = *req.LingkupLab
+	}
+	role.UpdatedAt = time.Now()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.roles.Update(ctx, role); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditRoleUpdate, "roles", role.ID, sebelum, role)
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengupdate role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Role berhasil diupdate",
+		Data:    role,
+	})
+}
+
+// DeleteRole menghapus role buatan super admin yang tidak dipegang user mana pun
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	objID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		role, err := h.roles.FindByID(ctx, objID)
+		if err != nil {
+			return err
+		}
+		if role.Bawaan {
+			return errRoleBawaan
+		}
+		_, dipakai, err := h.users.List(ctx, repository.UserFilter{Role: role.Nama}, repository.ListOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if dipakai > 0 {
+			return errRoleDipakai
+		}
+		if err := h.roles.Delete(ctx, objID); err != nil {
+			return err
+		}
+		return catatPerubahan(ctx, h.audit, r, models.AuditRoleDelete, "roles", objID, potret(role), nil)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Role tidak ditemukan")
+		return
+	case errors.Is(err, errRoleBawaan):
+		utils.WriteError(w, http.StatusBadRequest, "Role bawaan tidak bisa dihapus")
+		return
+	case errors.Is(err, errRoleDipakai):
+		utils.WriteError(w, http.StatusConflict, "Role masih dipakai user, pindahkan user ke role lain dulu")
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menghapus role")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Role berhasil dihapus",
+	})
+}
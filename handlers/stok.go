@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// saldoStok adalah stok satu alat pada satu saat, dipakai sebagai titik
+// awal perhitungan selisih mutasi
+type saldoStok struct {
+	alatID   primitive.ObjectID
+	total    int
+	tersedia int
+}
+
+// bukuStok mencatat setiap perubahan stok alat ke buku besar mutasi stok
+type bukuStok struct {
+	mutasi repository.MutasiStokRepository
+	alat   repository.AlatRepository
+}
+
+func newBukuStok(store *repository.Store) bukuStok {
+	return bukuStok{mutasi: store.MutasiStok, alat: store.Alat}
+}
+
+// saldo membaca stok alat saat ini. Alat yang belum dibuat atau sudah
+// dihapus bersaldo nol.
+func (b bukuStok) saldo(ctx context.Context, alatID primitive.ObjectID) (saldoStok, error) {
+	alat, err := b.alat.FindByID(ctx, alatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return saldoStok{alatID: alatID}, nil
+	}
+	if err != nil {
+		return saldoStok{}, err
+	}
+	return saldoStok{alatID: alatID, total: alat.StokTotal, tersedia: alat.StokTersedia}, nil
+}
+
+// catat menyimpan mutasi m dari saldo awal ke stok alat saat ini, lalu
+// mengembalikan saldo terbaru untuk mutasi berikutnya. Jenis, unit dan
+// rujukan diisi pemanggil. Mutasi tanpa unit dan tanpa perubahan stok
+// dilewati. Panggil di dalam transaksi yang sama dengan perubahan unitnya.
+func (b bukuStok) catat(ctx context.Context, r *http.Request, awal saldoStok, m models.MutasiStok) (saldoStok, error) {
+	akhir, err := b.saldo(ctx, awal.alatID)
+	if err != nil {
+		return awal, err
+	}
+	m.PerubahanTotal = akhir.total - awal.total
+	m.PerubahanTersedia = akhir.tersedia - awal.tersedia
+	if len(m.UnitIDs) == 0 && m.PerubahanTotal == 0 && m.PerubahanTersedia == 0 {
+		return akhir, nil
+	}
+
+	m.ID = primitive.NewObjectID()
+	m.AlatID = awal.alatID
+	m.StokTotal = akhir.total
+	m.StokTersedia = akhir.tersedia
+	m.AktorID, _ = primitive.ObjectIDFromHex(middleware.GetUserIDFromContext(r))
+	m.Waktu = time.Now()
+	return akhir, b.mutasi.Catat(ctx, &m)
+}
+
+// mutasiUnit mencatat mutasi stok akibat perubahan status unit oleh admin
+func mutasiUnit(unit *models.AlatUnit, statusLama string) models.MutasiStok {
+	m := models.MutasiStok{UnitIDs: []primitive.ObjectID{unit.ID}}
+	switch {
+	case unit.Status == models.UnitDihapus:
+		m.Jenis = models.MutasiPenghapusan
+	case !models.UnitBeredar(unit.Status) && models.UnitBeredar(statusLama):
+		m.Jenis = models.MutasiRusak
+	case unit.Status == models.UnitTersedia && statusLama == models.UnitPerbaikan:
+		m.Jenis = models.MutasiPerbaikan
+	default:
+		m.Jenis = models.MutasiPenyesuaian
+	}
+	m.Keterangan = "Status unit " + unit.KodeAset + " diubah dari " + statusLama + " ke " + unit.Status
+	return m
+}
+
+// StokHandler menampilkan buku besar stok dan merekonsiliasi stok alat
+type StokHandler struct {
+	alat         repository.AlatRepository
+	unit         repository.AlatUnitRepository
+	transactions repository.TransactionRepository
+	audit        repository.AuditRepository
+	tx           repository.Transactor
+	buku         bukuStok
+	akses        aksesLab
+}
+
+// NewStokHandler membuat StokHandler dari repository di store
+func NewStokHandler(store *repository.Store) *StokHandler {
+	return &StokHandler{
+		alat:         store.Alat,
+		unit:         store.Unit,
+		transactions: store.Transactions,
+		audit:        store.Audit,
+		tx:           store.Tx,
+		buku:         newBukuStok(store),
+		akses:        newAksesLab(store),
+	}
+}
+
+// ListMutasi (admin) menampilkan buku besar stok dari yang terbaru.
+// Query: alat_id, lab_id, jenis, transaction_id, from, to, page, limit, sort.
+func (h *StokHandler) ListMutasi(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "waktu")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	alatID, err := parseObjectIDQuery(r, "alat_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	transID, err := parseObjectIDQuery(r, "transaction_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dari, sampai, err := parseRentangQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := repository.MutasiStokFilter{
+		AlatID:        alatID,
+		Jenis:         strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("jenis"))),
+		TransactionID: transID,
+		Dari:          dari,
+		Sampai:        sampai,
+	}
+	if !h.akses.batasiAlat(w, r, &filter.AlatIDs) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, total, err := h.buku.mutasi.List(ctx, filter, opts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil mutasi stok")
+		return
+	}
+
+	writeList(w, list, opts, total)
+}
+
+// SelisihStok adalah hasil rekonsiliasi satu alat yang stoknya tidak
+// sinkron dengan unit dan transaksi aktifnya
+type SelisihStok struct {
+	AlatID primitive.ObjectID  `json:"alat_id"`
+	Nama   string              `json:"nama"`
+	LabID  *primitive.ObjectID `json:"lab_id,omitempty"`
+	// StokTotal dan StokTersedia adalah angka yang tercatat di data alat
+	StokTotal    int `json:"stok_total"`
+	StokTersedia int `json:"stok_tersedia"`
+	// UnitBeredar adalah jumlah unit TERSEDIA dan DIPINJAM
+	UnitBeredar int `json:"unit_beredar"`
+	// Ditahan adalah jumlah unit yang dipakai transaksi DISETUJUI / DIAMBIL
+	Ditahan            int `json:"ditahan"`
+	TersediaSeharusnya int `json:"tersedia_seharusnya"`
+	// UnitLepas adalah unit DIPINJAM yang tidak dipegang transaksi aktif
+	UnitLepas []primitive.ObjectID `json:"unit_lepas,omitempty"`
+	// TransaksiKurangUnit adalah transaksi aktif yang unitnya kurang dari jumlah pinjaman
+	TransaksiKurangUnit []primitive.ObjectID `json:"transaksi_kurang_unit,omitempty"`
+	// Hasil diisi jika rekonsiliasi dijalankan dengan perbaikan
+	Hasil *HasilRekonsiliasi `json:"hasil,omitempty"`
+
+	aktif  []models.Transaction
+	kurang map[primitive.ObjectID]int
+}
+
+// HasilRekonsiliasi adalah stok alat setelah diperbaiki. MasihSelisih
+// bernilai true jika unit tersedia tidak cukup untuk transaksi aktif dan
+// perlu ditangani manual.
+type HasilRekonsiliasi struct {
+	StokTotal    int  `json:"stok_total"`
+	StokTersedia int  `json:"stok_tersedia"`
+	MasihSelisih bool `json:"masih_selisih"`
+}
+
+// sinkron bernilai true jika stok alat sudah sesuai unit dan transaksinya
+func (s SelisihStok) sinkron() bool {
+	return s.StokTotal == s.UnitBeredar && s.StokTersedia == s.TersediaSeharusnya &&
+		len(s.UnitLepas) == 0 && len(s.TransaksiKurangUnit) == 0
+}
+
+// periksa membandingkan stok tercatat alat dengan unit dan transaksi yang
+// sedang menahan stok
+func (h *StokHandler) periksa(ctx context.Context, alat *models.Alat) (SelisihStok, error) {
+	s := SelisihStok{
+		AlatID:       alat.ID,
+		Nama:         alat.Nama,
+		LabID:        alat.LabID,
+		StokTotal:    alat.StokTotal,
+		StokTersedia: alat.StokTersedia,
+		kurang:       map[primitive.ObjectID]int{},
+	}
+
+	filter := repository.TransactionFilter{
+		AlatID: &alat.ID,
+		Status: []string{models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali},
+	}
+	aktif, _, err := h.transactions.List(ctx, filter, repository.ListOptions{})
+	if err != nil {
+		return s, err
+	}
+	s.aktif = aktif
+
+	units, err := h.unit.ListByAlat(ctx, alat.ID)
+	if err != nil {
+		return s, err
+	}
+	dipegang := map[primitive.ObjectID]int{}
+	for _, u := range units {
+		if models.UnitBeredar(u.Status) {
+			s.UnitBeredar++
+		}
+		if u.Status != models.UnitDipinjam {
+			continue
+		}
+		if u.TransactionID == nil || !slices.ContainsFunc(aktif, func(t models.Transaction) bool { return t.ID == *u.TransactionID }) {
+			s.UnitLepas = append(s.UnitLepas, u.ID)
+			continue
+		}
+		dipegang[*u.TransactionID]++
+	}
+
+	for _, t := range aktif {
+		s.Ditahan += t.SisaPinjam()
+		if n := t.SisaPinjam() - dipegang[t.ID]; n > 0 {
+			s.TransaksiKurangUnit = append(s.TransaksiKurangUnit, t.ID)
+			s.kurang[t.ID] = n
+		}
+	}
+	s.TersediaSeharusnya = s.UnitBeredar - s.Ditahan
+	return s, nil
+}
+
+// perbaiki melepas unit yang tidak dipegang transaksi aktif, memesankan
+// unit tersedia untuk transaksi aktif yang kekurangan unit, lalu menghitung
+// ulang stok alat. Setiap langkah dicatat di buku besar stok.
+func (h *StokHandler) perbaiki(ctx context.Context, r *http.Request, s *SelisihStok) error {
+	awal := saldoStok{alatID: s.AlatID, total: s.StokTotal, tersedia: s.StokTersedia}
+	if err := h.unit.Kembalikan(ctx, s.UnitLepas); err != nil {
+		return err
+	}
+	if err := h.unit.HitungUlang(ctx, s.AlatID); err != nil {
+		return err
+	}
+	saldo, err := h.buku.catat(ctx, r, awal, models.MutasiStok{
+		Jenis:      models.MutasiPenyesuaian,
+		UnitIDs:    s.UnitLepas,
+		Keterangan: "Rekonsiliasi stok",
+	})
+	if err != nil {
+		return err
+	}
+
+	masihSelisih := false
+	for _, t := range s.aktif {
+		n := s.kurang[t.ID]
+		if n == 0 {
+			continue
+		}
+		if saldo.tersedia < n {
+			masihSelisih = true
+			continue
+		}
+		units, err := h.unit.Pinjamkan(ctx, s.AlatID, t.ID, n)
+		if err != nil {
+			return err
+		}
+		ids := unitIDs(units)
+		t.UnitIDs = append(t.UnitIDs, ids...)
+		t.UpdatedAt = time.Now()
+		if err := h.transactions.Update(ctx, &t); err != nil {
+			return err
+		}
+		id := t.ID
+		saldo, err = h.buku.catat(ctx, r, saldo, models.MutasiStok{
+			Jenis:         models.MutasiPinjam,
+			UnitIDs:       ids,
+			TransactionID: &id,
+			Keterangan:    "Rekonsiliasi stok: unit untuk transaksi aktif",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Hasil = &HasilRekonsiliasi{StokTotal: saldo.total, StokTersedia: saldo.tersedia, MasihSelisih: masihSelisih}
+	return catatAudit(ctx, h.audit, r, models.AuditStokRekonsiliasi, "alat", s.AlatID,
+		map[string]interface{}{"stok_total": s.StokTotal, "stok_tersedia": s.StokTersedia},
+		map[string]interface{}{"stok_total": saldo.total, "stok_tersedia": saldo.tersedia})
+}
+
+// Rekonsiliasi (admin) menghitung ulang stok tersedia setiap alat dari unit
+// beredar dikurangi transaksi yang sedang menahan stok, lalu melaporkan
+// alat yang selisih. GET hanya melaporkan, POST sekaligus memperbaiki
+// selisihnya. Query: alat_id, lab_id.
+func (h *StokHandler) Rekonsiliasi(w http.ResponseWriter, r *http.Request) {
+	perbaiki := r.Method == http.MethodPost
+	alatID, err := parseObjectIDQuery(r, "alat_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var terlihat []primitive.ObjectID
+	if !h.akses.batasiAlat(w, r, &terlihat) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var daftar []models.Alat
+	if alatID != nil {
+		alat, err := h.alat.FindByID(ctx, *alatID)
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "Alat tidak ditemukan")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+			return
+		}
+		daftar = []models.Alat{*alat}
+	} else {
+		daftar, _, err = h.alat.List(ctx, repository.AlatFilter{TermasukDihapus: true}, repository.ListOptions{})
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data alat")
+			return
+		}
+	}
+
+	selisih := []SelisihStok{}
+	for i := range daftar {
+		alat := &daftar[i]
+		if terlihat != nil && !slices.Contains(terlihat, alat.ID) {
+			if alatID != nil {
+				utils.WriteError(w, http.StatusForbidden, pesanLuarLingkupLab)
+				return
+			}
+			continue
+		}
+
+		var s SelisihStok
+		err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			// Alat dikunci supaya persetujuan atau pengembalian paralel
+			// tidak mengubah unit di tengah pemeriksaan
+			if err := h.alat.Kunci(ctx, alat.ID); err != nil {
+				return err
+			}
+			terkini, err := h.alat.FindByID(ctx, alat.ID)
+			if err != nil {
+				return err
+			}
+			s, err = h.periksa(ctx, terkini)
+			if err != nil || s.sinkron() || !perbaiki {
+				return err
+			}
+			return h.perbaiki(ctx, r, &s)
+		})
+		if errors.Is(err, repository.ErrNotFound) {
+			// Alat terhapus saat rekonsiliasi berjalan
+			continue
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "Gagal merekonsiliasi stok alat")
+			return
+		}
+		if !s.sinkron() {
+			selisih = append(selisih, s)
+		}
+	}
+
+	pesan := "Stok semua alat sudah sesuai"
+	if len(selisih) > 0 {
+		pesan = strconv.Itoa(len(selisih)) + " alat stoknya tidak sesuai"
+	}
+	if len(selisih) > 0 && perbaiki {
+		// Hanya alat yang benar-benar sinkron yang disebut sudah diperbaiki
+		masih := 0
+		for _, s := range selisih {
+			if s.Hasil == nil || s.Hasil.MasihSelisih {
+				masih++
+			}
+		}
+		pesan += ", " + strconv.Itoa(len(selisih)-masih) + " sudah diperbaiki"
+		if masih > 0 {
+			pesan += ", " + strconv.Itoa(masih) + " masih selisih dan perlu ditangani manual"
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: pesan,
+		Data:    selisih,
+	})
+}
@@ -3,56 +3,100 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
-	"SIPAK/config"
 	"SIPAK/models"
+	"SIPAK/repository"
 	"SIPAK/utils"
 
 	"github.com/go-chi/chi/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserHandler mengelola endpoint admin terkait user
-type UserHandler struct{}
+type UserHandler struct {
+	users    repository.UserRepository
+	roles    repository.RoleRepository
+	sessions repository.SessionRepository
+	audit    repository.AuditRepository
+	tx       repository.Transactor
+}
+
+// NewUserHandler membuat UserHandler dari repository di store
+func NewUserHandler(store *repository.Store) *UserHandler {
+	return &UserHandler{users: store.Users, roles: store.Roles, sessions: store.Sessions, audit: store.Audit, tx: store.Tx}
+}
 
 // Request untuk update role user
 type updateRoleRequest struct {
 	Role string `json:"role"`
 }
 
-// ListUsers (admin) menampilkan semua user
+// ListUsers (admin) menampilkan semua user. Query: role, jurusan, nim
+// (awalan NIM), page, limit, sort.
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "nama", "email", "role", "nim", "created_at")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := r.URL.Query()
+	filter := repository.UserFilter{
+		Role:    q.Get("role"),
+		Jurusan: q.Get("jurusan"),
+		NIM:     strings.ToUpper(strings.TrimSpace(q.Get("nim"))),
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := config.UserCollection.Find(ctx, bson.M{})
+	users, total, err := h.users.List(ctx, filter, opts)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data user")
 		return
 	}
-	defer cursor.Close(ctx)
-
-	var users []models.User
-	if err := cursor.All(ctx, &users); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal decode data user")
-		return
-	}
 
 	// Hapus password hash sebelum dikirim ke client
 	for i := range users {
 		users[i].PasswordHash = ""
 	}
 
+	writeList(w, users, opts, total)
+}
+
+// GetUserByNIM (admin) mencari satu user dengan NIM yang persis sama
+func (h *UserHandler) GetUserByNIM(w http.ResponseWriter, r *http.Request) {
+	nim := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "nim")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByNIM(ctx, nim)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data user")
+		return
+	}
+
+	user.PasswordHash = ""
+
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
-		Data:    users,
+		Data:    user,
 	})
 }
 
-// UpdateUserRole (admin) mengubah role user (admin / mahasiswa)
+// UpdateUserRole (admin) mengubah role user ke salah satu role yang terdaftar.
+// Admin hanya bisa memberi atau mencabut role yang permission-nya juga ia
+// miliki, sehingga pemegang user:manage tidak bisa menaikkan hak aksesnya
+// sendiri. Semua session user dicabut sehingga role lama di access token
+// tidak bisa dipakai lagi dan role baru berlaku setelah login ulang.
 func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	userID, err := primitive.ObjectIDFromHex(idParam)
@@ -68,24 +112,118 @@ func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	if req.Role != "admin" && req.Role != "mahasiswa" {
-		utils.WriteError(w, http.StatusBadRequest, "Role harus 'admin' atau 'mahasiswa'")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	baru, err := h.roles.FindByNama(ctx, req.Role)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusBadRequest, "Role tidak terdaftar")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa role")
+		return
+	}
+
+	user, err := h.users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data user")
+		return
+	}
+
+	aktor, err := roleAktor(ctx, h.roles, r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa role")
+		return
+	}
+	perms := append([]string(nil), baru.Permissions...)
+	if lama, err := h.roles.FindByNama(ctx, user.Role); err == nil {
+		perms = append(perms, lama.Permissions...)
+	}
+	if !aktor.Mencakup(perms) {
+		utils.WriteError(w, http.StatusForbidden, "Tidak bisa mengatur role dengan hak akses melebihi role sendiri")
+		return
+	}
+
+	// Role ikut tertulis di access token, jadi semua session dicabut supaya
+	// user login ulang dengan role barunya
+	var n int64
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.users.UpdateRole(ctx, userID, baru.Nama); err != nil {
+			return err
+		}
+		n = 0
+		if user.Role != baru.Nama {
+			var err error
+			n, err = h.sessions.RevokeSemuaUser(ctx, userID, primitive.NilObjectID, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditUpdateRole, "users", userID,
+			map[string]interface{}{"role": user.Role},
+			map[string]interface{}{"role": baru.Nama, "jumlah_session": n})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal update role user")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Role user berhasil diupdate, user perlu login ulang",
+		Data:    map[string]interface{}{"jumlah_session": n},
+	})
+}
+
+// RevokeSessions (admin) mencabut semua session login user, misalnya saat
+// akun dicurigai bocor. Access token yang masih berlaku langsung ditolak.
+func (h *UserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "ID user tidak valid")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = config.UserCollection.UpdateByID(ctx, userID, bson.M{
-		"$set": bson.M{"role": req.Role},
+	if _, err := h.users.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "User tidak ditemukan")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mengambil data user")
+		return
+	}
+
+	var n int64
+	err = h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		n, err = h.sessions.RevokeSemuaUser(ctx, userID, primitive.NilObjectID, time.Now())
+		if err != nil {
+			return err
+		}
+		return catatAudit(ctx, h.audit, r, models.AuditRevokeSession, "users", userID,
+			nil, map[string]interface{}{"jumlah_session": n})
 	})
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Gagal update role user")
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal mencabut session user")
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
 		Success: true,
-		Message: "Role user berhasil diupdate",
+		Message: "Semua session user berhasil dicabut",
+		Data:    map[string]interface{}{"jumlah_session": n},
 	})
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"SIPAK/config"
+	"SIPAK/mail"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Request body untuk verifikasi email
+type verifikasiEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// Request body untuk kirim ulang email verifikasi
+type kirimUlangVerifikasiRequest struct {
+	Email string `json:"email"`
+}
+
+// domainEmailDiizinkan mengecek domain email terhadap DomainEmailRegistrasi.
+// Subdomain tidak ikut diizinkan supaya "@x.univ.ac.id" tidak lolos hanya
+// karena "univ.ac.id" terdaftar.
+func domainEmailDiizinkan(email string) bool {
+	if len(config.AppConfig.DomainEmailRegistrasi) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.Contains(config.AppConfig.DomainEmailRegistrasi, domain)
+}
+
+// siapkanVerifikasi membuat token verifikasi baru dan menyimpan hash-nya di user
+func siapkanVerifikasi(user *models.User, now time.Time) (string, error) {
+	token, err := buatTokenAcak()
+	if err != nil {
+		return "", err
+	}
+	user.VerifikasiHash = hashToken(token)
+	user.VerifikasiExpiresAt = now.Add(config.AppConfig.VerifikasiEmailTTL)
+	return token, nil
+}
+
+// isiEmailVerifikasi menyusun email berisi token verifikasi email
+func isiEmailVerifikasi(user *models.User, token string) mail.Pesan {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Halo %s,\n\n", user.Nama)
+	b.WriteString("Terima kasih sudah mendaftar di SIPAK. Verifikasi email Anda sebelum meminjam alat.\n\n")
+	if config.AppConfig.VerifikasiEmailURL != "" {
+		fmt.Fprintf(&b, "Buka link berikut untuk verifikasi:\n%s?token=%s\n\n",
+			config.AppConfig.VerifikasiEmailURL, url.QueryEscape(token))
+	} else {
+		fmt.Fprintf(&b, "Token verifikasi email Anda:\n%s\n\n", token)
+	}
+	fmt.Fprintf(&b, "Token berlaku %d jam.\n", int(config.AppConfig.VerifikasiEmailTTL/time.Hour))
+	return mail.Pesan{Ke: user.Email, Subjek: "Verifikasi email SIPAK", Isi: b.String()}
+}
+
+// emailTerverifikasi memeriksa apakah email user sudah diverifikasi
+func emailTerverifikasi(ctx context.Context, repo repository.UserRepository, userID primitive.ObjectID) (bool, error) {
+	user, err := repo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailTerverifikasi, nil
+}
+
+// VerifikasiEmail menandai email user terverifikasi dengan token dari email
+func (h *AuthHandler) VerifikasiEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifikasiEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Token wajib diisi")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user *models.User
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = h.users.Verifikasi(ctx, hashToken(req.Token), time.Now())
+		if err != nil {
+			return err
+		}
+		return catatAuditOleh(ctx, h.audit, r, user.ID, models.AuditVerifikasiEmail, "users", user.ID,
+			map[string]interface{}{"email_terverifikasi": false},
+			map[string]interface{}{"email": user.Email, "email_terverifikasi": true})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteError(w, http.StatusBadRequest, "Token verifikasi tidak valid atau kadaluarsa")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal verifikasi email")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+		Success: true,
+		Message: "Email berhasil diverifikasi",
+		Data:    user,
+	})
+}
+
+// KirimUlangVerifikasi mengirim token verifikasi baru. Token lama tidak
+// berlaku lagi. Response selalu sama supaya tidak bisa dipakai untuk
+// menebak email yang terdaftar.
+func (h *AuthHandler) KirimUlangVerifikasi(w http.ResponseWriter, r *http.Request) {
+	var req kirimUlangVerifikasiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Body tidak valid")
+		return
+	}
+	defer r.Body.Close()
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		utils.WriteError(w, http.StatusBadRequest, "Email wajib diisi")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	respon := utils.JSONResponse{
+		Success: true,
+		Message: "Jika email terdaftar dan belum diverifikasi, email verifikasi sudah dikirim",
+	}
+
+	user, err := h.users.FindByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusOK, respon)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa email")
+		return
+	}
+	if user.EmailTerverifikasi {
+		utils.WriteJSON(w, http.StatusOK, respon)
+		return
+	}
+
+	token, err := siapkanVerifikasi(user, time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal membuat token")
+		return
+	}
+	err = h.users.SetVerifikasi(ctx, user.ID, user.VerifikasiHash, user.VerifikasiExpiresAt)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Baru saja diverifikasi oleh request lain
+		utils.WriteJSON(w, http.StatusOK, respon)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Gagal menyimpan token verifikasi")
+		return
+	}
+
+	h.kirimTanpaBocor(ctx, "verifikasi", isiEmailVerifikasi(user, token))
+
+	utils.WriteJSON(w, http.StatusOK, respon)
+}
@@ -0,0 +1,154 @@
+// Package jobs berisi pekerjaan latar yang dijalankan berkala oleh server
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// intervalPurgeAlat adalah jeda antar pemeriksaan alat yang lewat masa retensi
+const intervalPurgeAlat = time.Hour
+
+// JalankanPurgeAlat menghapus permanen alat yang sudah lewat masa retensi
+// setiap intervalPurgeAlat sampai ctx selesai. Retensi 0 mematikan purge.
+func JalankanPurgeAlat(ctx context.Context, store *repository.Store, retensi time.Duration) {
+	if retensi <= 0 {
+		return
+	}
+	ticker := time.NewTicker(intervalPurgeAlat)
+	defer ticker.Stop()
+	for {
+		n, err := PurgeAlat(ctx, store, time.Now().Add(-retensi))
+		if err != nil {
+			log.Printf("Purge alat gagal: %v", err)
+		} else if n > 0 {
+			log.Printf("%d alat dihapus permanen setelah masa retensi", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeAlat menghapus permanen alat yang dihapus sebelum batas beserta
+// unitnya, lalu mengembalikan jumlah alat yang dihapus. Nama alat disalin
+// ke transaksinya supaya riwayat tetap terbaca. Alat yang masih punya
+// peminjaman berjalan dilewati sampai semuanya dikembalikan.
+func PurgeAlat(ctx context.Context, store *repository.Store, batas time.Time) (int, error) {
+	daftar, _, err := store.Alat.List(ctx, repository.AlatFilter{HanyaDihapus: true}, repository.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, alat := range daftar {
+		if !alat.DeletedAt.Before(batas) {
+			continue
+		}
+		err := store.Tx.WithTransaction(ctx, func(ctx context.Context) error {
+			return purgeSatuAlat(ctx, store, alat.ID, batas)
+		})
+		if errors.Is(err, errMasihDipinjam) || errors.Is(err, errSudahDipulihkan) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Alasan purge satu alat dilewati tanpa dianggap gagal
+var (
+	errMasihDipinjam   = errors.New("alat masih dipinjam")
+	errSudahDipulihkan = errors.New("alat sudah dipulihkan")
+)
+
+// statusBelumSelesai adalah status transaksi yang menahan purge alat
+var statusBelumSelesai = []string{models.StatusDiajukan, models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali}
+
+func purgeSatuAlat(ctx context.Context, store *repository.Store, alatID primitive.ObjectID, batas time.Time) error {
+	if err := store.Alat.Kunci(ctx, alatID); err != nil {
+		return err
+	}
+	// Dibaca ulang karena alat bisa dipulihkan setelah daftar diambil
+	alat, err := store.Alat.FindByID(ctx, alatID)
+	if err != nil {
+		return err
+	}
+	if !alat.Dihapus() || !alat.DeletedAt.Before(batas) {
+		return errSudahDipulihkan
+	}
+
+	_, berjalan, err := store.Transactions.List(ctx, repository.TransactionFilter{
+		AlatID: &alatID,
+		Status: statusBelumSelesai,
+	}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if berjalan > 0 {
+		return errMasihDipinjam
+	}
+
+	transaksi, _, err := store.Transactions.List(ctx, repository.TransactionFilter{AlatID: &alatID}, repository.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range transaksi {
+		transaksi[i].NamaAlat = alat.Nama
+		if err := store.Transactions.Update(ctx, &transaksi[i]); err != nil {
+			return err
+		}
+	}
+
+	units, err := store.Unit.ListByAlat(ctx, alatID)
+	if err != nil {
+		return err
+	}
+	if err := store.Unit.DeleteByAlat(ctx, alatID); err != nil {
+		return err
+	}
+	if err := store.Alat.Delete(ctx, alatID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	unitIDs := make([]primitive.ObjectID, len(units))
+	for i, u := range units {
+		unitIDs[i] = u.ID
+	}
+	err = store.MutasiStok.Catat(ctx, &models.MutasiStok{
+		ID:                primitive.NewObjectID(),
+		AlatID:            alatID,
+		Jenis:             models.MutasiPenghapusan,
+		UnitIDs:           unitIDs,
+		PerubahanTotal:    -alat.StokTotal,
+		PerubahanTersedia: -alat.StokTersedia,
+		Keterangan:        "Alat dihapus permanen setelah masa retensi",
+		Waktu:             now,
+	})
+	if err != nil {
+		return err
+	}
+	// Purge dijalankan sistem, jadi aktor audit dibiarkan kosong
+	return store.Audit.Catat(ctx, &models.AuditLog{
+		ID:       primitive.NewObjectID(),
+		Aksi:     models.AuditAlatPurge,
+		Target:   "alat",
+		TargetID: alatID,
+		Sebelum:  map[string]interface{}{"nama": alat.Nama, "deleted_at": alat.DeletedAt},
+		Waktu:    now,
+	})
+}
@@ -0,0 +1,93 @@
+// Package mail mengirim email ke user, misalnya token reset password.
+// Implementasinya bisa diganti lewat konfigurasi: SMTP untuk produksi dan
+// Log untuk development / test tanpa server email.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pesan adalah satu email teks biasa
+type Pesan struct {
+	Ke     string
+	Subjek string
+	Isi    string
+}
+
+// Mailer mengirim email
+type Mailer interface {
+	Kirim(ctx context.Context, pesan Pesan) error
+}
+
+// SMTP mengirim email lewat server SMTP dengan autentikasi PLAIN
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	dari string
+}
+
+// NewSMTP membuat SMTP untuk server host:port. Jika user kosong, email
+// dikirim tanpa autentikasi.
+func NewSMTP(host string, port int, user, password, dari string) *SMTP {
+	s := &SMTP{addr: net.JoinHostPort(host, fmt.Sprint(port)), dari: dari}
+	if user != "" {
+		s.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return s
+}
+
+// Kirim mengirim pesan ke server SMTP
+func (s *SMTP) Kirim(ctx context.Context, pesan Pesan) error {
+	return smtp.SendMail(s.addr, s.auth, s.dari, []string{pesan.Ke}, format(s.dari, pesan))
+}
+
+// format menyusun email lengkap dengan header sesuai RFC 5322
+func format(dari string, pesan Pesan) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", dari)
+	fmt.Fprintf(&b, "To: %s\r\n", pesan.Ke)
+	fmt.Fprintf(&b, "Subject: %s\r\n", pesan.Subjek)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(pesan.Isi, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// Log tidak benar-benar mengirim email, hanya menuliskannya ke file atau
+// log server supaya token bisa dibaca saat development
+type Log struct {
+	mu   sync.Mutex
+	dari string
+	out  io.Writer
+}
+
+// NewLog membuat Log yang menambahkan email ke file path. Jika path kosong,
+// email ditulis ke log standar.
+func NewLog(path, dari string) (*Log, error) {
+	if path == "" {
+		return &Log{dari: dari, out: log.Writer()}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{dari: dari, out: f}, nil
+}
+
+// Kirim menulis pesan ke file / log
+func (l *Log) Kirim(ctx context.Context, pesan Pesan) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := fmt.Fprintf(l.out, "%s\r\n----\r\n", format(l.dari, pesan))
+	return err
+}
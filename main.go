@@ -1,99 +1,69 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"SIPAK/config"
-	"SIPAK/handlers"
-	"SIPAK/middleware"
-	"SIPAK/utils"
-
-	chimw "github.com/go-chi/chi/v5/middleware"
-	"github.com/rs/cors"
-
-	"github.com/go-chi/chi/v5"
+	"SIPAK/jobs"
+	"SIPAK/mail"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/routes"
+	"SIPAK/storage"
 )
 
 func main() {
 	// 1. Load konfigurasi dari .env
 	config.LoadConfig()
 
-	// 2. Konek ke MongoDB Atlas
-	config.ConnectMongo()
-
-	// 3. Setup router Chi
-	r := chi.NewRouter()
-
-	r.Use(chimw.Logger)
-	r.Use(chimw.Recoverer)
-
-	r.Use(cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300,
-	}).Handler)
-
-
-	// Tambah middleware API key global untuk semua endpoint /api
-	r.Route("/api", func(api chi.Router) {
-		// Semua endpoint di bawah /api harus pakai API Key
-		api.Use(middleware.APIKeyMiddleware)
-
-		// ==== AUTH (tanpa JWT, tapi wajib API Key) ====
-		authHandler := &handlers.AuthHandler{}
-		api.Post("/auth/register", authHandler.Register)
-		api.Post("/auth/login", authHandler.Login)
-
-		// ==== ENDPOINT YANG BUTUH JWT ====
-		api.Group(func(priv chi.Router) {
-			// Semua endpoint di group ini butuh JWT
-			priv.Use(middleware.AuthMiddleware)
-
-			// ----- Alat -----
-			alatHandler := &handlers.AlatHandler{}
-			priv.Get("/alat", alatHandler.ListAlat)
-			priv.Get("/alat/{id}", alatHandler.GetAlatByID)
-
-			// ----- Peminjaman -----
-			pinjamHandler := &handlers.PeminjamanHandler{}
-			priv.Post("/peminjaman", pinjamHandler.PinjamAlat)
-			priv.Post("/pengembalian/{id}", pinjamHandler.KembalikanAlat)
-			priv.Get("/peminjaman/me", pinjamHandler.ListTransaksiUser)
-			priv.Get("/riwayat", pinjamHandler.RiwayatSaya)
+	// 2. Siapkan penyimpanan data (MongoDB Atlas atau in-memory)
+	var store *repository.Store
+	if config.AppConfig.DBDriver == "memory" {
+		store = repository.NewMemoryStore()
+		fmt.Println("⚠️  Memakai penyimpanan in-memory, data hilang saat server mati")
+	} else {
+		config.ConnectMongo()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := repository.MigrateMongo(ctx, config.MongoDB); err != nil {
+			log.Fatalf("Gagal migrasi data MongoDB: %v", err)
+		}
+		cancel()
+		store = repository.NewMongoStore(config.MongoClient, config.MongoDB)
+	}
 
-			// ----- Admin only group -----
-			priv.Group(func(admin chi.Router) {
-				admin.Use(middleware.AdminOnly)
+	if err := siapkanSuperAdmin(store, config.AppConfig.SuperAdminEmails); err != nil {
+		log.Fatalf("Gagal menyiapkan super admin: %v", err)
+	}
 
-				// CRUD alat admin
-				admin.Post("/admin/alat", alatHandler.CreateAlat)
-				admin.Put("/admin/alat/{id}", alatHandler.UpdateAlat)
-				admin.Delete("/admin/alat/{id}", alatHandler.DeleteAlat)
+	// 3. Siapkan folder file upload
+	files, err := storage.NewLocal(config.AppConfig.UploadDir, "/uploads")
+	if err != nil {
+		log.Fatalf("Gagal menyiapkan folder upload: %v", err)
+	}
 
-				// User management admin
-				userHandler := &handlers.UserHandler{}
-				admin.Get("/admin/users", userHandler.ListUsers)
-				admin.Patch("/admin/users/{id}/role", userHandler.UpdateUserRole)
+	// 4. Siapkan pengirim email
+	var mailer mail.Mailer
+	if config.AppConfig.MailDriver == "smtp" {
+		mailer = mail.NewSMTP(config.AppConfig.SMTPHost, config.AppConfig.SMTPPort,
+			config.AppConfig.SMTPUser, config.AppConfig.SMTPPassword, config.AppConfig.MailFrom)
+	} else {
+		mailer, err = mail.NewLog(config.AppConfig.MailLogFile, config.AppConfig.MailFrom)
+		if err != nil {
+			log.Fatalf("Gagal menyiapkan log email: %v", err)
+		}
+		fmt.Println("⚠️  Email tidak dikirim, hanya ditulis ke log")
+	}
 
-				// Semua transaksi (admin)
-				admin.Get("/admin/peminjaman", pinjamHandler.ListSemuaTransaksi)
-				admin.Get("/admin/riwayat", pinjamHandler.RiwayatSemua)
-			})
-		})
-	})
+	// 5. Jalankan pekerjaan latar
+	go jobs.JalankanPurgeAlat(context.Background(), store, config.AppConfig.RetensiAlatDihapus)
 
-	// Root endpoint sederhana untuk cek status API
-	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
-			Success: true,
-			Message: "SIPAK API berjalan 🚀",
-		})
-	})
+	// 6. Setup router Chi
+	r := routes.NewRouter(store, files, mailer)
 
 	addr := ":" + config.AppConfig.Port
 	fmt.Println("Server jalan di", addr)
@@ -101,3 +71,29 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// siapkanSuperAdmin menjadikan user dengan email di emails sebagai super_admin.
+// Email yang belum terdaftar hanya dicatat di log.
+func siapkanSuperAdmin(store *repository.Store, emails []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, email := range emails {
+		user, err := store.Users.FindByEmail(ctx, email)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Printf("SUPER_ADMIN_EMAILS: user %s belum terdaftar", email)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role == models.RoleSuperAdmin {
+			continue
+		}
+		if err := store.Users.UpdateRole(ctx, user.ID, models.RoleSuperAdmin); err != nil {
+			return err
+		}
+		log.Printf("User %s dijadikan super_admin", email)
+	}
+	return nil
+}
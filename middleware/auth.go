@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"SIPAK/config"
+	"SIPAK/repository"
 	"SIPAK/utils"
 )
 
@@ -13,8 +16,9 @@ import (
 type contextKey string
 
 const (
-	ContextUserID contextKey = "userID"
-	ContextRole   contextKey = "role"
+	ContextUserID    contextKey = "userID"
+	ContextRole      contextKey = "role"
+	ContextSessionID contextKey = "sessionID"
 )
 
 // APIKeyMiddleware memeriksa header X-API-Key
@@ -29,46 +33,78 @@ func APIKeyMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// AuthMiddleware memeriksa JWT di header Authorization: Bearer <token>
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			utils.WriteError(w, http.StatusUnauthorized, "Authorization header tidak ditemukan")
-			return
-		}
+// AuthMiddleware memeriksa JWT di header Authorization: Bearer <token>.
+// Token ditolak jika jti-nya bukan access token terakhir dari session yang
+// masih aktif, sehingga logout, rotasi refresh token dan pencabutan oleh
+// admin langsung berlaku.
+func AuthMiddleware(sessions repository.SessionRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				utils.WriteError(w, http.StatusUnauthorized, "Authorization header tidak ditemukan")
+				return
+			}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			utils.WriteError(w, http.StatusUnauthorized, "Format Authorization salah (harus Bearer token)")
-			return
-		}
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				utils.WriteError(w, http.StatusUnauthorized, "Format Authorization salah (harus Bearer token)")
+				return
+			}
 
-		tokenStr := parts[1]
-		claims, err := utils.ParseToken(tokenStr)
-		if err != nil {
-			utils.WriteError(w, http.StatusUnauthorized, "Token invalid atau kadaluarsa")
-			return
-		}
+			tokenStr := parts[1]
+			claims, err := utils.ParseToken(tokenStr)
+			if err != nil || claims.ID == "" {
+				utils.WriteError(w, http.StatusUnauthorized, "Token invalid atau kadaluarsa")
+				return
+			}
 
-		// Simpan userID & role ke context supaya bisa dipakai di handler
-		ctx := context.WithValue(r.Context(), ContextUserID, claims.UserID)
-		ctx = context.WithValue(ctx, ContextRole, claims.Role)
+			session, err := sessions.FindByAccessJTI(r.Context(), claims.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				utils.WriteError(w, http.StatusUnauthorized, "Token sudah dicabut")
+				return
+			}
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa session")
+				return
+			}
+			if !session.Aktif(time.Now()) || session.UserID.Hex() != claims.UserID {
+				utils.WriteError(w, http.StatusUnauthorized, "Token sudah dicabut")
+				return
+			}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			// Simpan userID, role & session ke context supaya bisa dipakai di handler
+			ctx := context.WithValue(r.Context(), ContextUserID, claims.UserID)
+			ctx = context.WithValue(ctx, ContextRole, claims.Role)
+			ctx = context.WithValue(ctx, ContextSessionID, session.ID.Hex())
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
-// AdminOnly middleware yang memastikan role = admin
-func AdminOnly(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		role, ok := r.Context().Value(ContextRole).(string)
-		if !ok || role != "admin" {
-			utils.WriteError(w, http.StatusForbidden, "Hanya admin yang bisa mengakses endpoint ini")
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+// RequirePermission memastikan role user memiliki semua permission di perms.
+// Permission role dibaca dari database di setiap request sehingga perubahan
+// oleh super admin langsung berlaku. Dipasang setelah AuthMiddleware.
+func RequirePermission(roles repository.RoleRepository, perms ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, err := roles.FindByNama(r.Context(), GetRoleFromContext(r))
+			if errors.Is(err, repository.ErrNotFound) {
+				utils.WriteError(w, http.StatusForbidden, "Role user tidak dikenal")
+				return
+			}
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "Gagal memeriksa permission")
+				return
+			}
+			if !role.Mencakup(perms) {
+				utils.WriteError(w, http.StatusForbidden, "Tidak punya izin untuk mengakses endpoint ini")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // GetUserIDFromContext helper untuk ambil userID di handler
@@ -82,3 +118,9 @@ func GetRoleFromContext(r *http.Request) string {
 	role, _ := r.Context().Value(ContextRole).(string)
 	return role
 }
+
+// GetSessionIDFromContext helper untuk ambil ID session login di handler
+func GetSessionIDFromContext(r *http.Request) string {
+	id, _ := r.Context().Value(ContextSessionID).(string)
+	return id
+}
@@ -8,12 +8,26 @@ import (
 
 // Alat adalah entitas alat kampus yang bisa dipinjam
 type Alat struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Nama          string             `bson:"nama" json:"nama"`
-	Kategori      string             `bson:"kategori" json:"kategori"`
-	Deskripsi     string             `bson:"deskripsi" json:"deskripsi"`
-	StokTotal     int                `bson:"stok_total" json:"stok_total"`
-	StokTersedia  int                `bson:"stok_tersedia" json:"stok_tersedia"`
-	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Nama      string             `bson:"nama" json:"nama"`
+	Kategori  string             `bson:"kategori" json:"kategori"`
+	Deskripsi string             `bson:"deskripsi" json:"deskripsi"`
+	// LabID adalah lab yang menyimpan alat. Alat tanpa lab hanya bisa
+	// dikelola role tanpa lingkup lab.
+	LabID        *primitive.ObjectID `bson:"lab_id,omitempty" json:"lab_id,omitempty"`
+	StokTotal    int                 `bson:"stok_total" json:"stok_total"`
+	StokTersedia int                 `bson:"stok_tersedia" json:"stok_tersedia"`
+	NilaiBarang  int64               `bson:"nilai_barang" json:"nilai_barang"`
+	// KebijakanDenda nil berarti memakai denda default dari konfigurasi
+	KebijakanDenda *KebijakanDenda `bson:"kebijakan_denda,omitempty" json:"kebijakan_denda,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updated_at"`
+	// DeletedAt diisi saat alat dihapus (soft delete). Alat yang dihapus
+	// tidak tampil di katalog dan dihapus permanen setelah masa retensi.
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+// Dihapus bernilai true jika alat sudah dihapus (soft delete)
+func (a Alat) Dihapus() bool {
+	return a.DeletedAt != nil
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kondisi fisik unit alat
+const (
+	KondisiBaik        = "BAIK"
+	KondisiRusakRingan = "RUSAK_RINGAN"
+	KondisiRusakBerat  = "RUSAK_BERAT"
+	// KondisiHilang hanya dipakai saat pemeriksaan pengembalian
+	KondisiHilang = "HILANG"
+)
+
+// Status unit alat
+const (
+	UnitTersedia  = "TERSEDIA"
+	UnitDipinjam  = "DIPINJAM"
+	UnitPerbaikan = "PERBAIKAN"
+	UnitHilang    = "HILANG"
+	UnitDihapus   = "DIHAPUS"
+)
+
+// KondisiValid memeriksa nilai kondisi unit
+func KondisiValid(kondisi string) bool {
+	switch kondisi {
+	case KondisiBaik, KondisiRusakRingan, KondisiRusakBerat:
+		return true
+	}
+	return false
+}
+
+// KondisiPengembalianValid memeriksa kondisi unit yang dilaporkan saat pengembalian
+func KondisiPengembalianValid(kondisi string) bool {
+	return kondisi == KondisiHilang || KondisiValid(kondisi)
+}
+
+// UnitBeredar menandai unit yang dihitung ke stok_total alat, yaitu unit
+// yang bisa dipinjam atau sedang dipinjam
+func UnitBeredar(status string) bool {
+	return status == UnitTersedia || status == UnitDipinjam
+}
+
+// AlatUnit adalah satu unit fisik alat yang bisa dilacak lewat kode aset
+type AlatUnit struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AlatID    primitive.ObjectID `bson:"alat_id" json:"alat_id"`
+	KodeAset  string             `bson:"kode_aset" json:"kode_aset"`
+	NomorSeri string             `bson:"nomor_seri,omitempty" json:"nomor_seri,omitempty"`
+	Kondisi   string             `bson:"kondisi" json:"kondisi"`
+	Status    string             `bson:"status" json:"status"`
+	// TransactionID diisi selama unit dipinjam
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// KodeAsetOtomatis membuat kode aset default untuk unit ke-n sebuah alat
+func KodeAsetOtomatis(alatID primitive.ObjectID, n int) string {
+	hex := alatID.Hex()
+	return fmt.Sprintf("%s-%03d", strings.ToUpper(hex[len(hex)-6:]), n)
+}
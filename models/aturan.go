@@ -0,0 +1,78 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis aturan peminjaman. Jenis aturan juga dipakai sebagai kode alasan
+// saat pengajuan ditolak.
+const (
+	// AturanMaksPeminjamanAktif membatasi jumlah transaksi yang sedang
+	// berjalan (diajukan sampai belum kembali) per user
+	AturanMaksPeminjamanAktif = "MAKS_PEMINJAMAN_AKTIF"
+	// AturanMaksJumlahAlat membatasi jumlah unit satu alat yang dipegang
+	// satu user sekaligus
+	AturanMaksJumlahAlat = "MAKS_JUMLAH_ALAT"
+	// AturanKategoriTerbatas membatasi kategori alat ke role / jurusan tertentu
+	AturanKategoriTerbatas = "KATEGORI_TERBATAS"
+	// AturanBlokirTerlambat memblokir peminjaman baru selama user masih
+	// punya peminjaman yang lewat jatuh tempo
+	AturanBlokirTerlambat = "BLOKIR_TERLAMBAT"
+)
+
+// Kode alasan penolakan pengajuan di luar aturan yang bisa diatur admin
+const (
+	KodeEmailBelumTerverifikasi = "EMAIL_BELUM_TERVERIFIKASI"
+	KodeDendaBelumLunas         = "DENDA_BELUM_LUNAS"
+)
+
+// JenisAturanValid mengecek apakah jenis aturan dikenal
+func JenisAturanValid(jenis string) bool {
+	switch jenis {
+	case AturanMaksPeminjamanAktif, AturanMaksJumlahAlat, AturanKategoriTerbatas, AturanBlokirTerlambat:
+		return true
+	}
+	return false
+}
+
+// AturanPeminjaman adalah satu aturan kelayakan yang diperiksa setiap ada
+// pengajuan peminjaman
+type AturanPeminjaman struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Nama  string             `bson:"nama" json:"nama"`
+	Jenis string             `bson:"jenis" json:"jenis"`
+	// Role membatasi aturan ke user dengan role ini, kosong berarti semua role
+	Role []string `bson:"role,omitempty" json:"role,omitempty"`
+	// Batas dipakai MAKS_PEMINJAMAN_AKTIF dan MAKS_JUMLAH_ALAT
+	Batas int `bson:"batas,omitempty" json:"batas,omitempty"`
+	// Kategori wajib untuk KATEGORI_TERBATAS. Untuk MAKS_JUMLAH_ALAT,
+	// kosong berarti berlaku untuk alat semua kategori.
+	Kategori string `bson:"kategori,omitempty" json:"kategori,omitempty"`
+	// RoleDiizinkan dan JurusanDiizinkan dipakai KATEGORI_TERBATAS: user
+	// boleh meminjam jika role atau jurusannya ada di salah satu daftar
+	RoleDiizinkan    []string  `bson:"role_diizinkan,omitempty" json:"role_diizinkan,omitempty"`
+	JurusanDiizinkan []string  `bson:"jurusan_diizinkan,omitempty" json:"jurusan_diizinkan,omitempty"`
+	Aktif            bool      `bson:"aktif" json:"aktif"`
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// BerlakuUntuk mengecek apakah aturan aktif dan berlaku untuk role user
+func (a *AturanPeminjaman) BerlakuUntuk(user *User) bool {
+	return a.Aktif && (len(a.Role) == 0 || slices.Contains(a.Role, user.Role))
+}
+
+// CocokKategori mengecek apakah aturan berlaku untuk alat dengan kategori ini
+func (a *AturanPeminjaman) CocokKategori(kategori string) bool {
+	return a.Kategori == "" || a.Kategori == kategori
+}
+
+// Mengizinkan mengecek apakah user boleh meminjam kategori yang dibatasi
+// aturan KATEGORI_TERBATAS
+func (a *AturanPeminjaman) Mengizinkan(user *User) bool {
+	return slices.Contains(a.RoleDiizinkan, user.Role) ||
+		(user.Jurusan != "" && slices.Contains(a.JurusanDiizinkan, user.Jurusan))
+}
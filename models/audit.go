@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Aksi yang dicatat di audit log, formatnya "<data>.<aksi>"
+const (
+	AuditUpdateProfil  = "user.update_profil"
+	AuditUpdateAvatar  = "user.update_avatar"
+	AuditUpdateRole    = "user.update_role"
+	AuditRevokeSession = "user.revoke_sessions"
+
+	AuditRegister        = "user.register"
+	AuditVerifikasiEmail = "user.verifikasi_email"
+	AuditResetPassword   = "user.reset_password"
+	AuditGantiPassword   = "user.ganti_password"
+	AuditLogout          = "user.logout"
+
+	AuditAlatCreate  = "alat.create"
+	AuditAlatUpdate  = "alat.update"
+	AuditAlatDelete  = "alat.delete"
+	AuditAlatRestore = "alat.restore"
+	AuditAlatPurge   = "alat.purge"
+	AuditUnitCreate  = "unit.create"
+	AuditUnitUpdate  = "unit.update"
+
+	AuditStokRekonsiliasi = "stok.rekonsiliasi"
+
+	AuditPeminjamanAjukan     = "peminjaman.ajukan"
+	AuditPeminjamanSetujui    = "peminjaman.setujui"
+	AuditPeminjamanTolak      = "peminjaman.tolak"
+	AuditPeminjamanAmbil      = "peminjaman.ambil"
+	AuditPeminjamanKembalikan = "peminjaman.kembalikan"
+	AuditPeminjamanBatal      = "peminjaman.batal"
+	AuditPerpanjanganAjukan   = "peminjaman.perpanjang"
+	AuditPerpanjanganSetujui  = "peminjaman.perpanjangan_setujui"
+	AuditPerpanjanganTolak    = "peminjaman.perpanjangan_tolak"
+
+	AuditReservasiBuat  = "reservasi.buat"
+	AuditReservasiBatal = "reservasi.batal"
+	AuditReservasiAmbil = "reservasi.ambil"
+
+	AuditDendaBayar      = "denda.bayar"
+	AuditDendaHapuskan   = "denda.hapuskan"
+	AuditKasusSelesaikan = "kasus.selesaikan"
+
+	AuditKategoriCreate = "kategori.create"
+	AuditKategoriUpdate = "kategori.update"
+	AuditKategoriDelete = "kategori.delete"
+	AuditAturanCreate   = "aturan.create"
+	AuditAturanUpdate   = "aturan.update"
+	AuditAturanDelete   = "aturan.delete"
+	AuditJurusanCreate  = "jurusan.create"
+	AuditJurusanUpdate  = "jurusan.update"
+	AuditJurusanDelete  = "jurusan.delete"
+	AuditLabCreate      = "lab.create"
+	AuditLabUpdate      = "lab.update"
+	AuditLabDelete      = "lab.delete"
+	AuditRoleCreate     = "role.create"
+	AuditRoleUpdate     = "role.update"
+	AuditRoleDelete     = "role.delete"
+)
+
+// AuditLog mencatat satu perubahan data oleh seorang aktor. Catatan hanya
+// ditambah, tidak pernah diubah atau dihapus.
+type AuditLog struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AktorID primitive.ObjectID `bson:"aktor_id" json:"aktor_id"`
+	Aksi    string             `bson:"aksi" json:"aksi"`
+	// Target adalah nama koleksi data yang diubah
+	Target   string             `bson:"target" json:"target"`
+	TargetID primitive.ObjectID `bson:"target_id" json:"target_id"`
+	// Sebelum dan Sesudah hanya berisi field yang berubah
+	Sebelum   map[string]interface{} `bson:"sebelum,omitempty" json:"sebelum,omitempty"`
+	Sesudah   map[string]interface{} `bson:"sesudah,omitempty" json:"sesudah,omitempty"`
+	IP        string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	RequestID string                 `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	Waktu     time.Time              `bson:"waktu" json:"waktu"`
+}
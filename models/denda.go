@@ -0,0 +1,102 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tipe kebijakan denda keterlambatan
+const (
+	DendaPerHari     = "PER_HARI"     // nominal tetap per hari per unit
+	DendaPersenNilai = "PERSEN_NILAI" // persen dari nilai barang per hari per unit
+)
+
+// Status denda
+const (
+	DendaBelumLunas = "BELUM_LUNAS"
+	DendaLunas      = "LUNAS"
+	DendaDihapuskan = "DIHAPUSKAN"
+)
+
+// Jenis denda. Denda lama tanpa jenis adalah denda keterlambatan.
+const (
+	DendaJenisTerlambat = "TERLAMBAT"
+	DendaJenisKerusakan = "KERUSAKAN"
+)
+
+// KebijakanDenda mengatur cara menghitung denda keterlambatan sebuah alat
+type KebijakanDenda struct {
+	Tipe          string  `bson:"tipe" json:"tipe"`
+	TarifPerHari  int64   `bson:"tarif_per_hari,omitempty" json:"tarif_per_hari,omitempty"`
+	PersenPerHari float64 `bson:"persen_per_hari,omitempty" json:"persen_per_hari,omitempty"`
+	// Maksimum adalah batas denda per unit, 0 berarti tanpa batas
+	Maksimum int64 `bson:"maksimum,omitempty" json:"maksimum,omitempty"`
+}
+
+// Hitung mengembalikan total denda untuk sejumlah unit yang terlambat
+// beberapa hari. nilaiBarang dipakai untuk tipe PERSEN_NILAI.
+func (k KebijakanDenda) Hitung(hariTerlambat, jumlah int, nilaiBarang int64) int64 {
+	if hariTerlambat <= 0 || jumlah <= 0 {
+		return 0
+	}
+
+	var perHari int64
+	switch k.Tipe {
+	case DendaPerHari:
+		perHari = k.TarifPerHari
+	case DendaPersenNilai:
+		perHari = int64(math.Ceil(float64(nilaiBarang) * k.PersenPerHari / 100))
+	}
+
+	perUnit := perHari * int64(hariTerlambat)
+	if k.Maksimum > 0 && perUnit > k.Maksimum {
+		perUnit = k.Maksimum
+	}
+	return perUnit * int64(jumlah)
+}
+
+// Valid memeriksa isi kebijakan denda dari request admin
+func (k KebijakanDenda) Valid() bool {
+	switch k.Tipe {
+	case DendaPerHari:
+		return k.TarifPerHari >= 0 && k.Maksimum >= 0
+	case DendaPersenNilai:
+		return k.PersenPerHari >= 0 && k.PersenPerHari <= 100 && k.Maksimum >= 0
+	}
+	return false
+}
+
+// PembayaranDenda mencatat satu pembayaran denda
+type PembayaranDenda struct {
+	Jumlah      int64              `bson:"jumlah" json:"jumlah"`
+	Catatan     string             `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	DicatatOleh primitive.ObjectID `bson:"dicatat_oleh" json:"dicatat_oleh"`
+	Waktu       time.Time          `bson:"waktu" json:"waktu"`
+}
+
+// Denda mencatat denda keterlambatan atau ganti rugi kerusakan sebuah
+// transaksi beserta pembayarannya
+type Denda struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Jenis          string              `bson:"jenis,omitempty" json:"jenis,omitempty"`
+	TransactionID  primitive.ObjectID  `bson:"transaction_id" json:"transaction_id"`
+	KasusID        *primitive.ObjectID `bson:"kasus_id,omitempty" json:"kasus_id,omitempty"`
+	UserID         primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	AlatID         primitive.ObjectID  `bson:"alat_id" json:"alat_id"`
+	HariTerlambat  int                 `bson:"hari_terlambat" json:"hari_terlambat"`
+	Jumlah         int64               `bson:"jumlah" json:"jumlah"`
+	TotalDibayar   int64               `bson:"total_dibayar" json:"total_dibayar"`
+	Pembayaran     []PembayaranDenda   `bson:"pembayaran" json:"pembayaran"`
+	Status         string              `bson:"status" json:"status"` // "BELUM_LUNAS", "LUNAS", "DIHAPUSKAN"
+	AlasanHapus    string              `bson:"alasan_hapus,omitempty" json:"alasan_hapus,omitempty"`
+	DihapuskanOleh *primitive.ObjectID `bson:"dihapuskan_oleh,omitempty" json:"dihapuskan_oleh,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Sisa mengembalikan jumlah denda yang belum dibayar
+func (d *Denda) Sisa() int64 {
+	return d.Jumlah - d.TotalDibayar
+}
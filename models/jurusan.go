@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jurusan adalah daftar jurusan yang boleh dipilih mahasiswa saat registrasi
+type Jurusan struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Nama      string             `bson:"nama" json:"nama"`
+	Fakultas  string             `bson:"fakultas,omitempty" json:"fakultas,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status kasus kerusakan
+const (
+	KasusTerbuka = "TERBUKA"
+	KasusSelesai = "SELESAI"
+)
+
+// Cara penyelesaian kasus kerusakan
+const (
+	PenyelesaianDiperbaiki = "DIPERBAIKI" // unit diperbaiki lalu kembali tersedia
+	PenyelesaianGantiRugi  = "GANTI_RUGI" // peminjam ditagih biaya penggantian
+	PenyelesaianDihapuskan = "DIHAPUSKAN" // unit dihapus dari inventaris tanpa tagihan
+)
+
+// KasusKerusakan dibuka saat unit dikembalikan dalam kondisi rusak atau
+// hilang, dan ditutup admin dengan perbaikan, ganti rugi atau penghapusan
+type KasusKerusakan struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TransactionID primitive.ObjectID `bson:"transaction_id" json:"transaction_id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AlatID        primitive.ObjectID `bson:"alat_id" json:"alat_id"`
+	UnitID        primitive.ObjectID `bson:"unit_id" json:"unit_id"`
+	KodeAset      string             `bson:"kode_aset" json:"kode_aset"`
+	Kondisi       string             `bson:"kondisi" json:"kondisi"`
+	Catatan       string             `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Foto          string             `bson:"foto,omitempty" json:"foto,omitempty"`
+	Status        string             `bson:"status" json:"status"`
+
+	// Diisi saat kasus diselesaikan
+	Penyelesaian        string              `bson:"penyelesaian,omitempty" json:"penyelesaian,omitempty"`
+	Biaya               int64               `bson:"biaya,omitempty" json:"biaya,omitempty"`
+	DendaID             *primitive.ObjectID `bson:"denda_id,omitempty" json:"denda_id,omitempty"`
+	CatatanPenyelesaian string              `bson:"catatan_penyelesaian,omitempty" json:"catatan_penyelesaian,omitempty"`
+	DiselesaikanOleh    *primitive.ObjectID `bson:"diselesaikan_oleh,omitempty" json:"diselesaikan_oleh,omitempty"`
+	DiselesaikanPada    *time.Time          `bson:"diselesaikan_pada,omitempty" json:"diselesaikan_pada,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
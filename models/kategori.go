@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kategori menyimpan aturan peminjaman per kategori alat
+type Kategori struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Nama           string             `bson:"nama" json:"nama"`
+	MaksHariPinjam int                `bson:"maks_hari_pinjam" json:"maks_hari_pinjam"`
+	// MaksPerpanjangan adalah jumlah perpanjangan yang disetujui otomatis.
+	// nil berarti memakai default dari konfigurasi.
+	MaksPerpanjangan *int      `bson:"maks_perpanjangan,omitempty" json:"maks_perpanjangan,omitempty"`
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
+}
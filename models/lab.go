@@ -0,0 +1,48 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hari operasional lab
+var HariLab = []string{"SENIN", "SELASA", "RABU", "KAMIS", "JUMAT", "SABTU", "MINGGU"}
+
+// JamOperasional adalah jam buka lab pada satu hari, format jam "15:04"
+type JamOperasional struct {
+	Hari  string `bson:"hari" json:"hari"`
+	Buka  string `bson:"buka" json:"buka"`
+	Tutup string `bson:"tutup" json:"tutup"`
+}
+
+// Valid memeriksa nama hari dan memastikan jam buka sebelum jam tutup
+func (j JamOperasional) Valid() bool {
+	if !slices.Contains(HariLab, j.Hari) {
+		return false
+	}
+	buka, err := time.Parse("15:04", j.Buka)
+	if err != nil {
+		return false
+	}
+	tutup, err := time.Parse("15:04", j.Tutup)
+	if err != nil {
+		return false
+	}
+	return buka.Before(tutup)
+}
+
+// Lab adalah laboratorium / lokasi yang menyimpan dan mengelola alat
+type Lab struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kode           string             `bson:"kode" json:"kode"`
+	Nama           string             `bson:"nama" json:"nama"`
+	Alamat         string             `bson:"alamat,omitempty" json:"alamat,omitempty"`
+	JamOperasional []JamOperasional   `bson:"jam_operasional" json:"jam_operasional"`
+	// StaffIDs adalah user yang mengelola lab ini. Untuk role dengan
+	// lingkup lab, permission hanya berlaku di lab tempat user menjadi staff.
+	StaffIDs  []primitive.ObjectID `bson:"staff_ids" json:"staff_ids"`
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis mutasi stok alat
+const (
+	// MutasiPembelian menambah unit baru ke inventaris
+	MutasiPembelian = "PEMBELIAN"
+	// MutasiPinjam memesan unit untuk peminjaman (stok tersedia berkurang)
+	MutasiPinjam = "PINJAM"
+	// MutasiKembali mengembalikan unit pinjaman ke stok, termasuk saat
+	// peminjaman yang sudah disetujui dibatalkan
+	MutasiKembali = "KEMBALI"
+	// MutasiRusak mengeluarkan unit rusak atau hilang dari stok beredar
+	MutasiRusak = "RUSAK"
+	// MutasiPerbaikan mengembalikan unit yang selesai diperbaiki ke stok
+	MutasiPerbaikan = "PERBAIKAN"
+	// MutasiPenghapusan menghapus unit dari inventaris (write-off)
+	MutasiPenghapusan = "PENGHAPUSAN"
+	// MutasiPenyesuaian adalah koreksi manual admin atau hasil rekonsiliasi
+	MutasiPenyesuaian = "PENYESUAIAN"
+)
+
+// MutasiStok adalah satu baris buku besar stok alat. Setiap perubahan
+// stok_total atau stok_tersedia dicatat di sini beserta saldo setelahnya.
+type MutasiStok struct {
+	ID      primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	AlatID  primitive.ObjectID   `bson:"alat_id" json:"alat_id"`
+	Jenis   string               `bson:"jenis" json:"jenis"`
+	UnitIDs []primitive.ObjectID `bson:"unit_ids,omitempty" json:"unit_ids,omitempty"`
+	// PerubahanTotal dan PerubahanTersedia adalah selisih stok akibat mutasi
+	PerubahanTotal    int `bson:"perubahan_total" json:"perubahan_total"`
+	PerubahanTersedia int `bson:"perubahan_tersedia" json:"perubahan_tersedia"`
+	// StokTotal dan StokTersedia adalah saldo alat setelah mutasi
+	StokTotal     int                 `bson:"stok_total" json:"stok_total"`
+	StokTersedia  int                 `bson:"stok_tersedia" json:"stok_tersedia"`
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	KasusID       *primitive.ObjectID `bson:"kasus_id,omitempty" json:"kasus_id,omitempty"`
+	AktorID       primitive.ObjectID  `bson:"aktor_id" json:"aktor_id"`
+	Keterangan    string              `bson:"keterangan,omitempty" json:"keterangan,omitempty"`
+	Waktu         time.Time           `bson:"waktu" json:"waktu"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status pengajuan perpanjangan peminjaman
+const (
+	PerpanjanganMenunggu  = "MENUNGGU"
+	PerpanjanganDisetujui = "DISETUJUI"
+	PerpanjanganDitolak   = "DITOLAK"
+)
+
+// Perpanjangan mencatat satu pengajuan perpanjangan jatuh tempo. Untuk
+// peminjaman multi-item, semua item grup mendapat pengajuan dengan ID sama.
+type Perpanjangan struct {
+	ID             primitive.ObjectID `bson:"id" json:"id"`
+	JatuhTempoLama time.Time          `bson:"jatuh_tempo_lama" json:"jatuh_tempo_lama"`
+	JatuhTempoBaru time.Time          `bson:"jatuh_tempo_baru" json:"jatuh_tempo_baru"`
+	Alasan         string             `bson:"alasan,omitempty" json:"alasan,omitempty"`
+	Status         string             `bson:"status" json:"status"`
+	// Otomatis bernilai true jika disetujui tanpa admin
+	Otomatis bool `bson:"otomatis" json:"otomatis"`
+	// Keterangan menjelaskan kenapa pengajuan masuk antrian admin
+	Keterangan   string    `bson:"keterangan,omitempty" json:"keterangan,omitempty"`
+	DiajukanPada time.Time `bson:"diajukan_pada" json:"diajukan_pada"`
+	// DiputuskanOleh kosong untuk perpanjangan yang disetujui otomatis
+	DiputuskanOleh  *primitive.ObjectID `bson:"diputuskan_oleh,omitempty" json:"diputuskan_oleh,omitempty"`
+	DiputuskanPada  *time.Time          `bson:"diputuskan_pada,omitempty" json:"diputuskan_pada,omitempty"`
+	AlasanPenolakan string              `bson:"alasan_penolakan,omitempty" json:"alasan_penolakan,omitempty"`
+	// AbaikanBentrok bernilai true jika admin menyetujui walaupun jatuh
+	// tempo baru bentrok dengan reservasi
+	AbaikanBentrok bool `bson:"abaikan_bentrok,omitempty" json:"abaikan_bentrok,omitempty"`
+}
+
+// PerpanjanganMenunggu mengembalikan pengajuan perpanjangan yang belum
+// diputuskan, atau nil jika tidak ada
+func (t *Transaction) PerpanjanganMenunggu() *Perpanjangan {
+	for i := range t.Perpanjangan {
+		if t.Perpanjangan[i].Status == PerpanjanganMenunggu {
+			return &t.Perpanjangan[i]
+		}
+	}
+	return nil
+}
+
+// JumlahPerpanjangan menghitung perpanjangan yang sudah disetujui
+func (t *Transaction) JumlahPerpanjangan() int {
+	n := 0
+	for _, p := range t.Perpanjangan {
+		if p.Status == PerpanjanganDisetujui {
+			n++
+		}
+	}
+	return n
+}
+
+// PutuskanPerpanjangan menyetujui atau menolak pengajuan yang menunggu.
+// Jika disetujui, jatuh tempo transaksi ikut diganti. Riwayat disalin
+// sebelum diubah supaya data yang sedang dibaca tidak ikut berubah.
+func (t *Transaction) PutuskanPerpanjangan(status string, oleh primitive.ObjectID, waktu time.Time, alasan string, abaikanBentrok bool) {
+	t.Perpanjangan = slices.Clone(t.Perpanjangan)
+	p := t.PerpanjanganMenunggu()
+	if p == nil {
+		return
+	}
+	p.Status = status
+	p.DiputuskanOleh = &oleh
+	p.DiputuskanPada = &waktu
+	p.AlasanPenolakan = alasan
+	p.AbaikanBentrok = abaikanBentrok
+	if status == PerpanjanganDisetujui {
+		t.JatuhTempo = p.JatuhTempoBaru
+	}
+	t.UpdatedAt = waktu
+}
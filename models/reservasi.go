@@ -0,0 +1,75 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status reservasi
+const (
+	ReservasiAktif      = "AKTIF"
+	ReservasiDiambil    = "DIAMBIL"
+	ReservasiDibatalkan = "DIBATALKAN"
+)
+
+// Reservasi adalah pemesanan alat untuk rentang waktu di masa depan.
+// Saat diambil, reservasi diubah menjadi Transaction berstatus DIAMBIL.
+type Reservasi struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	AlatID        primitive.ObjectID  `bson:"alat_id" json:"alat_id"`
+	Jumlah        int                 `bson:"jumlah" json:"jumlah"`
+	Mulai         time.Time           `bson:"mulai" json:"mulai"`
+	Selesai       time.Time           `bson:"selesai" json:"selesai"`
+	Status        string              `bson:"status" json:"status"` // "AKTIF", "DIAMBIL", "DIBATALKAN"
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Pemakaian adalah sejumlah unit alat yang terpakai pada rentang [Mulai, Selesai)
+type Pemakaian struct {
+	Mulai   time.Time
+	Selesai time.Time
+	Jumlah  int
+}
+
+// PemakaianPuncak menghitung jumlah unit terbanyak yang terpakai bersamaan
+// pada suatu saat di dalam rentang [dari, sampai)
+func PemakaianPuncak(list []Pemakaian, dari, sampai time.Time) int {
+	type event struct {
+		waktu time.Time
+		delta int
+	}
+
+	var events []event
+	for _, p := range list {
+		if !p.Mulai.Before(sampai) || !p.Selesai.After(dari) {
+			continue
+		}
+		mulai := p.Mulai
+		if mulai.Before(dari) {
+			mulai = dari
+		}
+		events = append(events, event{mulai, p.Jumlah}, event{p.Selesai, -p.Jumlah})
+	}
+
+	// Pemakaian yang selesai tepat saat pemakaian lain mulai tidak dihitung bersamaan
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].waktu.Equal(events[j].waktu) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].waktu.Before(events[j].waktu)
+	})
+
+	puncak, jalan := 0, 0
+	for _, e := range events {
+		jalan += e.delta
+		if jalan > puncak {
+			puncak = jalan
+		}
+	}
+	return puncak
+}
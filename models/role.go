@@ -0,0 +1,122 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Nama role bawaan. Role lain bisa dibuat super admin lewat API.
+const (
+	RoleSuperAdmin = "super_admin"
+	RoleAdmin      = "admin"
+	RoleLaboran    = "laboran"
+	RoleDosen      = "dosen"
+	RoleMahasiswa  = "mahasiswa"
+)
+
+// Permission yang bisa diberikan ke role
+const (
+	PermAlatWrite          = "alat:write"          // CRUD alat & unit
+	PermPeminjamanRead     = "peminjaman:read"     // melihat semua transaksi & keterlambatan
+	PermPeminjamanApprove  = "peminjaman:approve"  // menyetujui, menolak & membatalkan pengajuan
+	PermPeminjamanHandover = "peminjaman:handover" // serah terima alat (ambil & kembalikan)
+	PermReservasiManage    = "reservasi:manage"
+	PermDendaManage        = "denda:manage"
+	PermKasusManage        = "kasus:manage"
+	PermKategoriWrite      = "kategori:write"
+	PermJurusanWrite       = "jurusan:write"
+	PermUserManage         = "user:manage"
+	PermRoleManage         = "role:manage"
+	PermLabManage          = "lab:manage"    // CRUD lab & staff lab
+	PermAuditRead          = "audit:read"    // melihat audit log
+	PermAturanManage       = "aturan:manage" // aturan kuota & kelayakan peminjaman
+
+	// PermSemua memberi semua permission, termasuk yang ditambahkan nanti
+	PermSemua = "*"
+)
+
+// SemuaPermission berisi permission yang dikenal, urut seperti di atas
+var SemuaPermission = []string{
+	PermAlatWrite,
+	PermPeminjamanRead,
+	PermPeminjamanApprove,
+	PermPeminjamanHandover,
+	PermReservasiManage,
+	PermDendaManage,
+	PermKasusManage,
+	PermKategoriWrite,
+	PermJurusanWrite,
+	PermUserManage,
+	PermRoleManage,
+	PermLabManage,
+	PermAuditRead,
+	PermAturanManage,
+}
+
+// PermissionValid mengecek apakah perm dikenal. PermSemua tidak termasuk
+// karena hanya dipakai role super admin.
+func PermissionValid(perm string) bool {
+	return slices.Contains(SemuaPermission, perm)
+}
+
+// Role memetakan nama role user ke daftar permission-nya
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Nama        string             `bson:"nama" json:"nama"`
+	Deskripsi   string             `bson:"deskripsi,omitempty" json:"deskripsi,omitempty"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	// LingkupLab membatasi permission role ke lab tempat user menjadi
+	// staff. Role tanpa lingkup lab berlaku untuk semua lab.
+	LingkupLab bool `bson:"lingkup_lab" json:"lingkup_lab"`
+	// Bawaan menandai role yang dibuat sistem dan tidak bisa dihapus
+	Bawaan    bool      `bson:"bawaan" json:"bawaan"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Punya mengecek apakah role memiliki permission perm
+func (r *Role) Punya(perm string) bool {
+	return slices.Contains(r.Permissions, PermSemua) || slices.Contains(r.Permissions, perm)
+}
+
+// Mencakup mengecek apakah role memiliki semua permission di perms
+func (r *Role) Mencakup(perms []string) bool {
+	for _, p := range perms {
+		if !r.Punya(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoleBawaan mengembalikan role yang disiapkan saat aplikasi pertama jalan
+func RoleBawaan(now time.Time) []Role {
+	role := func(nama, deskripsi string, perms ...string) Role {
+		return Role{
+			ID:          primitive.NewObjectID(),
+			Nama:        nama,
+			Deskripsi:   deskripsi,
+			Permissions: append([]string{}, perms...),
+			Bawaan:      true,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+	laboran := role(RoleLaboran, "Asisten laboratorium, hanya di lab tempatnya bertugas",
+		PermAlatWrite, PermPeminjamanRead, PermPeminjamanApprove, PermPeminjamanHandover,
+		PermReservasiManage, PermKasusManage)
+	laboran.LingkupLab = true
+
+	return []Role{
+		role(RoleSuperAdmin, "Semua akses termasuk mengatur role", PermSemua),
+		role(RoleAdmin, "Pengelola sistem",
+			PermAlatWrite, PermPeminjamanRead, PermPeminjamanApprove, PermPeminjamanHandover,
+			PermReservasiManage, PermDendaManage, PermKasusManage, PermKategoriWrite,
+			PermJurusanWrite, PermUserManage, PermLabManage, PermAuditRead, PermAturanManage),
+		laboran,
+		role(RoleDosen, "Dosen pembimbing praktikum", PermPeminjamanRead, PermPeminjamanApprove),
+		role(RoleMahasiswa, "Peminjam"),
+	}
+}
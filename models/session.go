@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session adalah satu login aktif. Refresh token hanya disimpan sebagai
+// hash dan diganti setiap kali dipakai (rotasi), sedangkan AccessJTI
+// mencatat jti access token terakhir yang diterbitkan untuk session ini.
+type Session struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RefreshHash string             `bson:"refresh_hash" json:"-"`
+	AccessJTI   string             `bson:"access_jti" json:"-"`
+	UserAgent   string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP          string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	// ExpiresAt adalah batas berlaku refresh token
+	ExpiresAt time.Time  `bson:"expires_at" json:"expires_at"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// Aktif mengecek apakah session belum dicabut dan belum kadaluarsa
+func (s Session) Aktif(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
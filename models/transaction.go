@@ -6,15 +6,233 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Status transaksi peminjaman. Alurnya:
+//
+//	DIAJUKAN -> DISETUJUI / DITOLAK
+//	DISETUJUI -> DIAMBIL -> DIKEMBALIKAN
+//	DIAMBIL -> SEBAGIAN_KEMBALI -> DIKEMBALIKAN
+//	DIAJUKAN / DISETUJUI -> DIBATALKAN
+const (
+	StatusDiajukan     = "DIAJUKAN"
+	StatusDisetujui    = "DISETUJUI"
+	StatusDitolak      = "DITOLAK"
+	StatusDiambil      = "DIAMBIL"
+	StatusDikembalikan = "DIKEMBALIKAN"
+	StatusDibatalkan   = "DIBATALKAN"
+	// StatusSebagianKembali dipakai selama sebagian unit sudah dikembalikan
+	// dan sisanya masih dibawa peminjam
+	StatusSebagianKembali = "SEBAGIAN_KEMBALI"
+	// StatusTerlambat tidak disimpan di database, hanya diturunkan dari
+	// transaksi DIAMBIL / SEBAGIAN_KEMBALI yang sudah melewati jatuh tempo
+	StatusTerlambat = "TERLAMBAT"
+)
+
+// Status lama sebelum ada alur persetujuan, dimigrasi ke DIAMBIL / DIKEMBALIKAN
+const (
+	StatusLamaPinjam  = "PINJAM"
+	StatusLamaKembali = "KEMBALI"
+)
+
+// transisiStatus berisi perubahan status yang diizinkan
+var transisiStatus = map[string][]string{
+	StatusDiajukan:  {StatusDisetujui, StatusDitolak, StatusDibatalkan},
+	StatusDisetujui: {StatusDiambil, StatusDibatalkan},
+	StatusDiambil:   {StatusDikembalikan, StatusSebagianKembali},
+	// Pengembalian sebagian boleh dilakukan berkali-kali
+	StatusSebagianKembali: {StatusSebagianKembali, StatusDikembalikan},
+}
+
+// BolehTransisi memeriksa apakah status dari boleh berubah menjadi ke
+func BolehTransisi(dari, ke string) bool {
+	for _, s := range transisiStatus[dari] {
+		if s == ke {
+			return true
+		}
+	}
+	return false
+}
+
+// MenahanStok bernilai true untuk status yang stoknya sedang dipakai transaksi
+func MenahanStok(status string) bool {
+	return status == StatusDisetujui || Dipinjam(status)
+}
+
+// Dipinjam bernilai true untuk status yang alatnya (sebagian) masih dibawa peminjam
+func Dipinjam(status string) bool {
+	return status == StatusDiambil || status == StatusSebagianKembali
+}
+
+// PerubahanStatus mencatat satu transisi status transaksi
+type PerubahanStatus struct {
+	Dari    string             `bson:"dari" json:"dari"`
+	Ke      string             `bson:"ke" json:"ke"`
+	Oleh    primitive.ObjectID `bson:"oleh" json:"oleh"`
+	Waktu   time.Time          `bson:"waktu" json:"waktu"`
+	Catatan string             `bson:"catatan,omitempty" json:"catatan,omitempty"`
+}
+
 // Transaction menyimpan data peminjaman / pengembalian
 type Transaction struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
-	AlatID          primitive.ObjectID `bson:"alat_id" json:"alat_id"`
-	Jumlah          int                `bson:"jumlah" json:"jumlah"`
-	TanggalPinjam   time.Time          `bson:"tanggal_pinjam" json:"tanggal_pinjam"`
-	TanggalKembali  *time.Time         `bson:"tanggal_kembali,omitempty" json:"tanggal_kembali,omitempty"`
-	Status          string             `bson:"status" json:"status"` // "PINJAM", "KEMBALI"
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AlatID primitive.ObjectID `bson:"alat_id" json:"alat_id"`
+	Jumlah int                `bson:"jumlah" json:"jumlah"`
+	// JumlahDikembalikan adalah unit yang sudah dikembalikan, termasuk yang
+	// rusak / hilang. Transaksi selesai jika sudah sama dengan Jumlah.
+	JumlahDikembalikan int `bson:"jumlah_dikembalikan" json:"jumlah_dikembalikan"`
+	// GrupID diisi untuk peminjaman multi-item. Setiap item adalah satu
+	// transaksi, semua item dalam grup berbagi jatuh tempo dan persetujuan.
+	GrupID *primitive.ObjectID `bson:"grup_id,omitempty" json:"grup_id,omitempty"`
+	// UnitIDs adalah unit fisik yang dipesan untuk transaksi sejak disetujui
+	UnitIDs []primitive.ObjectID `bson:"unit_ids,omitempty" json:"unit_ids,omitempty"`
+	// TanggalPinjam diisi saat alat diambil (status DIAMBIL)
+	TanggalPinjam   time.Time         `bson:"tanggal_pinjam,omitempty" json:"tanggal_pinjam,omitempty"`
+	JatuhTempo      time.Time         `bson:"jatuh_tempo,omitempty" json:"jatuh_tempo,omitempty"`
+	TanggalKembali  *time.Time        `bson:"tanggal_kembali,omitempty" json:"tanggal_kembali,omitempty"`
+	Status          string            `bson:"status" json:"status"`
+	AlasanPenolakan string            `bson:"alasan_penolakan,omitempty" json:"alasan_penolakan,omitempty"`
+	RiwayatStatus   []PerubahanStatus `bson:"riwayat_status" json:"riwayat_status"`
+	// Pemeriksaan berisi kondisi tiap unit saat dikembalikan, bertambah
+	// setiap kali ada pengembalian sebagian
+	Pemeriksaan []PemeriksaanUnit `bson:"pemeriksaan,omitempty" json:"pemeriksaan,omitempty"`
+	// Penerimaan berisi check-in oleh staff lab, satu entri untuk setiap
+	// pengembalian yang diterima staff. Pengembalian mandiri tidak dicatat.
+	Penerimaan []PenerimaanPengembalian `bson:"penerimaan,omitempty" json:"penerimaan,omitempty"`
+	// Perpanjangan berisi riwayat pengajuan perpanjangan jatuh tempo
+	Perpanjangan []Perpanjangan `bson:"perpanjangan,omitempty" json:"perpanjangan,omitempty"`
+	// NamaAlat disalin saat alat dihapus permanen supaya riwayat tetap
+	// menampilkan nama alat
+	NamaAlat  string    `bson:"nama_alat,omitempty" json:"nama_alat,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// PenerimaanPengembalian mencatat staff yang menerima alat, kapan, di mana
+// dan unit mana saja yang diterima pada satu pengembalian
+type PenerimaanPengembalian struct {
+	StaffID primitive.ObjectID   `bson:"staff_id" json:"staff_id"`
+	Waktu   time.Time            `bson:"waktu" json:"waktu"`
+	Lokasi  string               `bson:"lokasi" json:"lokasi"`
+	Jumlah  int                  `bson:"jumlah" json:"jumlah"`
+	UnitIDs []primitive.ObjectID `bson:"unit_ids,omitempty" json:"unit_ids,omitempty"`
+}
+
+// PemeriksaanUnit mencatat kondisi satu unit saat pengembalian
+type PemeriksaanUnit struct {
+	UnitID   primitive.ObjectID `bson:"unit_id" json:"unit_id"`
+	KodeAset string             `bson:"kode_aset" json:"kode_aset"`
+	Kondisi  string             `bson:"kondisi" json:"kondisi"`
+	Catatan  string             `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Foto     string             `bson:"foto,omitempty" json:"foto,omitempty"`
+	// KasusID diisi jika unit rusak / hilang dan dibuatkan kasus kerusakan
+	KasusID *primitive.ObjectID `bson:"kasus_id,omitempty" json:"kasus_id,omitempty"`
+}
+
+// UbahStatus memindahkan transaksi ke status baru dan mencatatnya di riwayat.
+// Pemanggil wajib memeriksa BolehTransisi terlebih dahulu.
+func (t *Transaction) UbahStatus(ke string, oleh primitive.ObjectID, waktu time.Time, catatan string) {
+	t.RiwayatStatus = append(t.RiwayatStatus, PerubahanStatus{
+		Dari:    t.Status,
+		Ke:      ke,
+		Oleh:    oleh,
+		Waktu:   waktu,
+		Catatan: catatan,
+	})
+	t.Status = ke
+	t.UpdatedAt = waktu
+}
+
+// SisaPinjam adalah jumlah unit yang belum dikembalikan. Sebelum alat
+// diambil, nilainya sama dengan Jumlah.
+func (t *Transaction) SisaPinjam() int {
+	return t.Jumlah - t.JumlahDikembalikan
+}
+
+// UnitBelumKembali mengembalikan unit transaksi yang belum diperiksa
+// di pengembalian mana pun, dengan urutan sama seperti UnitIDs
+func (t *Transaction) UnitBelumKembali() []primitive.ObjectID {
+	sudah := make(map[primitive.ObjectID]bool, len(t.Pemeriksaan))
+	for _, p := range t.Pemeriksaan {
+		sudah[p.UnitID] = true
+	}
+	var sisa []primitive.ObjectID
+	for _, id := range t.UnitIDs {
+		if !sudah[id] {
+			sisa = append(sisa, id)
+		}
+	}
+	return sisa
+}
+
+// Terlambat bernilai true jika alat sudah diambil, belum kembali dan jatuh tempo sudah lewat
+func (t *Transaction) Terlambat(now time.Time) bool {
+	return terlambat(t.Status, t.JatuhTempo, now)
+}
+
+// StatusEfektif mengembalikan status untuk ditampilkan, termasuk TERLAMBAT
+func (t *Transaction) StatusEfektif(now time.Time) string {
+	if t.Terlambat(now) {
+		return StatusTerlambat
+	}
+	return t.Status
+}
+
+func terlambat(status string, jatuhTempo, now time.Time) bool {
+	return Dipinjam(status) && !jatuhTempo.IsZero() && now.After(jatuhTempo)
+}
+
+// HariTerlambat menghitung jumlah hari (dibulatkan ke atas) sejak jatuh tempo
+// sampai waktu tertentu. Mengembalikan 0 jika belum lewat jatuh tempo.
+func HariTerlambat(jatuhTempo, sampai time.Time) int {
+	if jatuhTempo.IsZero() || !sampai.After(jatuhTempo) {
+		return 0
+	}
+	selisih := sampai.Sub(jatuhTempo)
+	hari := int(selisih / (24 * time.Hour))
+	if selisih%(24*time.Hour) != 0 {
+		hari++
+	}
+	return hari
+}
+
+// RiwayatPeminjaman adalah transaksi yang sudah digabung dengan nama alat
+type RiwayatPeminjaman struct {
+	ID                 primitive.ObjectID  `bson:"_id" json:"id"`
+	GrupID             *primitive.ObjectID `bson:"grup_id,omitempty" json:"grup_id,omitempty"`
+	AlatID             primitive.ObjectID  `bson:"alat_id" json:"alat_id"`
+	NamaAlat           string              `bson:"nama_alat" json:"nama_alat"`
+	Jumlah             int                 `bson:"jumlah" json:"jumlah"`
+	JumlahDikembalikan int                 `bson:"jumlah_dikembalikan" json:"jumlah_dikembalikan"`
+	TanggalPinjam      time.Time           `bson:"tanggal_pinjam,omitempty" json:"tanggal_pinjam,omitempty"`
+	JatuhTempo         time.Time           `bson:"jatuh_tempo,omitempty" json:"jatuh_tempo,omitempty"`
+	TanggalKembali     *time.Time          `bson:"tanggal_kembali,omitempty" json:"tanggal_kembali,omitempty"`
+	Status             string              `bson:"status" json:"status"`
+	AlasanPenolakan    string              `bson:"alasan_penolakan,omitempty" json:"alasan_penolakan,omitempty"`
+	CreatedAt          time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// StatusEfektif mengembalikan status untuk ditampilkan, termasuk TERLAMBAT
+func (r *RiwayatPeminjaman) StatusEfektif(now time.Time) string {
+	if terlambat(r.Status, r.JatuhTempo, now) {
+		return StatusTerlambat
+	}
+	return r.Status
+}
+
+// PeminjamanTerlambat adalah transaksi yang lewat jatuh tempo beserta
+// data peminjam dan alat, untuk daftar keterlambatan admin
+type PeminjamanTerlambat struct {
+	ID                 primitive.ObjectID `bson:"_id" json:"id"`
+	UserID             primitive.ObjectID `bson:"user_id" json:"user_id"`
+	NamaPeminjam       string             `bson:"nama_peminjam" json:"nama_peminjam"`
+	EmailPeminjam      string             `bson:"email_peminjam" json:"email_peminjam"`
+	NIM                string             `bson:"nim,omitempty" json:"nim,omitempty"`
+	AlatID             primitive.ObjectID `bson:"alat_id" json:"alat_id"`
+	NamaAlat           string             `bson:"nama_alat" json:"nama_alat"`
+	Kategori           string             `bson:"kategori" json:"kategori"`
+	Jumlah             int                `bson:"jumlah" json:"jumlah"`
+	JumlahDikembalikan int                `bson:"jumlah_dikembalikan" json:"jumlah_dikembalikan"`
+	TanggalPinjam      time.Time          `bson:"tanggal_pinjam" json:"tanggal_pinjam"`
+	JatuhTempo         time.Time          `bson:"jatuh_tempo" json:"jatuh_tempo"`
+	HariTerlambat      int                `bson:"-" json:"hari_terlambat"`
 }
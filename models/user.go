@@ -12,8 +12,17 @@ type User struct {
 	Nama         string             `bson:"nama" json:"nama"`
 	Email        string             `bson:"email" json:"email"`
 	PasswordHash string             `bson:"password_hash" json:"-"`
-	Role         string             `bson:"role" json:"role"` // "admin" atau "mahasiswa"
+	Role         string             `bson:"role" json:"role"` // nama Role, misalnya "admin" atau "mahasiswa"
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
-	NIM 		 string             `bson:"nim,omitempty" json:"nim,omitempty"`
-	Jurusan 	 string             `bson:"jurusan,omitempty" json:"jurusan,omitempty"`
+	NIM          string             `bson:"nim,omitempty" json:"nim,omitempty"`
+	Jurusan      string             `bson:"jurusan,omitempty" json:"jurusan,omitempty"`
+	Phone        string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	AvatarURL    string             `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"`
+	UpdatedAt    time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+
+	// EmailTerverifikasi harus true sebelum user bisa meminjam. Token
+	// verifikasi hanya disimpan sebagai hash sampai email diverifikasi.
+	EmailTerverifikasi  bool      `bson:"email_terverifikasi" json:"email_terverifikasi"`
+	VerifikasiHash      string    `bson:"verifikasi_hash,omitempty" json:"-"`
+	VerifikasiExpiresAt time.Time `bson:"verifikasi_expires_at,omitempty" json:"-"`
 }
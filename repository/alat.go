@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AlatFilter membatasi alat yang diambil. Field kosong diabaikan.
+type AlatFilter struct {
+	Kategori string
+	// Q adalah kata kunci pencarian teks pada nama, kategori dan deskripsi.
+	// Jika diisi dan tidak ada sort lain, hasil diurutkan dari yang paling relevan.
+	Q string
+	// HanyaTersedia membatasi ke alat dengan stok_tersedia > 0
+	HanyaTersedia bool
+	// LabIDs membatasi ke alat milik lab ini. nil berarti semua alat,
+	// slice kosong berarti tidak ada alat yang cocok.
+	LabIDs []primitive.ObjectID
+	// Alat yang sudah dihapus (soft delete) tidak ikut kecuali
+	// TermasukDihapus, sedangkan HanyaDihapus hanya mengambil alat yang dihapus
+	TermasukDihapus bool
+	HanyaDihapus    bool
+}
+
+// AlatRepository mengakses data alat kampus
+type AlatRepository interface {
+	Create(ctx context.Context, alat *models.Alat) error
+	// FindByID juga mengembalikan alat yang sudah dihapus (soft delete),
+	// periksa Alat.Dihapus jika alat harus masih aktif
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Alat, error)
+	// List mengembalikan satu halaman alat beserta jumlah seluruh alat yang cocok
+	List(ctx context.Context, filter AlatFilter, opts ListOptions) ([]models.Alat, int64, error)
+	// ListNama mengembalikan nama unik alat yang cocok dengan filter (Q
+	// diabaikan), dipakai sebagai kandidat saran pencarian
+	ListNama(ctx context.Context, filter AlatFilter) ([]string, error)
+	// Update menyimpan data alat kecuali stok_total dan stok_tersedia, yang
+	// dihitung ulang oleh AlatUnitRepository setiap kali unit berubah
+	Update(ctx context.Context, alat *models.Alat) error
+	// Hapus menandai alat aktif sebagai dihapus pada waktu yang diberikan.
+	// Mengembalikan ErrNotFound jika alat tidak ada atau sudah dihapus.
+	Hapus(ctx context.Context, id primitive.ObjectID, waktu time.Time) error
+	// Pulihkan membatalkan penghapusan alat. Mengembalikan ErrNotFound jika
+	// alat tidak ada atau tidak sedang dihapus.
+	Pulihkan(ctx context.Context, id primitive.ObjectID) error
+	// Delete menghapus dokumen alat secara permanen
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Kunci menulis dokumen alat di dalam transaksi supaya transaksi paralel
+	// yang menyentuh alat yang sama saling konflik dan dijalankan berurutan.
+	// Dipakai sebelum cek ketersediaan yang hanya membaca dokumen lain.
+	Kunci(ctx context.Context, id primitive.ObjectID) error
+}
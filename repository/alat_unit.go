@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AlatUnitRepository mengakses unit fisik alat. Setiap perubahan unit
+// menghitung ulang stok_total dan stok_tersedia pada dokumen alat.
+type AlatUnitRepository interface {
+	// Create menyimpan unit baru. Mengembalikan ErrDuplikat jika kode aset
+	// sudah dipakai.
+	Create(ctx context.Context, unit *models.AlatUnit) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.AlatUnit, error)
+	ListByAlat(ctx context.Context, alatID primitive.ObjectID) ([]models.AlatUnit, error)
+	Update(ctx context.Context, unit *models.AlatUnit) error
+	DeleteByAlat(ctx context.Context, alatID primitive.ObjectID) error
+	// Pinjamkan menandai sejumlah unit TERSEDIA milik alat sebagai DIPINJAM
+	// untuk transaksi. Mengembalikan ErrStokTidakCukup jika unit tersedia
+	// kurang; harus dipanggil di dalam WithTransaction supaya unit yang
+	// sempat ditandai ikut dibatalkan.
+	Pinjamkan(ctx context.Context, alatID, transID primitive.ObjectID, jumlah int) ([]models.AlatUnit, error)
+	// Kembalikan menandai unit yang sedang DIPINJAM menjadi TERSEDIA lagi.
+	// Unit yang sudah dihapus dilewati.
+	Kembalikan(ctx context.Context, ids []primitive.ObjectID) error
+	// HitungUlang menghitung ulang stok_total dan stok_tersedia alat dari
+	// unitnya, dipakai rekonsiliasi jika data alat sempat tidak sinkron
+	HitungUlang(ctx context.Context, alatID primitive.ObjectID) error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AturanRepository mengakses aturan kuota & kelayakan peminjaman
+type AturanRepository interface {
+	Create(ctx context.Context, aturan *models.AturanPeminjaman) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.AturanPeminjaman, error)
+	// List mengembalikan semua aturan urut waktu dibuat
+	List(ctx context.Context) ([]models.AturanPeminjaman, error)
+	Update(ctx context.Context, aturan *models.AturanPeminjaman) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
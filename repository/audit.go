@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditFilter membatasi audit log yang diambil. Field kosong diabaikan.
+// Dari dan Sampai membatasi waktu perubahan (inklusif).
+type AuditFilter struct {
+	AktorID  *primitive.ObjectID
+	Aksi     string
+	Target   string
+	TargetID *primitive.ObjectID
+	Dari     time.Time
+	Sampai   time.Time
+}
+
+// AuditRepository menyimpan audit log. Sengaja tidak ada method untuk
+// mengubah atau menghapus catatan.
+type AuditRepository interface {
+	Catat(ctx context.Context, log *models.AuditLog) error
+	// List mengembalikan audit log yang cocok, default dari yang terbaru
+	List(ctx context.Context, filter AuditFilter, opts ListOptions) ([]models.AuditLog, int64, error)
+}
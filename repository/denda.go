@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DendaFilter membatasi denda yang diambil. Field kosong diabaikan.
+type DendaFilter struct {
+	UserID *primitive.ObjectID
+	Status string
+	Jenis  string
+}
+
+// DendaRepository mengakses catatan denda dan pembayarannya
+type DendaRepository interface {
+	Create(ctx context.Context, denda *models.Denda) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Denda, error)
+	List(ctx context.Context, filter DendaFilter, opts ListOptions) ([]models.Denda, int64, error)
+	Update(ctx context.Context, denda *models.Denda) error
+}
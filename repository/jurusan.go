@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JurusanRepository mengakses daftar jurusan
+type JurusanRepository interface {
+	Create(ctx context.Context, jurusan *models.Jurusan) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Jurusan, error)
+	FindByNama(ctx context.Context, nama string) (*models.Jurusan, error)
+	// List mengembalikan semua jurusan urut nama
+	List(ctx context.Context) ([]models.Jurusan, error)
+	Update(ctx context.Context, jurusan *models.Jurusan) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KasusFilter membatasi kasus kerusakan yang diambil. Field kosong diabaikan.
+type KasusFilter struct {
+	UserID *primitive.ObjectID
+	AlatID *primitive.ObjectID
+	Status string
+	// AlatIDs membatasi ke alat ini, misalnya alat milik satu lab. nil
+	// berarti semua alat, slice kosong berarti tidak ada yang cocok.
+	AlatIDs []primitive.ObjectID
+}
+
+// KasusRepository mengakses data kasus kerusakan / kehilangan unit
+type KasusRepository interface {
+	Create(ctx context.Context, kasus *models.KasusKerusakan) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.KasusKerusakan, error)
+	List(ctx context.Context, filter KasusFilter, opts ListOptions) ([]models.KasusKerusakan, int64, error)
+	Update(ctx context.Context, kasus *models.KasusKerusakan) error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KategoriRepository mengakses aturan per kategori alat
+type KategoriRepository interface {
+	Create(ctx context.Context, kategori *models.Kategori) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Kategori, error)
+	FindByNama(ctx context.Context, nama string) (*models.Kategori, error)
+	List(ctx context.Context) ([]models.Kategori, error)
+	Update(ctx context.Context, kategori *models.Kategori) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
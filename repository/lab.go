@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LabFilter membatasi lab yang diambil. Field kosong diabaikan.
+type LabFilter struct {
+	// StaffID mencari lab tempat user ini menjadi staff
+	StaffID *primitive.ObjectID
+}
+
+// LabRepository mengakses data laboratorium / lokasi alat
+type LabRepository interface {
+	// Create menyimpan lab baru. Mengembalikan ErrDuplikat jika kode sudah dipakai.
+	Create(ctx context.Context, lab *models.Lab) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Lab, error)
+	// List mengembalikan semua lab yang cocok urut nama
+	List(ctx context.Context, filter LabFilter) ([]models.Lab, error)
+	Update(ctx context.Context, lab *models.Lab) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListOptions mengatur paginasi dan urutan hasil list. Nilai nol berarti
+// semua data dengan urutan default repository.
+type ListOptions struct {
+	Page  int // mulai dari 1
+	Limit int // 0 berarti tanpa batas
+	// SortBy adalah nama field bson yang sudah divalidasi pemanggil
+	SortBy string
+	Desc   bool
+}
+
+// skip menghitung jumlah data yang dilewati untuk halaman yang diminta
+func (o ListOptions) skip() int {
+	if o.Limit <= 0 || o.Page <= 1 {
+		return 0
+	}
+	return (o.Page - 1) * o.Limit
+}
+
+// sort mengembalikan urutan Mongo, dengan _id sebagai penentu urutan
+// data yang nilainya sama supaya paginasi stabil
+func (o ListOptions) sort(defaultSort bson.D) bson.D {
+	if o.SortBy == "" {
+		return defaultSort
+	}
+	dir := 1
+	if o.Desc {
+		dir = -1
+	}
+	return bson.D{{Key: o.SortBy, Value: dir}, {Key: "_id", Value: dir}}
+}
+
+// findOptions menyusun opsi Find untuk halaman yang diminta
+func (o ListOptions) findOptions(defaultSort bson.D) *options.FindOptions {
+	opts := options.Find().SetSort(o.sort(defaultSort))
+	if o.Limit > 0 {
+		opts.SetSkip(int64(o.skip())).SetLimit(int64(o.Limit))
+	}
+	return opts
+}
+
+// stages menyusun tahap $sort, $skip dan $limit untuk aggregation pipeline
+func (o ListOptions) stages(defaultSort bson.D) []bson.D {
+	stages := []bson.D{{{Key: "$sort", Value: o.sort(defaultSort)}}}
+	if o.Limit > 0 {
+		stages = append(stages,
+			bson.D{{Key: "$skip", Value: int64(o.skip())}},
+			bson.D{{Key: "$limit", Value: int64(o.Limit)}},
+		)
+	}
+	return stages
+}
+
+// total menghitung jumlah seluruh data yang cocok dengan query. Tanpa
+// limit, semua data sudah terambil sehingga tidak perlu query tambahan.
+func (o ListOptions) total(ctx context.Context, col *mongo.Collection, query interface{}, n int) (int64, error) {
+	if o.Limit <= 0 {
+		return int64(n), nil
+	}
+	return col.CountDocuments(ctx, query)
+}
+
+// findPage menjalankan Find dengan paginasi dan mengembalikan total datanya
+func findPage[T any](ctx context.Context, col *mongo.Collection, query bson.M, opts ListOptions, defaultSort bson.D) ([]T, int64, error) {
+	cursor, err := col.Find(ctx, query, opts.findOptions(defaultSort))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []T
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, 0, err
+	}
+	total, err := opts.total(ctx, col, query, len(list))
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// saringAlat menambahkan filter alat_id ke query dari filter satu alat
+// dan/atau daftar alat
+func saringAlat(query bson.M, alatID *primitive.ObjectID, alatIDs []primitive.ObjectID) {
+	kondisi := bson.M{}
+	if alatID != nil {
+		kondisi["$eq"] = *alatID
+	}
+	if alatIDs != nil {
+		kondisi["$in"] = alatIDs
+	}
+	if len(kondisi) > 0 {
+		query["alat_id"] = kondisi
+	}
+}
+
+// cocokAlat adalah padanan saringAlat untuk store in-memory
+func cocokAlat(id primitive.ObjectID, alatID *primitive.ObjectID, alatIDs []primitive.ObjectID) bool {
+	if alatID != nil && id != *alatID {
+		return false
+	}
+	return alatIDs == nil || slices.Contains(alatIDs, id)
+}
+
+// pembanding membandingkan dua data berdasarkan satu field
+type pembanding[T any] func(a, b T) int
+
+// halaman mengurutkan list in-memory sesuai opts lalu mengambil halaman
+// yang diminta. Jika SortBy kosong atau tidak dikenal, urutan list dipakai apa adanya.
+func halaman[T any](list []T, opts ListOptions, kolom map[string]pembanding[T]) ([]T, int64) {
+	if cmp, ok := kolom[opts.SortBy]; ok {
+		sort.SliceStable(list, func(i, j int) bool {
+			if opts.Desc {
+				return cmp(list[i], list[j]) > 0
+			}
+			return cmp(list[i], list[j]) < 0
+		})
+	}
+
+	total := int64(len(list))
+	if opts.Limit <= 0 {
+		return list, total
+	}
+	mulai := opts.skip()
+	if mulai >= len(list) {
+		return []T{}, total
+	}
+	akhir := mulai + opts.Limit
+	if akhir > len(list) {
+		akhir = len(list)
+	}
+	return list[mulai:akhir], total
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryStore membuat Store yang menyimpan data di memori.
+// Cocok untuk test dan menjalankan API secara offline.
+func NewMemoryStore() *Store {
+	db := &memoryDB{}
+	users := newTable[models.User](db)
+	alat := newTable[models.Alat](db)
+	units := newTable[models.AlatUnit](db)
+	transactions := newTable[models.Transaction](db)
+	kategori := newTable[models.Kategori](db)
+	jurusan := newTable[models.Jurusan](db)
+	denda := newTable[models.Denda](db)
+	kasus := newTable[models.KasusKerusakan](db)
+	reservasi := newTable[models.Reservasi](db)
+	sessions := newTable[models.Session](db)
+	resets := newTable[models.PasswordReset](db)
+	audit := newTable[models.AuditLog](db)
+	mutasi := newTable[models.MutasiStok](db)
+	roles := newTable[models.Role](db)
+	lab := newTable[models.Lab](db)
+	aturan := newTable[models.AturanPeminjaman](db)
+	for _, role := range models.RoleBawaan(time.Now()) {
+		roles.put(role.ID, role)
+	}
+
+	return &Store{
+		Users:        &memoryUserRepository{db: db, users: users},
+		Alat:         &memoryAlatRepository{db: db, alat: alat},
+		Unit:         &memoryAlatUnitRepository{db: db, units: units, alat: alat},
+		Transactions: &memoryTransactionRepository{db: db, transactions: transactions, alat: alat, users: users},
+		Kategori:     &memoryKategoriRepository{db: db, kategori: kategori},
+		Jurusan:      &memoryJurusanRepository{db: db, jurusan: jurusan},
+		Denda:        &memoryDendaRepository{db: db, denda: denda},
+		Kasus:        &memoryKasusRepository{db: db, kasus: kasus},
+		Reservasi:    &memoryReservasiRepository{db: db, reservasi: reservasi},
+		Sessions:     &memorySessionRepository{db: db, sessions: sessions},
+		Resets:       &memoryPasswordResetRepository{db: db, resets: resets},
+		Audit:        &memoryAuditRepository{db: db, audit: audit},
+		MutasiStok:   &memoryMutasiStokRepository{db: db, mutasi: mutasi},
+		Roles:        &memoryRoleRepository{db: db, role: roles},
+		Lab:          &memoryLabRepository{db: db, lab: lab},
+		Aturan:       &memoryAturanRepository{db: db, aturan: aturan},
+		Tx:           db,
+	}
+}
+
+// memoryDB memegang satu mutex untuk semua tabel in-memory sehingga
+// WithTransaction bisa mengunci seluruh data selama fn berjalan
+type memoryDB struct {
+	mu     sync.Mutex
+	tables []snapshotter
+}
+
+// txKey menandai ctx yang sedang berada di dalam WithTransaction
+type txKey struct{}
+
+// lock mengunci database, kecuali ctx sudah memegang kunci lewat WithTransaction
+func (db *memoryDB) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == db {
+		return func() {}
+	}
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+// WithTransaction menjalankan fn secara eksklusif. Jika fn gagal,
+// semua tabel dikembalikan ke kondisi sebelum fn dijalankan.
+func (db *memoryDB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == db {
+		return fn(ctx)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	restores := make([]func(), 0, len(db.tables))
+	for _, t := range db.tables {
+		restores = append(restores, t.snapshot())
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, db)); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return err
+	}
+	return nil
+}
+
+// snapshotter disimpan memoryDB untuk rollback transaksi
+type snapshotter interface {
+	snapshot() (restore func())
+}
+
+// table menyimpan dokumen berdasarkan _id dengan urutan insert,
+// meniru natural order koleksi MongoDB
+type table[T any] struct {
+	rows  map[primitive.ObjectID]T
+	order []primitive.ObjectID
+}
+
+func newTable[T any](db *memoryDB) *table[T] {
+	t := &table[T]{rows: map[primitive.ObjectID]T{}}
+	db.tables = append(db.tables, t)
+	return t
+}
+
+func (t *table[T]) get(id primitive.ObjectID) (T, bool) {
+	row, ok := t.rows[id]
+	return row, ok
+}
+
+func (t *table[T]) put(id primitive.ObjectID, row T) {
+	if _, ok := t.rows[id]; !ok {
+		t.order = append(t.order, id)
+	}
+	t.rows[id] = row
+}
+
+func (t *table[T]) delete(id primitive.ObjectID) bool {
+	if _, ok := t.rows[id]; !ok {
+		return false
+	}
+	delete(t.rows, id)
+	for i, oid := range t.order {
+		if oid == id {
+			t.order = append(t.order[:i:i], t.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// all mengembalikan semua dokumen yang lolos match (nil = semua)
+func (t *table[T]) all(match func(T) bool) []T {
+	var out []T
+	for _, id := range t.order {
+		row := t.rows[id]
+		if match == nil || match(row) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+func (t *table[T]) snapshot() func() {
+	rows := make(map[primitive.ObjectID]T, len(t.rows))
+	for id, row := range t.rows {
+		rows[id] = row
+	}
+	order := append([]primitive.ObjectID(nil), t.order...)
+	return func() {
+		t.rows = rows
+		t.order = order
+	}
+}
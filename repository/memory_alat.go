@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryAlatRepository struct {
+	db   *memoryDB
+	alat *table[models.Alat]
+}
+
+func (r *memoryAlatRepository) Create(ctx context.Context, alat *models.Alat) error {
+	defer r.db.lock(ctx)()
+	r.alat.put(alat.ID, *alat)
+	return nil
+}
+
+func (r *memoryAlatRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Alat, error) {
+	defer r.db.lock(ctx)()
+	alat, ok := r.alat.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &alat, nil
+}
+
+// urutanAlat adalah field alat yang bisa dipakai untuk mengurutkan list
+var urutanAlat = map[string]pembanding[models.Alat]{
+	"nama":          func(a, b models.Alat) int { return strings.Compare(a.Nama, b.Nama) },
+	"kategori":      func(a, b models.Alat) int { return strings.Compare(a.Kategori, b.Kategori) },
+	"stok_tersedia": func(a, b models.Alat) int { return cmp.Compare(a.StokTersedia, b.StokTersedia) },
+	"stok_total":    func(a, b models.Alat) int { return cmp.Compare(a.StokTotal, b.StokTotal) },
+	"created_at":    func(a, b models.Alat) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// matchAlat mengecek filter selain pencarian teks
+func matchAlat(filter AlatFilter) func(models.Alat) bool {
+	return func(a models.Alat) bool {
+		if filter.Kategori != "" && a.Kategori != filter.Kategori {
+			return false
+		}
+		if filter.HanyaTersedia && a.StokTersedia <= 0 {
+			return false
+		}
+		if filter.LabIDs != nil && (a.LabID == nil || !slices.Contains(filter.LabIDs, *a.LabID)) {
+			return false
+		}
+		if filter.HanyaDihapus {
+			return a.Dihapus()
+		}
+		return filter.TermasukDihapus || !a.Dihapus()
+	}
+}
+
+// Bobot relevansi tiap field, sama dengan bobot text index di MongoDB
+var bobotTeksAlat = map[string]int{"nama": 10, "kategori": 5, "deskripsi": 1}
+
+// skorTeks meniru $text MongoDB tanpa stemming: jumlah bobot field untuk
+// setiap kata kunci yang muncul sebagai kata utuh. 0 berarti tidak cocok.
+func skorTeks(a models.Alat, kataKunci []string) int {
+	field := map[string]string{"nama": a.Nama, "kategori": a.Kategori, "deskripsi": a.Deskripsi}
+	skor := 0
+	for nama, isi := range field {
+		kata := utils.PecahKata(isi)
+		for _, k := range kataKunci {
+			if slices.Contains(kata, k) {
+				skor += bobotTeksAlat[nama]
+			}
+		}
+	}
+	return skor
+}
+
+func (r *memoryAlatRepository) List(ctx context.Context, filter AlatFilter, opts ListOptions) ([]models.Alat, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.alat.all(matchAlat(filter))
+
+	if filter.Q != "" {
+		kataKunci := utils.PecahKata(filter.Q)
+		skor := make(map[primitive.ObjectID]int, len(list))
+		list = slices.DeleteFunc(list, func(a models.Alat) bool {
+			skor[a.ID] = skorTeks(a, kataKunci)
+			return skor[a.ID] == 0
+		})
+		if opts.SortBy == "" {
+			sort.SliceStable(list, func(i, j int) bool { return skor[list[i].ID] > skor[list[j].ID] })
+		}
+	}
+
+	list, total := halaman(list, opts, urutanAlat)
+	return list, total, nil
+}
+
+func (r *memoryAlatRepository) ListNama(ctx context.Context, filter AlatFilter) ([]string, error) {
+	defer r.db.lock(ctx)()
+	var nama []string
+	for _, a := range r.alat.all(matchAlat(filter)) {
+		if !slices.Contains(nama, a.Nama) {
+			nama = append(nama, a.Nama)
+		}
+	}
+	return nama, nil
+}
+
+func (r *memoryAlatRepository) Update(ctx context.Context, alat *models.Alat) error {
+	defer r.db.lock(ctx)()
+	current, ok := r.alat.get(alat.ID)
+	if !ok {
+		return ErrNotFound
+	}
+	updated := *alat
+	updated.StokTotal = current.StokTotal
+	updated.StokTersedia = current.StokTersedia
+	updated.CreatedAt = current.CreatedAt
+	updated.DeletedAt = current.DeletedAt
+	r.alat.put(alat.ID, updated)
+	return nil
+}
+
+func (r *memoryAlatRepository) Hapus(ctx context.Context, id primitive.ObjectID, waktu time.Time) error {
+	defer r.db.lock(ctx)()
+	alat, ok := r.alat.get(id)
+	if !ok || alat.Dihapus() {
+		return ErrNotFound
+	}
+	alat.DeletedAt = &waktu
+	alat.UpdatedAt = waktu
+	r.alat.put(id, alat)
+	return nil
+}
+
+func (r *memoryAlatRepository) Pulihkan(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	alat, ok := r.alat.get(id)
+	if !ok || !alat.Dihapus() {
+		return ErrNotFound
+	}
+	alat.DeletedAt = nil
+	alat.UpdatedAt = time.Now()
+	r.alat.put(id, alat)
+	return nil
+}
+
+func (r *memoryAlatRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.alat.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryAlatRepository) Kunci(ctx context.Context, id primitive.ObjectID) error {
+	// Semua operasi in-memory di dalam WithTransaction sudah berjalan eksklusif
+	defer r.db.lock(ctx)()
+	if _, ok := r.alat.get(id); !ok {
+		return ErrNotFound
+	}
+	return nil
+}
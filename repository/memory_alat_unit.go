@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryAlatUnitRepository struct {
+	db    *memoryDB
+	units *table[models.AlatUnit]
+	alat  *table[models.Alat]
+}
+
+// hitungStok menyimpan jumlah unit beredar dan tersedia ke data alat.
+// Dipanggil saat kunci database sudah dipegang.
+func (r *memoryAlatUnitRepository) hitungStok(alatID primitive.ObjectID) {
+	alat, ok := r.alat.get(alatID)
+	if !ok {
+		return
+	}
+	alat.StokTotal, alat.StokTersedia = 0, 0
+	for _, u := range r.units.all(func(u models.AlatUnit) bool { return u.AlatID == alatID }) {
+		if models.UnitBeredar(u.Status) {
+			alat.StokTotal++
+		}
+		if u.Status == models.UnitTersedia {
+			alat.StokTersedia++
+		}
+	}
+	alat.UpdatedAt = time.Now()
+	r.alat.put(alatID, alat)
+}
+
+// kodeDipakai memeriksa apakah kode aset sudah dipakai unit lain
+func (r *memoryAlatUnitRepository) kodeDipakai(unit *models.AlatUnit) bool {
+	return len(r.units.all(func(u models.AlatUnit) bool {
+		return u.KodeAset == unit.KodeAset && u.ID != unit.ID
+	})) > 0
+}
+
+func (r *memoryAlatUnitRepository) Create(ctx context.Context, unit *models.AlatUnit) error {
+	defer r.db.lock(ctx)()
+	if r.kodeDipakai(unit) {
+		return ErrDuplikat
+	}
+	r.units.put(unit.ID, *unit)
+	r.hitungStok(unit.AlatID)
+	return nil
+}
+
+func (r *memoryAlatUnitRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.AlatUnit, error) {
+	defer r.db.lock(ctx)()
+	unit, ok := r.units.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &unit, nil
+}
+
+func (r *memoryAlatUnitRepository) ListByAlat(ctx context.Context, alatID primitive.ObjectID) ([]models.AlatUnit, error) {
+	defer r.db.lock(ctx)()
+	return r.listByAlat(alatID, ""), nil
+}
+
+// listByAlat mengambil unit alat urut kode aset, opsional difilter status
+func (r *memoryAlatUnitRepository) listByAlat(alatID primitive.ObjectID, status string) []models.AlatUnit {
+	list := r.units.all(func(u models.AlatUnit) bool {
+		return u.AlatID == alatID && (status == "" || u.Status == status)
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].KodeAset < list[j].KodeAset
+	})
+	return list
+}
+
+func (r *memoryAlatUnitRepository) Update(ctx context.Context, unit *models.AlatUnit) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.units.get(unit.ID); !ok {
+		return ErrNotFound
+	}
+	if r.kodeDipakai(unit) {
+		return ErrDuplikat
+	}
+	r.units.put(unit.ID, *unit)
+	r.hitungStok(unit.AlatID)
+	return nil
+}
+
+func (r *memoryAlatUnitRepository) DeleteByAlat(ctx context.Context, alatID primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	for _, u := range r.listByAlat(alatID, "") {
+		r.units.delete(u.ID)
+	}
+	return nil
+}
+
+func (r *memoryAlatUnitRepository) Pinjamkan(ctx context.Context, alatID, transID primitive.ObjectID, jumlah int) ([]models.AlatUnit, error) {
+	defer r.db.lock(ctx)()
+	if _, ok := r.alat.get(alatID); !ok {
+		return nil, ErrNotFound
+	}
+	tersedia := r.listByAlat(alatID, models.UnitTersedia)
+	if len(tersedia) < jumlah {
+		return nil, ErrStokTidakCukup
+	}
+
+	now := time.Now()
+	units := tersedia[:jumlah]
+	for i := range units {
+		units[i].Status = models.UnitDipinjam
+		units[i].TransactionID = &transID
+		units[i].UpdatedAt = now
+		r.units.put(units[i].ID, units[i])
+	}
+	r.hitungStok(alatID)
+	return units, nil
+}
+
+func (r *memoryAlatUnitRepository) Kembalikan(ctx context.Context, ids []primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	now := time.Now()
+	for _, id := range ids {
+		unit, ok := r.units.get(id)
+		if !ok || unit.Status != models.UnitDipinjam {
+			continue
+		}
+		unit.Status = models.UnitTersedia
+		unit.TransactionID = nil
+		unit.UpdatedAt = now
+		r.units.put(id, unit)
+		r.hitungStok(unit.AlatID)
+	}
+	return nil
+}
+
+func (r *memoryAlatUnitRepository) HitungUlang(ctx context.Context, alatID primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.alat.get(alatID); !ok {
+		return ErrNotFound
+	}
+	r.hitungStok(alatID)
+	return nil
+}
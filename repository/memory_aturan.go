@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryAturanRepository struct {
+	db     *memoryDB
+	aturan *table[models.AturanPeminjaman]
+}
+
+func (r *memoryAturanRepository) Create(ctx context.Context, aturan *models.AturanPeminjaman) error {
+	defer r.db.lock(ctx)()
+	r.aturan.put(aturan.ID, *aturan)
+	return nil
+}
+
+func (r *memoryAturanRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.AturanPeminjaman, error) {
+	defer r.db.lock(ctx)()
+	aturan, ok := r.aturan.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &aturan, nil
+}
+
+func (r *memoryAturanRepository) List(ctx context.Context) ([]models.AturanPeminjaman, error) {
+	defer r.db.lock(ctx)()
+	return r.aturan.all(nil), nil
+}
+
+func (r *memoryAturanRepository) Update(ctx context.Context, aturan *models.AturanPeminjaman) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.aturan.get(aturan.ID); !ok {
+		return ErrNotFound
+	}
+	r.aturan.put(aturan.ID, *aturan)
+	return nil
+}
+
+func (r *memoryAturanRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.aturan.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"SIPAK/models"
+)
+
+type memoryAuditRepository struct {
+	db    *memoryDB
+	audit *table[models.AuditLog]
+}
+
+func (r *memoryAuditRepository) Catat(ctx context.Context, log *models.AuditLog) error {
+	defer r.db.lock(ctx)()
+	r.audit.put(log.ID, *log)
+	return nil
+}
+
+// urutanAudit adalah field audit log yang bisa dipakai untuk mengurutkan list
+var urutanAudit = map[string]pembanding[models.AuditLog]{
+	"waktu": func(a, b models.AuditLog) int { return a.Waktu.Compare(b.Waktu) },
+}
+
+func (r *memoryAuditRepository) List(ctx context.Context, filter AuditFilter, opts ListOptions) ([]models.AuditLog, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.audit.all(func(l models.AuditLog) bool {
+		if filter.AktorID != nil && l.AktorID != *filter.AktorID {
+			return false
+		}
+		if filter.Aksi != "" && l.Aksi != filter.Aksi {
+			return false
+		}
+		if filter.Target != "" && l.Target != filter.Target {
+			return false
+		}
+		if filter.TargetID != nil && l.TargetID != *filter.TargetID {
+			return false
+		}
+		if !filter.Dari.IsZero() && l.Waktu.Before(filter.Dari) {
+			return false
+		}
+		if !filter.Sampai.IsZero() && l.Waktu.After(filter.Sampai) {
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Waktu.After(list[j].Waktu)
+	})
+	list, total := halaman(list, opts, urutanAudit)
+	return list, total, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"sort"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryDendaRepository struct {
+	db    *memoryDB
+	denda *table[models.Denda]
+}
+
+func (r *memoryDendaRepository) Create(ctx context.Context, denda *models.Denda) error {
+	defer r.db.lock(ctx)()
+	r.denda.put(denda.ID, *denda)
+	return nil
+}
+
+func (r *memoryDendaRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Denda, error) {
+	defer r.db.lock(ctx)()
+	denda, ok := r.denda.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	denda.Pembayaran = append([]models.PembayaranDenda(nil), denda.Pembayaran...)
+	return &denda, nil
+}
+
+// urutanDenda adalah field denda yang bisa dipakai untuk mengurutkan list
+var urutanDenda = map[string]pembanding[models.Denda]{
+	"created_at": func(a, b models.Denda) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"jumlah":     func(a, b models.Denda) int { return cmp.Compare(a.Jumlah, b.Jumlah) },
+}
+
+func (r *memoryDendaRepository) List(ctx context.Context, filter DendaFilter, opts ListOptions) ([]models.Denda, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.denda.all(func(d models.Denda) bool {
+		if filter.UserID != nil && d.UserID != *filter.UserID {
+			return false
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			return false
+		}
+		if filter.Jenis != "" && jenisDenda(d) != filter.Jenis {
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	list, total := halaman(list, opts, urutanDenda)
+	return list, total, nil
+}
+
+// jenisDenda mengembalikan jenis denda, denda lama tanpa jenis adalah TERLAMBAT
+func jenisDenda(d models.Denda) string {
+	if d.Jenis == "" {
+		return models.DendaJenisTerlambat
+	}
+	return d.Jenis
+}
+
+func (r *memoryDendaRepository) Update(ctx context.Context, denda *models.Denda) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.denda.get(denda.ID); !ok {
+		return ErrNotFound
+	}
+	r.denda.put(denda.ID, *denda)
+	return nil
+}
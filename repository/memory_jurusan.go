@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryJurusanRepository struct {
+	db      *memoryDB
+	jurusan *table[models.Jurusan]
+}
+
+func (r *memoryJurusanRepository) Create(ctx context.Context, jurusan *models.Jurusan) error {
+	defer r.db.lock(ctx)()
+	r.jurusan.put(jurusan.ID, *jurusan)
+	return nil
+}
+
+func (r *memoryJurusanRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Jurusan, error) {
+	defer r.db.lock(ctx)()
+	jurusan, ok := r.jurusan.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &jurusan, nil
+}
+
+func (r *memoryJurusanRepository) FindByNama(ctx context.Context, nama string) (*models.Jurusan, error) {
+	defer r.db.lock(ctx)()
+	found := r.jurusan.all(func(k models.Jurusan) bool { return k.Nama == nama })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &found[0], nil
+}
+
+func (r *memoryJurusanRepository) List(ctx context.Context) ([]models.Jurusan, error) {
+	defer r.db.lock(ctx)()
+	list := r.jurusan.all(nil)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Nama < list[j].Nama })
+	return list, nil
+}
+
+func (r *memoryJurusanRepository) Update(ctx context.Context, jurusan *models.Jurusan) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.jurusan.get(jurusan.ID); !ok {
+		return ErrNotFound
+	}
+	r.jurusan.put(jurusan.ID, *jurusan)
+	return nil
+}
+
+func (r *memoryJurusanRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.jurusan.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
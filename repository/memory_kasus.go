@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryKasusRepository struct {
+	db    *memoryDB
+	kasus *table[models.KasusKerusakan]
+}
+
+func (r *memoryKasusRepository) Create(ctx context.Context, kasus *models.KasusKerusakan) error {
+	defer r.db.lock(ctx)()
+	r.kasus.put(kasus.ID, *kasus)
+	return nil
+}
+
+func (r *memoryKasusRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.KasusKerusakan, error) {
+	defer r.db.lock(ctx)()
+	kasus, ok := r.kasus.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &kasus, nil
+}
+
+// urutanKasus adalah field kasus yang bisa dipakai untuk mengurutkan list
+var urutanKasus = map[string]pembanding[models.KasusKerusakan]{
+	"created_at": func(a, b models.KasusKerusakan) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"status":     func(a, b models.KasusKerusakan) int { return strings.Compare(a.Status, b.Status) },
+}
+
+func (r *memoryKasusRepository) List(ctx context.Context, filter KasusFilter, opts ListOptions) ([]models.KasusKerusakan, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.kasus.all(func(k models.KasusKerusakan) bool {
+		if filter.UserID != nil && k.UserID != *filter.UserID {
+			return false
+		}
+		if !cocokAlat(k.AlatID, filter.AlatID, filter.AlatIDs) {
+			return false
+		}
+		if filter.Status != "" && k.Status != filter.Status {
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	list, total := halaman(list, opts, urutanKasus)
+	return list, total, nil
+}
+
+func (r *memoryKasusRepository) Update(ctx context.Context, kasus *models.KasusKerusakan) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.kasus.get(kasus.ID); !ok {
+		return ErrNotFound
+	}
+	r.kasus.put(kasus.ID, *kasus)
+	return nil
+}
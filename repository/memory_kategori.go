@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryKategoriRepository struct {
+	db       *memoryDB
+	kategori *table[models.Kategori]
+}
+
+func (r *memoryKategoriRepository) Create(ctx context.Context, kategori *models.Kategori) error {
+	defer r.db.lock(ctx)()
+	r.kategori.put(kategori.ID, *kategori)
+	return nil
+}
+
+func (r *memoryKategoriRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Kategori, error) {
+	defer r.db.lock(ctx)()
+	kategori, ok := r.kategori.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &kategori, nil
+}
+
+func (r *memoryKategoriRepository) FindByNama(ctx context.Context, nama string) (*models.Kategori, error) {
+	defer r.db.lock(ctx)()
+	found := r.kategori.all(func(k models.Kategori) bool { return k.Nama == nama })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &found[0], nil
+}
+
+func (r *memoryKategoriRepository) List(ctx context.Context) ([]models.Kategori, error) {
+	defer r.db.lock(ctx)()
+	return r.kategori.all(nil), nil
+}
+
+func (r *memoryKategoriRepository) Update(ctx context.Context, kategori *models.Kategori) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.kategori.get(kategori.ID); !ok {
+		return ErrNotFound
+	}
+	r.kategori.put(kategori.ID, *kategori)
+	return nil
+}
+
+func (r *memoryKategoriRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.kategori.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
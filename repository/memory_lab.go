@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryLabRepository struct {
+	db  *memoryDB
+	lab *table[models.Lab]
+}
+
+func (r *memoryLabRepository) Create(ctx context.Context, lab *models.Lab) error {
+	defer r.db.lock(ctx)()
+	if r.kodeDipakai(*lab) {
+		return ErrDuplikat
+	}
+	r.lab.put(lab.ID, *lab)
+	return nil
+}
+
+func (r *memoryLabRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Lab, error) {
+	defer r.db.lock(ctx)()
+	lab, ok := r.lab.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &lab, nil
+}
+
+// kodeDipakai mengecek apakah kode lab sudah dipakai lab lain
+func (r *memoryLabRepository) kodeDipakai(lab models.Lab) bool {
+	return len(r.lab.all(func(l models.Lab) bool { return l.Kode == lab.Kode && l.ID != lab.ID })) > 0
+}
+
+func (r *memoryLabRepository) List(ctx context.Context, filter LabFilter) ([]models.Lab, error) {
+	defer r.db.lock(ctx)()
+	list := r.lab.all(func(l models.Lab) bool {
+		return filter.StaffID == nil || slices.Contains(l.StaffIDs, *filter.StaffID)
+	})
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Nama < list[j].Nama })
+	return list, nil
+}
+
+func (r *memoryLabRepository) Update(ctx context.Context, lab *models.Lab) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.lab.get(lab.ID); !ok {
+		return ErrNotFound
+	}
+	if r.kodeDipakai(*lab) {
+		return ErrDuplikat
+	}
+	r.lab.put(lab.ID, *lab)
+	return nil
+}
+
+func (r *memoryLabRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.lab.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
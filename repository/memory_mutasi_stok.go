@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"SIPAK/models"
+)
+
+type memoryMutasiStokRepository struct {
+	db     *memoryDB
+	mutasi *table[models.MutasiStok]
+}
+
+func (r *memoryMutasiStokRepository) Catat(ctx context.Context, m *models.MutasiStok) error {
+	defer r.db.lock(ctx)()
+	r.mutasi.put(m.ID, *m)
+	return nil
+}
+
+// urutanMutasiStok adalah field mutasi stok yang bisa dipakai untuk mengurutkan list
+var urutanMutasiStok = map[string]pembanding[models.MutasiStok]{
+	"waktu": func(a, b models.MutasiStok) int { return a.Waktu.Compare(b.Waktu) },
+}
+
+func (r *memoryMutasiStokRepository) List(ctx context.Context, filter MutasiStokFilter, opts ListOptions) ([]models.MutasiStok, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.mutasi.all(func(m models.MutasiStok) bool {
+		if !cocokAlat(m.AlatID, filter.AlatID, filter.AlatIDs) {
+			return false
+		}
+		if filter.Jenis != "" && m.Jenis != filter.Jenis {
+			return false
+		}
+		if filter.TransactionID != nil && (m.TransactionID == nil || *m.TransactionID != *filter.TransactionID) {
+			return false
+		}
+		if !filter.Dari.IsZero() && m.Waktu.Before(filter.Dari) {
+			return false
+		}
+		if !filter.Sampai.IsZero() && m.Waktu.After(filter.Sampai) {
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Waktu.After(list[j].Waktu)
+	})
+	list, total := halaman(list, opts, urutanMutasiStok)
+	return list, total, nil
+}
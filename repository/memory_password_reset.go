@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryPasswordResetRepository struct {
+	db     *memoryDB
+	resets *table[models.PasswordReset]
+}
+
+func (r *memoryPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	defer r.db.lock(ctx)()
+	r.resets.put(reset.ID, *reset)
+	return nil
+}
+
+func (r *memoryPasswordResetRepository) Pakai(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	defer r.db.lock(ctx)()
+	list := r.resets.all(func(p models.PasswordReset) bool {
+		return p.TokenHash == tokenHash && p.UsedAt == nil && now.Before(p.ExpiresAt)
+	})
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	reset := list[0]
+	reset.UsedAt = &now
+	r.resets.put(reset.ID, reset)
+	return &reset, nil
+}
+
+func (r *memoryPasswordResetRepository) HapusUser(ctx context.Context, userID primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	for _, p := range r.resets.all(func(p models.PasswordReset) bool { return p.UserID == userID }) {
+		r.resets.delete(p.ID)
+	}
+	return nil
+}
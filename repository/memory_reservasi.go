@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryReservasiRepository struct {
+	db        *memoryDB
+	reservasi *table[models.Reservasi]
+}
+
+func (r *memoryReservasiRepository) Create(ctx context.Context, reservasi *models.Reservasi) error {
+	defer r.db.lock(ctx)()
+	r.reservasi.put(reservasi.ID, *reservasi)
+	return nil
+}
+
+func (r *memoryReservasiRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Reservasi, error) {
+	defer r.db.lock(ctx)()
+	reservasi, ok := r.reservasi.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &reservasi, nil
+}
+
+// urutanReservasi adalah field reservasi yang bisa dipakai untuk mengurutkan list
+var urutanReservasi = map[string]pembanding[models.Reservasi]{
+	"mulai":      func(a, b models.Reservasi) int { return a.Mulai.Compare(b.Mulai) },
+	"created_at": func(a, b models.Reservasi) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+func (r *memoryReservasiRepository) List(ctx context.Context, filter ReservasiFilter, opts ListOptions) ([]models.Reservasi, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.reservasi.all(func(res models.Reservasi) bool {
+		if filter.UserID != nil && res.UserID != *filter.UserID {
+			return false
+		}
+		if !cocokAlat(res.AlatID, filter.AlatID, filter.AlatIDs) {
+			return false
+		}
+		if filter.Status != "" && res.Status != filter.Status {
+			return false
+		}
+		if !filter.Dari.IsZero() && !filter.Sampai.IsZero() &&
+			(!res.Mulai.Before(filter.Sampai) || !res.Selesai.After(filter.Dari)) {
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Mulai.Before(list[j].Mulai)
+	})
+	list, total := halaman(list, opts, urutanReservasi)
+	return list, total, nil
+}
+
+func (r *memoryReservasiRepository) Update(ctx context.Context, reservasi *models.Reservasi) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.reservasi.get(reservasi.ID); !ok {
+		return ErrNotFound
+	}
+	r.reservasi.put(reservasi.ID, *reservasi)
+	return nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryRoleRepository struct {
+	db   *memoryDB
+	role *table[models.Role]
+}
+
+func (r *memoryRoleRepository) Create(ctx context.Context, role *models.Role) error {
+	defer r.db.lock(ctx)()
+	if len(r.role.all(func(k models.Role) bool { return k.Nama == role.Nama })) > 0 {
+		return ErrDuplikat
+	}
+	r.role.put(role.ID, *role)
+	return nil
+}
+
+func (r *memoryRoleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	defer r.db.lock(ctx)()
+	role, ok := r.role.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &role, nil
+}
+
+func (r *memoryRoleRepository) FindByNama(ctx context.Context, nama string) (*models.Role, error) {
+	defer r.db.lock(ctx)()
+	found := r.role.all(func(k models.Role) bool { return k.Nama == nama })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &found[0], nil
+}
+
+func (r *memoryRoleRepository) List(ctx context.Context) ([]models.Role, error) {
+	defer r.db.lock(ctx)()
+	list := r.role.all(nil)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Nama < list[j].Nama })
+	return list, nil
+}
+
+func (r *memoryRoleRepository) Update(ctx context.Context, role *models.Role) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.role.get(role.ID); !ok {
+		return ErrNotFound
+	}
+	r.role.put(role.ID, *role)
+	return nil
+}
+
+func (r *memoryRoleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.db.lock(ctx)()
+	if !r.role.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
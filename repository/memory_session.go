@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memorySessionRepository struct {
+	db       *memoryDB
+	sessions *table[models.Session]
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	defer r.db.lock(ctx)()
+	r.sessions.put(session.ID, *session)
+	return nil
+}
+
+func (r *memorySessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	defer r.db.lock(ctx)()
+	session, ok := r.sessions.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepository) FindByAccessJTI(ctx context.Context, jti string) (*models.Session, error) {
+	defer r.db.lock(ctx)()
+	list := r.sessions.all(func(s models.Session) bool { return s.AccessJTI == jti })
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return &list[0], nil
+}
+
+func (r *memorySessionRepository) Rotasi(ctx context.Context, id primitive.ObjectID, refreshHashLama, refreshHashBaru, accessJTI string, expiresAt, now time.Time) error {
+	defer r.db.lock(ctx)()
+	session, ok := r.sessions.get(id)
+	if !ok || session.RefreshHash != refreshHashLama || session.RevokedAt != nil {
+		return ErrNotFound
+	}
+	session.RefreshHash = refreshHashBaru
+	session.AccessJTI = accessJTI
+	session.ExpiresAt = expiresAt
+	session.UpdatedAt = now
+	r.sessions.put(id, session)
+	return nil
+}
+
+func (r *memorySessionRepository) Revoke(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	defer r.db.lock(ctx)()
+	session, ok := r.sessions.get(id)
+	if !ok || session.RevokedAt != nil {
+		return ErrNotFound
+	}
+	session.RevokedAt = &now
+	session.UpdatedAt = now
+	r.sessions.put(id, session)
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeSemuaUser(ctx context.Context, userID, kecuali primitive.ObjectID, now time.Time) (int64, error) {
+	defer r.db.lock(ctx)()
+	var n int64
+	for _, s := range r.sessions.all(func(s models.Session) bool { return s.UserID == userID && s.ID != kecuali && s.Aktif(now) }) {
+		s.RevokedAt = &now
+		s.UpdatedAt = now
+		r.sessions.put(s.ID, s)
+		n++
+	}
+	return n, nil
+}
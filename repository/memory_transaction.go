@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryTransactionRepository struct {
+	db           *memoryDB
+	transactions *table[models.Transaction]
+	alat         *table[models.Alat]
+	users        *table[models.User]
+}
+
+func matchTransaction(filter TransactionFilter) func(models.Transaction) bool {
+	return func(t models.Transaction) bool {
+		if filter.UserID != nil && t.UserID != *filter.UserID {
+			return false
+		}
+		if !cocokAlat(t.AlatID, filter.AlatID, filter.AlatIDs) {
+			return false
+		}
+		if filter.GrupID != nil && (t.GrupID == nil || *t.GrupID != *filter.GrupID) {
+			return false
+		}
+		if len(filter.Status) > 0 && !slices.Contains(filter.Status, t.Status) {
+			return false
+		}
+		if !filter.Dari.IsZero() && t.CreatedAt.Before(filter.Dari) {
+			return false
+		}
+		if !filter.Sampai.IsZero() && t.CreatedAt.After(filter.Sampai) {
+			return false
+		}
+		if !filter.JatuhTempoSebelum.IsZero() && !t.JatuhTempo.Before(filter.JatuhTempoSebelum) {
+			return false
+		}
+		if filter.PerpanjanganMenunggu && t.PerpanjanganMenunggu() == nil {
+			return false
+		}
+		if filter.Foto != "" && !slices.ContainsFunc(t.Pemeriksaan, func(p models.PemeriksaanUnit) bool {
+			return p.Foto == filter.Foto
+		}) {
+			return false
+		}
+		return true
+	}
+}
+
+func (r *memoryTransactionRepository) Create(ctx context.Context, trans *models.Transaction) error {
+	defer r.db.lock(ctx)()
+	r.transactions.put(trans.ID, *trans)
+	return nil
+}
+
+func (r *memoryTransactionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error) {
+	defer r.db.lock(ctx)()
+	trans, ok := r.transactions.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &trans, nil
+}
+
+// urutanTransaksi adalah field transaksi yang bisa dipakai untuk mengurutkan list
+var urutanTransaksi = map[string]pembanding[models.Transaction]{
+	"created_at":     func(a, b models.Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"jatuh_tempo":    func(a, b models.Transaction) int { return a.JatuhTempo.Compare(b.JatuhTempo) },
+	"tanggal_pinjam": func(a, b models.Transaction) int { return a.TanggalPinjam.Compare(b.TanggalPinjam) },
+	"status":         func(a, b models.Transaction) int { return strings.Compare(a.Status, b.Status) },
+	"jumlah":         func(a, b models.Transaction) int { return cmp.Compare(a.Jumlah, b.Jumlah) },
+}
+
+// urutanRiwayat sama seperti urutanTransaksi untuk data riwayat
+var urutanRiwayat = map[string]pembanding[models.RiwayatPeminjaman]{
+	"created_at":     func(a, b models.RiwayatPeminjaman) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"jatuh_tempo":    func(a, b models.RiwayatPeminjaman) int { return a.JatuhTempo.Compare(b.JatuhTempo) },
+	"tanggal_pinjam": func(a, b models.RiwayatPeminjaman) int { return a.TanggalPinjam.Compare(b.TanggalPinjam) },
+	"status":         func(a, b models.RiwayatPeminjaman) int { return strings.Compare(a.Status, b.Status) },
+	"jumlah":         func(a, b models.RiwayatPeminjaman) int { return cmp.Compare(a.Jumlah, b.Jumlah) },
+}
+
+func (r *memoryTransactionRepository) List(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.Transaction, int64, error) {
+	defer r.db.lock(ctx)()
+	list, total := halaman(r.transactions.all(matchTransaction(filter)), opts, urutanTransaksi)
+	return list, total, nil
+}
+
+func (r *memoryTransactionRepository) Update(ctx context.Context, trans *models.Transaction) error {
+	defer r.db.lock(ctx)()
+	if _, ok := r.transactions.get(trans.ID); !ok {
+		return ErrNotFound
+	}
+	stored := *trans
+	stored.RiwayatStatus = append([]models.PerubahanStatus(nil), trans.RiwayatStatus...)
+	stored.Perpanjangan = append([]models.Perpanjangan(nil), trans.Perpanjangan...)
+	r.transactions.put(trans.ID, stored)
+	return nil
+}
+
+func (r *memoryTransactionRepository) Riwayat(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.RiwayatPeminjaman, int64, error) {
+	defer r.db.lock(ctx)()
+
+	var riwayat []models.RiwayatPeminjaman
+	for _, t := range r.transactions.all(matchTransaction(filter)) {
+		item := models.RiwayatPeminjaman{
+			ID:                 t.ID,
+			GrupID:             t.GrupID,
+			AlatID:             t.AlatID,
+			Jumlah:             t.Jumlah,
+			JumlahDikembalikan: t.JumlahDikembalikan,
+			TanggalPinjam:      t.TanggalPinjam,
+			JatuhTempo:         t.JatuhTempo,
+			TanggalKembali:     t.TanggalKembali,
+			Status:             t.Status,
+			AlasanPenolakan:    t.AlasanPenolakan,
+			CreatedAt:          t.CreatedAt,
+		}
+		item.NamaAlat = t.NamaAlat
+		if alat, ok := r.alat.get(t.AlatID); ok {
+			item.NamaAlat = alat.Nama
+		}
+		riwayat = append(riwayat, item)
+	}
+
+	sort.SliceStable(riwayat, func(i, j int) bool {
+		return riwayat[i].CreatedAt.After(riwayat[j].CreatedAt)
+	})
+	riwayat, total := halaman(riwayat, opts, urutanRiwayat)
+	return riwayat, total, nil
+}
+
+func (r *memoryTransactionRepository) ListTerlambat(ctx context.Context, now time.Time) ([]models.PeminjamanTerlambat, error) {
+	defer r.db.lock(ctx)()
+
+	var list []models.PeminjamanTerlambat
+	for _, t := range r.transactions.all(func(t models.Transaction) bool { return t.Terlambat(now) }) {
+		item := models.PeminjamanTerlambat{
+			ID:                 t.ID,
+			UserID:             t.UserID,
+			AlatID:             t.AlatID,
+			Jumlah:             t.Jumlah,
+			JumlahDikembalikan: t.JumlahDikembalikan,
+			TanggalPinjam:      t.TanggalPinjam,
+			JatuhTempo:         t.JatuhTempo,
+			HariTerlambat:      models.HariTerlambat(t.JatuhTempo, now),
+		}
+		if user, ok := r.users.get(t.UserID); ok {
+			item.NamaPeminjam = user.Nama
+			item.EmailPeminjam = user.Email
+			item.NIM = user.NIM
+		}
+		item.NamaAlat = t.NamaAlat
+		if alat, ok := r.alat.get(t.AlatID); ok {
+			item.NamaAlat = alat.Nama
+			item.Kategori = alat.Kategori
+		}
+		list = append(list, item)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].JatuhTempo.Before(list[j].JatuhTempo)
+	})
+	return list, nil
+}
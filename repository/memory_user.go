@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUserRepository struct {
+	db    *memoryDB
+	users *table[models.User]
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	defer r.db.lock(ctx)()
+	if user.NIM != "" && len(r.users.all(func(u models.User) bool { return u.NIM == user.NIM })) > 0 {
+		return ErrDuplikat
+	}
+	r.users.put(user.ID, *user)
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	defer r.db.lock(ctx)()
+	user, ok := r.users.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer r.db.lock(ctx)()
+	found := r.users.all(func(u models.User) bool { return u.Email == email })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &found[0], nil
+}
+
+func (r *memoryUserRepository) FindByNIM(ctx context.Context, nim string) (*models.User, error) {
+	defer r.db.lock(ctx)()
+	found := r.users.all(func(u models.User) bool { return u.NIM == nim })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return &found[0], nil
+}
+
+// urutanUser adalah field user yang bisa dipakai untuk mengurutkan list
+var urutanUser = map[string]pembanding[models.User]{
+	"nama":       func(a, b models.User) int { return strings.Compare(a.Nama, b.Nama) },
+	"email":      func(a, b models.User) int { return strings.Compare(a.Email, b.Email) },
+	"role":       func(a, b models.User) int { return strings.Compare(a.Role, b.Role) },
+	"nim":        func(a, b models.User) int { return strings.Compare(a.NIM, b.NIM) },
+	"created_at": func(a, b models.User) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, int64, error) {
+	defer r.db.lock(ctx)()
+	list := r.users.all(func(u models.User) bool {
+		if filter.Role != "" && u.Role != filter.Role {
+			return false
+		}
+		if filter.Jurusan != "" && u.Jurusan != filter.Jurusan {
+			return false
+		}
+		if filter.NIM != "" && !strings.HasPrefix(u.NIM, filter.NIM) {
+			return false
+		}
+		if filter.AvatarURL != "" && u.AvatarURL != filter.AvatarURL {
+			return false
+		}
+		return true
+	})
+	list, total := halaman(list, opts, urutanUser)
+	return list, total, nil
+}
+
+func (r *memoryUserRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	defer r.db.lock(ctx)()
+	user, ok := r.users.get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	user.Role = role
+	r.users.put(id, user)
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	defer r.db.lock(ctx)()
+	user, ok := r.users.get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	r.users.put(id, user)
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateProfil(ctx context.Context, user *models.User) error {
+	defer r.db.lock(ctx)()
+	lama, ok := r.users.get(user.ID)
+	if !ok {
+		return ErrNotFound
+	}
+	lama.Nama = user.Nama
+	lama.Phone = user.Phone
+	lama.Jurusan = user.Jurusan
+	lama.AvatarURL = user.AvatarURL
+	lama.UpdatedAt = user.UpdatedAt
+	r.users.put(user.ID, lama)
+	return nil
+}
+
+func (r *memoryUserRepository) SetVerifikasi(ctx context.Context, id primitive.ObjectID, hash string, expiresAt time.Time) error {
+	defer r.db.lock(ctx)()
+	user, ok := r.users.get(id)
+	if !ok || user.EmailTerverifikasi {
+		return ErrNotFound
+	}
+	user.VerifikasiHash = hash
+	user.VerifikasiExpiresAt = expiresAt
+	r.users.put(id, user)
+	return nil
+}
+
+func (r *memoryUserRepository) Verifikasi(ctx context.Context, hash string, now time.Time) (*models.User, error) {
+	defer r.db.lock(ctx)()
+	list := r.users.all(func(u models.User) bool {
+		return u.VerifikasiHash != "" && u.VerifikasiHash == hash && now.Before(u.VerifikasiExpiresAt)
+	})
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	user := list[0]
+	user.EmailTerverifikasi = true
+	user.VerifikasiHash = ""
+	user.VerifikasiExpiresAt = time.Time{}
+	r.users.put(user.ID, user)
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GantiJurusan(ctx context.Context, lama, baru string) error {
+	defer r.db.lock(ctx)()
+	for _, u := range r.users.all(func(u models.User) bool { return u.Jurusan == lama }) {
+		u.Jurusan = baru
+		r.users.put(u.ID, u)
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) Kunci(ctx context.Context, id primitive.ObjectID) error {
+	// Semua operasi in-memory di dalam WithTransaction sudah berjalan eksklusif
+	defer r.db.lock(ctx)()
+	if _, ok := r.users.get(id); !ok {
+		return ErrNotFound
+	}
+	return nil
+}
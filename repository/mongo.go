@@ -0,0 +1,402 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore membuat Store yang menyimpan data di database MongoDB
+func NewMongoStore(client *mongo.Client, db *mongo.Database) *Store {
+	return &Store{
+		Users:        &mongoUserRepository{col: db.Collection("users")},
+		Alat:         &mongoAlatRepository{col: db.Collection("alat")},
+		Unit:         &mongoAlatUnitRepository{col: db.Collection("alat_units"), alat: db.Collection("alat")},
+		Transactions: &mongoTransactionRepository{col: db.Collection("transactions")},
+		Kategori:     &mongoKategoriRepository{col: db.Collection("kategori")},
+		Jurusan:      &mongoJurusanRepository{col: db.Collection("jurusan")},
+		Denda:        &mongoDendaRepository{col: db.Collection("denda")},
+		Kasus:        &mongoKasusRepository{col: db.Collection("kasus_kerusakan")},
+		Reservasi:    &mongoReservasiRepository{col: db.Collection("reservasi")},
+		Sessions:     &mongoSessionRepository{col: db.Collection("sessions")},
+		Resets:       &mongoPasswordResetRepository{col: db.Collection("password_resets")},
+		Audit:        &mongoAuditRepository{col: db.Collection("audit_log")},
+		MutasiStok:   &mongoMutasiStokRepository{col: db.Collection("mutasi_stok")},
+		Roles:        &mongoRoleRepository{col: db.Collection("roles")},
+		Lab:          &mongoLabRepository{col: db.Collection("lab")},
+		Aturan:       &mongoAturanRepository{col: db.Collection("aturan_peminjaman")},
+		Tx:           &mongoTransactor{client: client},
+	}
+}
+
+// MigrateMongo menyesuaikan data lama dengan skema terbaru.
+// Aman dijalankan berulang kali setiap server start.
+func MigrateMongo(ctx context.Context, db *mongo.Database) error {
+	transactions := db.Collection("transactions")
+
+	// Status sebelum ada alur persetujuan
+	statusLama := map[string]string{
+		models.StatusLamaPinjam:  models.StatusDiambil,
+		models.StatusLamaKembali: models.StatusDikembalikan,
+	}
+	for lama, baru := range statusLama {
+		_, err := transactions.UpdateMany(ctx,
+			bson.M{"status": lama},
+			bson.M{"$set": bson.M{"status": baru}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Transaksi yang selesai sebelum ada pengembalian sebagian
+	_, err := transactions.UpdateMany(ctx,
+		bson.M{"status": models.StatusDikembalikan, "jumlah_dikembalikan": bson.M{"$exists": false}},
+		mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{"jumlah_dikembalikan": "$jumlah"}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Akun yang dibuat sebelum ada verifikasi email dianggap sudah terverifikasi
+	users := db.Collection("users")
+	_, err = users.UpdateMany(ctx,
+		bson.M{"email_terverifikasi": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_terverifikasi": true}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "verifikasi_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// NIM unik, user tanpa NIM (misalnya admin) tidak ikut diindeks
+		{Keys: bson.D{{Key: "nim", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("jurusan").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "nama", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := indeksTeksAlat(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksSession(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksPasswordReset(ctx, db); err != nil {
+		return err
+	}
+	if err := siapkanRole(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksLab(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksAudit(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksMutasiStok(ctx, db); err != nil {
+		return err
+	}
+	if err := indeksGrupPeminjaman(ctx, db); err != nil {
+		return err
+	}
+	return migrasiUnit(ctx, db)
+}
+
+// indeksTeksAlat membuat text index untuk pencarian katalog alat. Bobotnya
+// sama dengan bobotTeksAlat di store in-memory. Bahasa "none" mematikan
+// stemming dan stop word bahasa Inggris yang tidak cocok untuk nama alat.
+func indeksTeksAlat(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("alat").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "nama", Value: "text"},
+			{Key: "kategori", Value: "text"},
+			{Key: "deskripsi", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("alat_teks").
+			SetWeights(bson.M{"nama": 10, "kategori": 5, "deskripsi": 1}).
+			SetDefaultLanguage("none"),
+	})
+	return err
+}
+
+// indeksSession membuat index untuk cek jti di setiap request dan
+// menghapus otomatis session yang refresh token-nya sudah kadaluarsa
+func indeksSession(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "access_jti", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// indeksPasswordReset membuat index pencarian token dan menghapus otomatis
+// token reset password yang sudah kadaluarsa
+func indeksPasswordReset(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("password_resets").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// siapkanRole membuat role bawaan yang belum ada. Role yang sudah ada tidak
+// diubah supaya permission yang diatur super admin tetap berlaku.
+func siapkanRole(ctx context.Context, db *mongo.Database) error {
+	roles := db.Collection("roles")
+	_, err := roles.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "nama", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	for _, role := range models.RoleBawaan(time.Now()) {
+		_, err := roles.UpdateOne(ctx,
+			bson.M{"nama": role.Nama},
+			bson.M{"$setOnInsert": role},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+
+		// Role bawaan yang dibuat sebelum ada lab belum punya lingkup_lab
+		// dan permission lab:manage
+		update := bson.M{"$set": bson.M{"lingkup_lab": role.LingkupLab}}
+		if role.Punya(models.PermLabManage) && !role.Punya(models.PermSemua) {
+			update["$addToSet"] = bson.M{"permissions": models.PermLabManage}
+		}
+		_, err = roles.UpdateOne(ctx, bson.M{"nama": role.Nama, "lingkup_lab": bson.M{"$exists": false}}, update)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Permission baru ditambahkan sekali ke role bawaan yang punya, supaya
+	// tidak muncul lagi jika kemudian dicabut super admin
+	permissionBaru := []struct{ migrasi, perm string }{
+		{"role_audit_read", models.PermAuditRead},
+		{"role_aturan_manage", models.PermAturanManage},
+	}
+	for _, p := range permissionBaru {
+		err := sekali(ctx, db, p.migrasi, func() error {
+			for _, role := range models.RoleBawaan(time.Now()) {
+				if !role.Punya(p.perm) || role.Punya(models.PermSemua) {
+					continue
+				}
+				_, err := roles.UpdateOne(ctx,
+					bson.M{"nama": role.Nama},
+					bson.M{"$addToSet": bson.M{"permissions": p.perm}},
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sekali menjalankan migrasi fn jika belum pernah berhasil dijalankan,
+// ditandai dengan dokumen bernama nama di koleksi migrasi
+func sekali(ctx context.Context, db *mongo.Database, nama string, fn func() error) error {
+	migrasi := db.Collection("migrasi")
+	err := migrasi.FindOne(ctx, bson.M{"_id": nama}).Err()
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	_, err = migrasi.InsertOne(ctx, bson.M{"_id": nama, "waktu": time.Now()})
+	return err
+}
+
+// indeksAudit membuat index pencarian audit log per aktor, per data dan
+// per waktu
+func indeksAudit(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("audit_log").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "aktor_id", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "target", Value: 1}, {Key: "target_id", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "waktu", Value: -1}}},
+	})
+	return err
+}
+
+// indeksMutasiStok membuat index buku besar stok per alat, per transaksi
+// dan per waktu
+func indeksMutasiStok(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("mutasi_stok").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "alat_id", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "transaction_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "waktu", Value: -1}}},
+	})
+	return err
+}
+
+// indeksGrupPeminjaman membuat index untuk mengambil semua item satu
+// peminjaman multi-item
+func indeksGrupPeminjaman(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("transactions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "grup_id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	return err
+}
+
+// indeksLab membuat index kode lab yang unik, pencarian lab per staff dan
+// pencarian alat per lab
+func indeksLab(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("lab").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "kode", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "staff_ids", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("alat").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "lab_id", Value: 1}}},
+		// Untuk mencari alat yang sudah melewati masa retensi penghapusan
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	return err
+}
+
+// migrasiUnit membuat unit fisik untuk alat yang dibuat sebelum ada pelacakan
+// unit, lalu memasangkan unit ke transaksi yang sedang menahan stok
+func migrasiUnit(ctx context.Context, db *mongo.Database) error {
+	units := db.Collection("alat_units")
+	_, err := units.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "kode_aset", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "alat_id", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := db.Collection("alat").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var alatList []models.Alat
+	if err := cursor.All(ctx, &alatList); err != nil {
+		return err
+	}
+
+	repo := &mongoAlatUnitRepository{col: units, alat: db.Collection("alat")}
+	for _, alat := range alatList {
+		n, err := units.CountDocuments(ctx, bson.M{"alat_id": alat.ID})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		now := time.Now()
+		for i := 1; i <= alat.StokTotal; i++ {
+			unit := models.AlatUnit{
+				ID:        primitive.NewObjectID(),
+				AlatID:    alat.ID,
+				KodeAset:  models.KodeAsetOtomatis(alat.ID, i),
+				Kondisi:   models.KondisiBaik,
+				Status:    models.UnitTersedia,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := repo.Create(ctx, &unit); err != nil {
+				return err
+			}
+		}
+
+		cursor, err := db.Collection("transactions").Find(ctx, bson.M{
+			"alat_id":  alat.ID,
+			"status":   bson.M{"$in": []string{models.StatusDisetujui, models.StatusDiambil}},
+			"unit_ids": bson.M{"$exists": false},
+		})
+		if err != nil {
+			return err
+		}
+		var aktif []models.Transaction
+		if err := cursor.All(ctx, &aktif); err != nil {
+			return err
+		}
+		for _, trans := range aktif {
+			dipinjam, err := repo.Pinjamkan(ctx, alat.ID, trans.ID, trans.Jumlah)
+			if errors.Is(err, ErrStokTidakCukup) {
+				// Data stok lama tidak konsisten, biarkan admin memeriksa manual
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ids := make([]primitive.ObjectID, len(dipinjam))
+			for i, u := range dipinjam {
+				ids[i] = u.ID
+			}
+			_, err = db.Collection("transactions").UpdateByID(ctx, trans.ID,
+				bson.M{"$set": bson.M{"unit_ids": ids}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mongoTransactor menjalankan fn di dalam session transaction MongoDB
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+func (t *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Sudah di dalam transaksi: cukup jalankan fn di session yang sama
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// Driver otomatis mengulang fn jika terjadi TransientTransactionError
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// notFound menerjemahkan mongo.ErrNoDocuments menjadi ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoAlatRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoAlatRepository) Create(ctx context.Context, alat *models.Alat) error {
+	_, err := r.col.InsertOne(ctx, alat)
+	return err
+}
+
+func (r *mongoAlatRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Alat, error) {
+	var alat models.Alat
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&alat); err != nil {
+		return nil, notFound(err)
+	}
+	return &alat, nil
+}
+
+func alatQuery(filter AlatFilter) bson.M {
+	query := bson.M{}
+	if filter.Kategori != "" {
+		query["kategori"] = filter.Kategori
+	}
+	if filter.Q != "" {
+		query["$text"] = bson.M{"$search": filter.Q}
+	}
+	if filter.HanyaTersedia {
+		query["stok_tersedia"] = bson.M{"$gt": 0}
+	}
+	if filter.LabIDs != nil {
+		query["lab_id"] = bson.M{"$in": filter.LabIDs}
+	}
+	switch {
+	case filter.HanyaDihapus:
+		query["deleted_at"] = bson.M{"$ne": nil}
+	case !filter.TermasukDihapus:
+		query["deleted_at"] = nil
+	}
+	return query
+}
+
+func (r *mongoAlatRepository) List(ctx context.Context, filter AlatFilter, opts ListOptions) ([]models.Alat, int64, error) {
+	defaultSort := bson.D{{Key: "_id", Value: 1}}
+	if filter.Q != "" {
+		defaultSort = bson.D{{Key: "skor", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}
+	}
+	return findPage[models.Alat](ctx, r.col, alatQuery(filter), opts, defaultSort)
+}
+
+func (r *mongoAlatRepository) ListNama(ctx context.Context, filter AlatFilter) ([]string, error) {
+	filter.Q = ""
+	hasil, err := r.col.Distinct(ctx, "nama", alatQuery(filter))
+	if err != nil {
+		return nil, err
+	}
+	nama := make([]string, 0, len(hasil))
+	for _, v := range hasil {
+		if s, ok := v.(string); ok {
+			nama = append(nama, s)
+		}
+	}
+	return nama, nil
+}
+
+func (r *mongoAlatRepository) Update(ctx context.Context, alat *models.Alat) error {
+	res, err := r.col.UpdateByID(ctx, alat.ID, bson.M{"$set": bson.M{
+		"nama":            alat.Nama,
+		"kategori":        alat.Kategori,
+		"deskripsi":       alat.Deskripsi,
+		"nilai_barang":    alat.NilaiBarang,
+		"kebijakan_denda": alat.KebijakanDenda,
+		"lab_id":          alat.LabID,
+		"updated_at":      alat.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAlatRepository) Hapus(ctx context.Context, id primitive.ObjectID, waktu time.Time) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": waktu, "updated_at": waktu}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAlatRepository) Pulihkan(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}},
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAlatRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAlatRepository) Kunci(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$inc": bson.M{"versi_kunci": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
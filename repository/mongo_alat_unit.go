@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAlatUnitRepository struct {
+	col  *mongo.Collection
+	alat *mongo.Collection
+}
+
+// hitungStok menyimpan jumlah unit beredar dan tersedia ke dokumen alat
+func (r *mongoAlatUnitRepository) hitungStok(ctx context.Context, alatID primitive.ObjectID) error {
+	total, err := r.col.CountDocuments(ctx, bson.M{
+		"alat_id": alatID,
+		"status":  bson.M{"$in": []string{models.UnitTersedia, models.UnitDipinjam}},
+	})
+	if err != nil {
+		return err
+	}
+	tersedia, err := r.col.CountDocuments(ctx, bson.M{"alat_id": alatID, "status": models.UnitTersedia})
+	if err != nil {
+		return err
+	}
+	_, err = r.alat.UpdateByID(ctx, alatID, bson.M{"$set": bson.M{
+		"stok_total":    int(total),
+		"stok_tersedia": int(tersedia),
+		"updated_at":    time.Now(),
+	}})
+	return err
+}
+
+func (r *mongoAlatUnitRepository) Create(ctx context.Context, unit *models.AlatUnit) error {
+	if _, err := r.col.InsertOne(ctx, unit); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplikat
+		}
+		return err
+	}
+	return r.hitungStok(ctx, unit.AlatID)
+}
+
+func (r *mongoAlatUnitRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.AlatUnit, error) {
+	var unit models.AlatUnit
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&unit); err != nil {
+		return nil, notFound(err)
+	}
+	return &unit, nil
+}
+
+func (r *mongoAlatUnitRepository) ListByAlat(ctx context.Context, alatID primitive.ObjectID) ([]models.AlatUnit, error) {
+	opts := options.Find().SetSort(bson.M{"kode_aset": 1})
+	cursor, err := r.col.Find(ctx, bson.M{"alat_id": alatID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.AlatUnit
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoAlatUnitRepository) Update(ctx context.Context, unit *models.AlatUnit) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": unit.ID}, unit)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplikat
+		}
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return r.hitungStok(ctx, unit.AlatID)
+}
+
+func (r *mongoAlatUnitRepository) DeleteByAlat(ctx context.Context, alatID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"alat_id": alatID})
+	return err
+}
+
+func (r *mongoAlatUnitRepository) Pinjamkan(ctx context.Context, alatID, transID primitive.ObjectID, jumlah int) ([]models.AlatUnit, error) {
+	// Setiap unit diklaim dengan update bersyarat status TERSEDIA, sehingga
+	// satu unit tidak bisa diberikan ke dua transaksi
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"kode_aset": 1}).
+		SetReturnDocument(options.After)
+
+	units := make([]models.AlatUnit, 0, jumlah)
+	for i := 0; i < jumlah; i++ {
+		var unit models.AlatUnit
+		err := r.col.FindOneAndUpdate(ctx,
+			bson.M{"alat_id": alatID, "status": models.UnitTersedia},
+			bson.M{"$set": bson.M{
+				"status":         models.UnitDipinjam,
+				"transaction_id": transID,
+				"updated_at":     time.Now(),
+			}},
+			opts,
+		).Decode(&unit)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			n, err := r.alat.CountDocuments(ctx, bson.M{"_id": alatID})
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return nil, ErrNotFound
+			}
+			return nil, ErrStokTidakCukup
+		}
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+	return units, r.hitungStok(ctx, alatID)
+}
+
+func (r *mongoAlatUnitRepository) Kembalikan(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	alatIDs, err := r.col.Distinct(ctx, "alat_id", bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": models.UnitDipinjam},
+		bson.M{
+			"$set":   bson.M{"status": models.UnitTersedia, "updated_at": time.Now()},
+			"$unset": bson.M{"transaction_id": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	for _, id := range alatIDs {
+		if alatID, ok := id.(primitive.ObjectID); ok {
+			if err := r.hitungStok(ctx, alatID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *mongoAlatUnitRepository) HitungUlang(ctx context.Context, alatID primitive.ObjectID) error {
+	n, err := r.alat.CountDocuments(ctx, bson.M{"_id": alatID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return r.hitungStok(ctx, alatID)
+}
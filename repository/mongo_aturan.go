@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAturanRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoAturanRepository) Create(ctx context.Context, aturan *models.AturanPeminjaman) error {
+	_, err := r.col.InsertOne(ctx, aturan)
+	return err
+}
+
+func (r *mongoAturanRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.AturanPeminjaman, error) {
+	var aturan models.AturanPeminjaman
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&aturan); err != nil {
+		return nil, notFound(err)
+	}
+	return &aturan, nil
+}
+
+func (r *mongoAturanRepository) List(ctx context.Context) ([]models.AturanPeminjaman, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.AturanPeminjaman
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoAturanRepository) Update(ctx context.Context, aturan *models.AturanPeminjaman) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": aturan.ID}, aturan)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAturanRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoAuditRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoAuditRepository) Catat(ctx context.Context, log *models.AuditLog) error {
+	_, err := r.col.InsertOne(ctx, log)
+	return err
+}
+
+// auditQuery menyusun query Mongo dari AuditFilter
+func auditQuery(filter AuditFilter) bson.M {
+	query := bson.M{}
+	if filter.AktorID != nil {
+		query["aktor_id"] = *filter.AktorID
+	}
+	if filter.Aksi != "" {
+		query["aksi"] = filter.Aksi
+	}
+	if filter.Target != "" {
+		query["target"] = filter.Target
+	}
+	if filter.TargetID != nil {
+		query["target_id"] = *filter.TargetID
+	}
+	if !filter.Dari.IsZero() || !filter.Sampai.IsZero() {
+		rentang := bson.M{}
+		if !filter.Dari.IsZero() {
+			rentang["$gte"] = filter.Dari
+		}
+		if !filter.Sampai.IsZero() {
+			rentang["$lte"] = filter.Sampai
+		}
+		query["waktu"] = rentang
+	}
+	return query
+}
+
+func (r *mongoAuditRepository) List(ctx context.Context, filter AuditFilter, opts ListOptions) ([]models.AuditLog, int64, error) {
+	return findPage[models.AuditLog](ctx, r.col, auditQuery(filter), opts, bson.D{{Key: "waktu", Value: -1}, {Key: "_id", Value: -1}})
+}
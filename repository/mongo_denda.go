@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoDendaRepository struct {
+	col *mongo.Collection
+}
+
+func dendaQuery(filter DendaFilter) bson.M {
+	query := bson.M{}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	switch filter.Jenis {
+	case "":
+	case models.DendaJenisTerlambat:
+		// Denda lama tanpa field jenis adalah denda keterlambatan
+		query["jenis"] = bson.M{"$in": []interface{}{models.DendaJenisTerlambat, nil}}
+	default:
+		query["jenis"] = filter.Jenis
+	}
+	return query
+}
+
+func (r *mongoDendaRepository) Create(ctx context.Context, denda *models.Denda) error {
+	_, err := r.col.InsertOne(ctx, denda)
+	return err
+}
+
+func (r *mongoDendaRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Denda, error) {
+	var denda models.Denda
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&denda); err != nil {
+		return nil, notFound(err)
+	}
+	return &denda, nil
+}
+
+func (r *mongoDendaRepository) List(ctx context.Context, filter DendaFilter, opts ListOptions) ([]models.Denda, int64, error) {
+	return findPage[models.Denda](ctx, r.col, dendaQuery(filter), opts, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+}
+
+func (r *mongoDendaRepository) Update(ctx context.Context, denda *models.Denda) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": denda.ID}, denda)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoJurusanRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoJurusanRepository) Create(ctx context.Context, jurusan *models.Jurusan) error {
+	_, err := r.col.InsertOne(ctx, jurusan)
+	return err
+}
+
+func (r *mongoJurusanRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Jurusan, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoJurusanRepository) FindByNama(ctx context.Context, nama string) (*models.Jurusan, error) {
+	return r.findOne(ctx, bson.M{"nama": nama})
+}
+
+func (r *mongoJurusanRepository) findOne(ctx context.Context, filter bson.M) (*models.Jurusan, error) {
+	var jurusan models.Jurusan
+	if err := r.col.FindOne(ctx, filter).Decode(&jurusan); err != nil {
+		return nil, notFound(err)
+	}
+	return &jurusan, nil
+}
+
+func (r *mongoJurusanRepository) List(ctx context.Context) ([]models.Jurusan, error) {
+	cursor, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "nama", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Jurusan
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoJurusanRepository) Update(ctx context.Context, jurusan *models.Jurusan) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": jurusan.ID}, jurusan)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoJurusanRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
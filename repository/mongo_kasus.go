@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoKasusRepository struct {
+	col *mongo.Collection
+}
+
+func kasusQuery(filter KasusFilter) bson.M {
+	query := bson.M{}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	saringAlat(query, filter.AlatID, filter.AlatIDs)
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	return query
+}
+
+func (r *mongoKasusRepository) Create(ctx context.Context, kasus *models.KasusKerusakan) error {
+	_, err := r.col.InsertOne(ctx, kasus)
+	return err
+}
+
+func (r *mongoKasusRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.KasusKerusakan, error) {
+	var kasus models.KasusKerusakan
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&kasus); err != nil {
+		return nil, notFound(err)
+	}
+	return &kasus, nil
+}
+
+func (r *mongoKasusRepository) List(ctx context.Context, filter KasusFilter, opts ListOptions) ([]models.KasusKerusakan, int64, error) {
+	return findPage[models.KasusKerusakan](ctx, r.col, kasusQuery(filter), opts, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+}
+
+func (r *mongoKasusRepository) Update(ctx context.Context, kasus *models.KasusKerusakan) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": kasus.ID}, kasus)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
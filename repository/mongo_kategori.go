@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoKategoriRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoKategoriRepository) Create(ctx context.Context, kategori *models.Kategori) error {
+	_, err := r.col.InsertOne(ctx, kategori)
+	return err
+}
+
+func (r *mongoKategoriRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Kategori, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoKategoriRepository) FindByNama(ctx context.Context, nama string) (*models.Kategori, error) {
+	return r.findOne(ctx, bson.M{"nama": nama})
+}
+
+func (r *mongoKategoriRepository) findOne(ctx context.Context, filter bson.M) (*models.Kategori, error) {
+	var kategori models.Kategori
+	if err := r.col.FindOne(ctx, filter).Decode(&kategori); err != nil {
+		return nil, notFound(err)
+	}
+	return &kategori, nil
+}
+
+func (r *mongoKategoriRepository) List(ctx context.Context) ([]models.Kategori, error) {
+	cursor, err := r.col.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Kategori
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoKategoriRepository) Update(ctx context.Context, kategori *models.Kategori) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": kategori.ID}, kategori)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoKategoriRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoLabRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoLabRepository) Create(ctx context.Context, lab *models.Lab) error {
+	_, err := r.col.InsertOne(ctx, lab)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplikat
+	}
+	return err
+}
+
+func (r *mongoLabRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Lab, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoLabRepository) findOne(ctx context.Context, filter bson.M) (*models.Lab, error) {
+	var lab models.Lab
+	if err := r.col.FindOne(ctx, filter).Decode(&lab); err != nil {
+		return nil, notFound(err)
+	}
+	return &lab, nil
+}
+
+func (r *mongoLabRepository) List(ctx context.Context, filter LabFilter) ([]models.Lab, error) {
+	query := bson.M{}
+	if filter.StaffID != nil {
+		query["staff_ids"] = *filter.StaffID
+	}
+	cursor, err := r.col.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "nama", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Lab
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoLabRepository) Update(ctx context.Context, lab *models.Lab) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": lab.ID}, lab)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplikat
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoLabRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoMutasiStokRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoMutasiStokRepository) Catat(ctx context.Context, m *models.MutasiStok) error {
+	_, err := r.col.InsertOne(ctx, m)
+	return err
+}
+
+// mutasiStokQuery menyusun query Mongo dari MutasiStokFilter
+func mutasiStokQuery(filter MutasiStokFilter) bson.M {
+	query := bson.M{}
+	saringAlat(query, filter.AlatID, filter.AlatIDs)
+	if filter.Jenis != "" {
+		query["jenis"] = filter.Jenis
+	}
+	if filter.TransactionID != nil {
+		query["transaction_id"] = *filter.TransactionID
+	}
+	if !filter.Dari.IsZero() || !filter.Sampai.IsZero() {
+		rentang := bson.M{}
+		if !filter.Dari.IsZero() {
+			rentang["$gte"] = filter.Dari
+		}
+		if !filter.Sampai.IsZero() {
+			rentang["$lte"] = filter.Sampai
+		}
+		query["waktu"] = rentang
+	}
+	return query
+}
+
+func (r *mongoMutasiStokRepository) List(ctx context.Context, filter MutasiStokFilter, opts ListOptions) ([]models.MutasiStok, int64, error) {
+	return findPage[models.MutasiStok](ctx, r.col, mutasiStokQuery(filter), opts, bson.D{{Key: "waktu", Value: -1}, {Key: "_id", Value: -1}})
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoPasswordResetRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	_, err := r.col.InsertOne(ctx, reset)
+	return err
+}
+
+func (r *mongoPasswordResetRepository) Pakai(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"token_hash": tokenHash, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reset)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &reset, nil
+}
+
+func (r *mongoPasswordResetRepository) HapusUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoReservasiRepository struct {
+	col *mongo.Collection
+}
+
+func reservasiQuery(filter ReservasiFilter) bson.M {
+	query := bson.M{}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	saringAlat(query, filter.AlatID, filter.AlatIDs)
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if !filter.Dari.IsZero() && !filter.Sampai.IsZero() {
+		query["mulai"] = bson.M{"$lt": filter.Sampai}
+		query["selesai"] = bson.M{"$gt": filter.Dari}
+	}
+	return query
+}
+
+func (r *mongoReservasiRepository) Create(ctx context.Context, reservasi *models.Reservasi) error {
+	_, err := r.col.InsertOne(ctx, reservasi)
+	return err
+}
+
+func (r *mongoReservasiRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Reservasi, error) {
+	var reservasi models.Reservasi
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&reservasi); err != nil {
+		return nil, notFound(err)
+	}
+	return &reservasi, nil
+}
+
+func (r *mongoReservasiRepository) List(ctx context.Context, filter ReservasiFilter, opts ListOptions) ([]models.Reservasi, int64, error) {
+	return findPage[models.Reservasi](ctx, r.col, reservasiQuery(filter), opts, bson.D{{Key: "mulai", Value: 1}, {Key: "_id", Value: 1}})
+}
+
+func (r *mongoReservasiRepository) Update(ctx context.Context, reservasi *models.Reservasi) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": reservasi.ID}, reservasi)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRoleRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoRoleRepository) Create(ctx context.Context, role *models.Role) error {
+	_, err := r.col.InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplikat
+	}
+	return err
+}
+
+func (r *mongoRoleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoRoleRepository) FindByNama(ctx context.Context, nama string) (*models.Role, error) {
+	return r.findOne(ctx, bson.M{"nama": nama})
+}
+
+func (r *mongoRoleRepository) findOne(ctx context.Context, filter bson.M) (*models.Role, error) {
+	var role models.Role
+	if err := r.col.FindOne(ctx, filter).Decode(&role); err != nil {
+		return nil, notFound(err)
+	}
+	return &role, nil
+}
+
+func (r *mongoRoleRepository) List(ctx context.Context) ([]models.Role, error) {
+	cursor, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "nama", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Role
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *mongoRoleRepository) Update(ctx context.Context, role *models.Role) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": role.ID}, role)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoRoleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
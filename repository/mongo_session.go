@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoSessionRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.col.InsertOne(ctx, session)
+	return err
+}
+
+func (r *mongoSessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepository) FindByAccessJTI(ctx context.Context, jti string) (*models.Session, error) {
+	var session models.Session
+	if err := r.col.FindOne(ctx, bson.M{"access_jti": jti}).Decode(&session); err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepository) Rotasi(ctx context.Context, id primitive.ObjectID, refreshHashLama, refreshHashBaru, accessJTI string, expiresAt, now time.Time) error {
+	// Filter refresh_hash lama membuat dua refresh paralel dengan token yang
+	// sama tidak bisa sama-sama berhasil
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "refresh_hash": refreshHashLama, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"refresh_hash": refreshHashBaru,
+			"access_jti":   accessJTI,
+			"expires_at":   expiresAt,
+			"updated_at":   now,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepository) Revoke(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepository) RevokeSemuaUser(ctx context.Context, userID, kecuali primitive.ObjectID, now time.Time) (int64, error) {
+	res, err := r.col.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$ne": kecuali}, "user_id": userID, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoTransactionRepository struct {
+	col *mongo.Collection
+}
+
+func transactionQuery(filter TransactionFilter) bson.M {
+	query := bson.M{}
+	if filter.UserID != nil {
+		query["user_id"] = *filter.UserID
+	}
+	saringAlat(query, filter.AlatID, filter.AlatIDs)
+	if filter.GrupID != nil {
+		query["grup_id"] = *filter.GrupID
+	}
+	if len(filter.Status) > 0 {
+		query["status"] = bson.M{"$in": filter.Status}
+	}
+	if !filter.Dari.IsZero() || !filter.Sampai.IsZero() {
+		rentang := bson.M{}
+		if !filter.Dari.IsZero() {
+			rentang["$gte"] = filter.Dari
+		}
+		if !filter.Sampai.IsZero() {
+			rentang["$lte"] = filter.Sampai
+		}
+		query["created_at"] = rentang
+	}
+	if !filter.JatuhTempoSebelum.IsZero() {
+		query["jatuh_tempo"] = bson.M{"$lt": filter.JatuhTempoSebelum}
+	}
+	if filter.PerpanjanganMenunggu {
+		query["perpanjangan.status"] = models.PerpanjanganMenunggu
+	}
+	if filter.Foto != "" {
+		query["pemeriksaan.foto"] = filter.Foto
+	}
+	return query
+}
+
+func (r *mongoTransactionRepository) Create(ctx context.Context, trans *models.Transaction) error {
+	_, err := r.col.InsertOne(ctx, trans)
+	return err
+}
+
+func (r *mongoTransactionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error) {
+	var trans models.Transaction
+	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&trans); err != nil {
+		return nil, notFound(err)
+	}
+	return &trans, nil
+}
+
+func (r *mongoTransactionRepository) List(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.Transaction, int64, error) {
+	return findPage[models.Transaction](ctx, r.col, transactionQuery(filter), opts, bson.D{{Key: "_id", Value: 1}})
+}
+
+func (r *mongoTransactionRepository) Update(ctx context.Context, trans *models.Transaction) error {
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": trans.ID}, trans)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoTransactionRepository) Riwayat(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.RiwayatPeminjaman, int64, error) {
+	query := transactionQuery(filter)
+
+	// Paginasi dilakukan sebelum $lookup supaya join hanya untuk satu halaman
+	pipeline := mongo.Pipeline{bson.D{{Key: "$match", Value: query}}}
+	pipeline = append(pipeline, opts.stages(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})...)
+	pipeline = append(pipeline,
+		bson.D{
+			{Key: "$lookup", Value: bson.M{
+				"from":         "alat",
+				"localField":   "alat_id",
+				"foreignField": "_id",
+				"as":           "alat",
+			}},
+		},
+		bson.D{
+			{Key: "$unwind", Value: bson.M{
+				"path":                       "$alat",
+				"preserveNullAndEmptyArrays": true,
+			}},
+		},
+		bson.D{
+			{Key: "$project", Value: bson.M{
+				"_id":                 1,
+				"alat_id":             "$alat_id",
+				"grup_id":             1,
+				"jumlah":              1,
+				"jumlah_dikembalikan": 1,
+				"tanggal_pinjam":      1,
+				"jatuh_tempo":         1,
+				"tanggal_kembali":     1,
+				"status":              1,
+				"alasan_penolakan":    1,
+				"created_at":          1,
+				"nama_alat":           bson.M{"$ifNull": bson.A{"$alat.nama", "$nama_alat"}},
+			}},
+		},
+	)
+	// $lookup tidak menjamin urutan, jadi urutkan ulang hasil halaman ini
+	pipeline = append(pipeline, opts.stages(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})[0])
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var riwayat []models.RiwayatPeminjaman
+	if err := cursor.All(ctx, &riwayat); err != nil {
+		return nil, 0, err
+	}
+	total, err := opts.total(ctx, r.col, query, len(riwayat))
+	if err != nil {
+		return nil, 0, err
+	}
+	return riwayat, total, nil
+}
+
+func (r *mongoTransactionRepository) ListTerlambat(ctx context.Context, now time.Time) ([]models.PeminjamanTerlambat, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"status":      bson.M{"$in": bson.A{models.StatusDiambil, models.StatusSebagianKembali}},
+			"jatuh_tempo": bson.M{"$lt": now},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.M{
+			"path":                       "$user",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "alat",
+			"localField":   "alat_id",
+			"foreignField": "_id",
+			"as":           "alat",
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.M{
+			"path":                       "$alat",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":                 1,
+			"user_id":             1,
+			"nama_peminjam":       "$user.nama",
+			"email_peminjam":      "$user.email",
+			"nim":                 "$user.nim",
+			"alat_id":             1,
+			"nama_alat":           bson.M{"$ifNull": bson.A{"$alat.nama", "$nama_alat"}},
+			"kategori":            "$alat.kategori",
+			"jumlah":              1,
+			"jumlah_dikembalikan": 1,
+			"tanggal_pinjam":      1,
+			"jatuh_tempo":         1,
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"jatuh_tempo": 1}}},
+	}
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.PeminjamanTerlambat
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].HariTerlambat = models.HariTerlambat(list[i].JatuhTempo, now)
+	}
+	return list, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserRepository struct {
+	col *mongo.Collection
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.col.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplikat
+	}
+	return err
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) FindByNIM(ctx context.Context, nim string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"nim": nim})
+}
+
+func (r *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	if err := r.col.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, int64, error) {
+	query := bson.M{}
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.Jurusan != "" {
+		query["jurusan"] = filter.Jurusan
+	}
+	if filter.NIM != "" {
+		query["nim"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.NIM)}
+	}
+	if filter.AvatarURL != "" {
+		query["avatar_url"] = filter.AvatarURL
+	}
+	return findPage[models.User](ctx, r.col, query, opts, bson.D{{Key: "_id", Value: 1}})
+}
+
+func (r *mongoUserRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"password_hash": passwordHash}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) UpdateProfil(ctx context.Context, user *models.User) error {
+	res, err := r.col.UpdateByID(ctx, user.ID, bson.M{"$set": bson.M{
+		"nama":       user.Nama,
+		"phone":      user.Phone,
+		"jurusan":    user.Jurusan,
+		"avatar_url": user.AvatarURL,
+		"updated_at": user.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) SetVerifikasi(ctx context.Context, id primitive.ObjectID, hash string, expiresAt time.Time) error {
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "email_terverifikasi": false},
+		bson.M{"$set": bson.M{"verifikasi_hash": hash, "verifikasi_expires_at": expiresAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Verifikasi(ctx context.Context, hash string, now time.Time) (*models.User, error) {
+	var user models.User
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"verifikasi_hash": hash, "verifikasi_expires_at": bson.M{"$gt": now}},
+		bson.M{
+			"$set":   bson.M{"email_terverifikasi": true},
+			"$unset": bson.M{"verifikasi_hash": "", "verifikasi_expires_at": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) GantiJurusan(ctx context.Context, lama, baru string) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{"jurusan": lama}, bson.M{"$set": bson.M{"jurusan": baru}})
+	return err
+}
+
+func (r *mongoUserRepository) Kunci(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$inc": bson.M{"versi_kunci": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
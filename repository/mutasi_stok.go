@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MutasiStokFilter membatasi mutasi stok yang diambil. Field kosong
+// diabaikan. Dari dan Sampai membatasi waktu mutasi (inklusif).
+type MutasiStokFilter struct {
+	AlatID        *primitive.ObjectID
+	Jenis         string
+	TransactionID *primitive.ObjectID
+	Dari          time.Time
+	Sampai        time.Time
+	// AlatIDs membatasi ke alat ini, misalnya alat milik satu lab. nil
+	// berarti semua alat, slice kosong berarti tidak ada yang cocok.
+	AlatIDs []primitive.ObjectID
+}
+
+// MutasiStokRepository menyimpan buku besar stok alat. Seperti audit log,
+// catatan tidak bisa diubah atau dihapus.
+type MutasiStokRepository interface {
+	Catat(ctx context.Context, m *models.MutasiStok) error
+	// List mengembalikan mutasi yang cocok, default dari yang terbaru
+	List(ctx context.Context, filter MutasiStokFilter, opts ListOptions) ([]models.MutasiStok, int64, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordResetRepository mengakses token reset password
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	// Pakai menandai token dengan hash ini sudah dipakai dan mengembalikannya.
+	// ErrNotFound jika token tidak ada, sudah dipakai atau kadaluarsa.
+	Pakai(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordReset, error)
+	// HapusUser menghapus semua token reset milik user
+	HapusUser(ctx context.Context, userID primitive.ObjectID) error
+}
@@ -0,0 +1,43 @@
+// Package repository memisahkan akses data dari handler. Setiap koleksi
+// punya interface repository dengan dua implementasi: MongoDB untuk produksi
+// dan in-memory untuk menjalankan aplikasi / test tanpa cluster Atlas.
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+// Error umum yang dikembalikan oleh semua implementasi repository
+var (
+	ErrNotFound       = errors.New("data tidak ditemukan")
+	ErrStokTidakCukup = errors.New("stok alat tidak mencukupi")
+	ErrDuplikat       = errors.New("data sudah ada")
+)
+
+// Transactor menjalankan beberapa operasi repository secara atomik.
+// Operasi di dalam fn harus memakai ctx yang diberikan ke fn.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Store mengumpulkan semua repository yang dipakai handler
+type Store struct {
+	Users        UserRepository
+	Alat         AlatRepository
+	Unit         AlatUnitRepository
+	Transactions TransactionRepository
+	Kategori     KategoriRepository
+	Jurusan      JurusanRepository
+	Denda        DendaRepository
+	Kasus        KasusRepository
+	Reservasi    ReservasiRepository
+	Sessions     SessionRepository
+	Resets       PasswordResetRepository
+	Audit        AuditRepository
+	MutasiStok   MutasiStokRepository
+	Roles        RoleRepository
+	Lab          LabRepository
+	Aturan       AturanRepository
+	Tx           Transactor
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReservasiFilter membatasi reservasi yang diambil. Field kosong diabaikan.
+// Jika Dari dan Sampai diisi, hanya reservasi yang beririsan dengan rentang
+// tersebut yang dikembalikan.
+type ReservasiFilter struct {
+	UserID *primitive.ObjectID
+	AlatID *primitive.ObjectID
+	Status string
+	Dari   time.Time
+	Sampai time.Time
+	// AlatIDs membatasi ke alat ini, misalnya alat milik satu lab. nil
+	// berarti semua alat, slice kosong berarti tidak ada yang cocok.
+	AlatIDs []primitive.ObjectID
+}
+
+// ReservasiRepository mengakses data reservasi alat
+type ReservasiRepository interface {
+	Create(ctx context.Context, reservasi *models.Reservasi) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Reservasi, error)
+	List(ctx context.Context, filter ReservasiFilter, opts ListOptions) ([]models.Reservasi, int64, error)
+	Update(ctx context.Context, reservasi *models.Reservasi) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleRepository mengakses role dan permission-nya
+type RoleRepository interface {
+	// Create menyimpan role baru. Mengembalikan ErrDuplikat jika nama sudah dipakai.
+	Create(ctx context.Context, role *models.Role) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error)
+	FindByNama(ctx context.Context, nama string) (*models.Role, error)
+	// List mengembalikan semua role urut nama
+	List(ctx context.Context) ([]models.Role, error)
+	Update(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionRepository mengakses data session login (refresh token)
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	// FindByAccessJTI mencari session yang access token terakhirnya ber-jti ini
+	FindByAccessJTI(ctx context.Context, jti string) (*models.Session, error)
+	// Rotasi mengganti refresh token dan jti access token session. Gagal
+	// dengan ErrNotFound jika refresh token lama sudah tidak cocok, misalnya
+	// karena sudah dipakai oleh request refresh lain.
+	Rotasi(ctx context.Context, id primitive.ObjectID, refreshHashLama, refreshHashBaru, accessJTI string, expiresAt, now time.Time) error
+	Revoke(ctx context.Context, id primitive.ObjectID, now time.Time) error
+	// RevokeSemuaUser mencabut semua session aktif milik user kecuali
+	// session kecuali (NilObjectID berarti semua) dan mengembalikan jumlah
+	// session yang dicabut
+	RevokeSemuaUser(ctx context.Context, userID, kecuali primitive.ObjectID, now time.Time) (int64, error)
+}
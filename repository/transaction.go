@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TransactionFilter membatasi transaksi yang diambil. Field kosong diabaikan.
+type TransactionFilter struct {
+	UserID *primitive.ObjectID
+	AlatID *primitive.ObjectID
+	// GrupID membatasi ke item satu peminjaman multi-item
+	GrupID *primitive.ObjectID
+	Status []string
+	// Dari dan Sampai membatasi created_at (waktu pengajuan)
+	Dari   time.Time
+	Sampai time.Time
+	// JatuhTempoSebelum dipakai untuk mencari transaksi yang terlambat
+	JatuhTempoSebelum time.Time
+	// AlatIDs membatasi ke alat ini, misalnya alat milik satu lab. nil
+	// berarti semua alat, slice kosong berarti tidak ada yang cocok.
+	AlatIDs []primitive.ObjectID
+	// PerpanjanganMenunggu membatasi ke transaksi dengan pengajuan
+	// perpanjangan yang belum diputuskan
+	PerpanjanganMenunggu bool
+	// Foto mencari transaksi yang pemeriksaan pengembaliannya memakai foto ini
+	Foto string
+}
+
+// TransactionRepository mengakses data transaksi peminjaman
+type TransactionRepository interface {
+	Create(ctx context.Context, trans *models.Transaction) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error)
+	// List mengembalikan satu halaman transaksi beserta jumlah seluruh
+	// transaksi yang cocok
+	List(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.Transaction, int64, error)
+	Update(ctx context.Context, trans *models.Transaction) error
+	// Riwayat mengembalikan transaksi lengkap dengan nama alat,
+	// default diurutkan dari pengajuan terbaru
+	Riwayat(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]models.RiwayatPeminjaman, int64, error)
+	// ListTerlambat mengembalikan transaksi DIAMBIL yang jatuh temponya
+	// sebelum now, lengkap dengan data peminjam dan alat
+	ListTerlambat(ctx context.Context, now time.Time) ([]models.PeminjamanTerlambat, error)
+}
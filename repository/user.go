@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserFilter membatasi user yang diambil. Field kosong diabaikan.
+type UserFilter struct {
+	Role    string
+	Jurusan string
+	// NIM mencari user yang NIM-nya diawali teks ini
+	NIM string
+	// AvatarURL mencari pemilik foto profil
+	AvatarURL string
+}
+
+// UserRepository mengakses data akun user
+type UserRepository interface {
+	// Create menyimpan user baru. Mengembalikan ErrDuplikat jika NIM sudah dipakai.
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByNIM(ctx context.Context, nim string) (*models.User, error)
+	List(ctx context.Context, filter UserFilter, opts ListOptions) ([]models.User, int64, error)
+	UpdateRole(ctx context.Context, id primitive.ObjectID, role string) error
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	// UpdateProfil menyimpan field profil yang bisa diubah user sendiri:
+	// nama, phone, jurusan, avatar_url dan updated_at
+	UpdateProfil(ctx context.Context, user *models.User) error
+	// GantiJurusan memindahkan semua user dari jurusan lama ke nama baru
+	GantiJurusan(ctx context.Context, lama, baru string) error
+	// SetVerifikasi mengganti token verifikasi email user yang belum terverifikasi
+	SetVerifikasi(ctx context.Context, id primitive.ObjectID, hash string, expiresAt time.Time) error
+	// Verifikasi menandai email user pemilik token ini sudah terverifikasi.
+	// ErrNotFound jika token tidak ada atau sudah kadaluarsa.
+	Verifikasi(ctx context.Context, hash string, now time.Time) (*models.User, error)
+	// Kunci menulis dokumen user di dalam transaksi supaya transaksi paralel
+	// milik user yang sama saling konflik dan dijalankan berurutan. Dipakai
+	// sebelum memeriksa kuota peminjaman user.
+	Kunci(ctx context.Context, id primitive.ObjectID) error
+}
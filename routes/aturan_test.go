@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// buatAturanKuota membuat aturan MAKS_PEMINJAMAN_AKTIF untuk mahasiswa
+func (s *serverUji) buatAturanKuota(admin string, batas int) {
+	s.t.Helper()
+	s.harus(http.StatusCreated, "POST", "/api/admin/aturan", admin, map[string]any{
+		"nama": "Kuota mahasiswa", "jenis": models.AturanMaksPeminjamanAktif, "role": []string{models.RoleMahasiswa},
+		"batas": batas, "aktif": true,
+	})
+}
+
+func TestAturanBerlakuUntukReservasi(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 3)
+	s.buatAturanKuota(admin, 1)
+
+	besok := time.Now().Add(24 * time.Hour)
+	reservasi := map[string]any{
+		"alat_id": alatID, "jumlah": 1,
+		"mulai": besok.Format(time.RFC3339), "selesai": besok.Add(2 * time.Hour).Format(time.RFC3339),
+	}
+	out := s.harus(http.StatusCreated, "POST", "/api/reservasi", mhs, reservasi)
+	reservasiID := data(out)["id"].(string)
+
+	// Pengajuan biasa memenuhi kuota, reservasi berikutnya ditolak
+	s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	out = s.harus(http.StatusForbidden, "POST", "/api/reservasi", mhs, reservasi)
+	if out["kode"] != models.AturanMaksPeminjamanAktif {
+		t.Errorf("kode %v, ingin %s", out["kode"], models.AturanMaksPeminjamanAktif)
+	}
+
+	// Reservasi yang sudah ada juga tidak bisa diambil selama kuota penuh
+	ctx := context.Background()
+	oid, _ := primitive.ObjectIDFromHex(reservasiID)
+	res, err := s.store.Reservasi.FindByID(ctx, oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Mulai = time.Now().Add(-time.Hour)
+	if err := s.store.Reservasi.Update(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+	out = s.harus(http.StatusForbidden, "POST", "/api/admin/reservasi/"+reservasiID+"/ambil", admin, nil)
+	if out["kode"] != models.AturanMaksPeminjamanAktif {
+		t.Errorf("kode %v, ingin %s", out["kode"], models.AturanMaksPeminjamanAktif)
+	}
+	res, err = s.store.Reservasi.FindByID(ctx, oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != models.ReservasiAktif {
+		t.Errorf("status reservasi %s, ingin %s", res.Status, models.ReservasiAktif)
+	}
+}
+
+// TestAturanKuotaPengajuanParalel memastikan pengajuan paralel dari user
+// yang sama tidak bisa sama-sama lolos kuota
+func TestAturanKuotaPengajuanParalel(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 10)
+	s.buatAturanKuota(admin, 1)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		dibuat int
+	)
+	mulai := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-mulai
+			if code, _ := s.kirim("POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1}); code == http.StatusCreated {
+				mu.Lock()
+				dibuat++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(mulai)
+	wg.Wait()
+
+	if dibuat != 1 {
+		t.Errorf("%d pengajuan dibuat, ingin 1", dibuat)
+	}
+	oid, _ := primitive.ObjectIDFromHex(alatID)
+	_, total, err := s.store.Transactions.List(context.Background(), repository.TransactionFilter{AlatID: &oid}, repository.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Errorf("%d transaksi tersimpan, ingin 1", total)
+	}
+}
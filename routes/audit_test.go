@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+)
+
+// TestAuditAkun memastikan registrasi, verifikasi email, ganti dan reset
+// password serta logout tercatat di audit log dengan aktor pemilik akun
+func TestAuditAkun(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+
+	s.harus(http.StatusOK, "PUT", "/api/me/password", mhs, map[string]any{"password_lama": sandiMahasiswa, "password_baru": "rahasia456"})
+	s.harus(http.StatusOK, "POST", "/api/auth/logout", mhs, nil)
+	s.harus(http.StatusOK, "POST", "/api/auth/forgot-password", "", map[string]any{"email": "budi@kampus.ac.id"})
+	s.harus(http.StatusOK, "POST", "/api/auth/reset-password", "", map[string]any{
+		"token": s.surat.tokenTerakhir(t, "budi@kampus.ac.id"), "password_baru": "rahasia789",
+	})
+
+	ctx := context.Background()
+	user, err := s.store.Users.FindByEmail(ctx, "budi@kampus.ac.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, aksi := range []string{
+		models.AuditRegister, models.AuditVerifikasiEmail, models.AuditGantiPassword,
+		models.AuditLogout, models.AuditResetPassword,
+	} {
+		logs, _, err := s.store.Audit.List(ctx, repository.AuditFilter{Aksi: aksi, TargetID: &user.ID}, repository.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != 1 {
+			t.Errorf("%s tercatat %d kali, ingin 1", aksi, len(logs))
+			continue
+		}
+		if logs[0].AktorID != user.ID {
+			t.Errorf("%s dicatat dengan aktor %s, ingin %s", aksi, logs[0].AktorID.Hex(), user.ID.Hex())
+		}
+		if _, ada := logs[0].Sesudah["password_hash"]; ada {
+			t.Errorf("%s mencatat password hash", aksi)
+		}
+	}
+}
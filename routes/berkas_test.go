@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// fotoPNG cukup dikenali sebagai PNG oleh http.DetectContentType
+var fotoPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR-isi-foto-uji")
+
+func TestBerkasHanyaUntukPemilikAtauStaff(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	budi := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	ani := s.daftarMahasiswa(admin, "ani@kampus.ac.id", "F55124002")
+
+	code, out := s.kirimForm("PUT", "/api/me/avatar", budi, nil, map[string][]byte{"avatar": fotoPNG})
+	if code != http.StatusOK {
+		t.Fatalf("upload avatar: %d %v", code, out)
+	}
+	url := data(out)["avatar_url"].(string)
+
+	if code, isi := s.unduh(url, budi); code != http.StatusOK || !bytes.Equal(isi, fotoPNG) {
+		t.Errorf("pemilik: status %d", code)
+	}
+	if code, _ := s.unduh(url, admin); code != http.StatusOK {
+		t.Errorf("staff: status %d, ingin 200", code)
+	}
+	if code, _ := s.unduh(url, ani); code != http.StatusNotFound {
+		t.Errorf("user lain: status %d, ingin 404", code)
+	}
+	if code, _ := s.unduh(url, ""); code != http.StatusUnauthorized {
+		t.Errorf("tanpa login: status %d, ingin 401", code)
+	}
+	if code, _ := s.unduh("/uploads/", admin); code == http.StatusOK {
+		t.Error("daftar isi folder upload tidak boleh tersaji")
+	}
+	if code, _ := s.unduh("/uploads/..%2fgo.mod", admin); code == http.StatusOK {
+		t.Error("path di luar folder upload tidak boleh tersaji")
+	}
+}
+
+func TestFotoPengembalianGagalDihapus(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 2)
+	lainID := s.buatAlat(admin, "Multimeter", 1)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	transID := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+
+	// Unit milik alat lain ditolak setelah foto sempat tersimpan
+	out = s.harus(http.StatusOK, "GET", "/api/admin/alat/"+lainID+"/unit", admin, nil)
+	unitLain := out["data"].([]any)[0].(map[string]any)["id"].(string)
+	laporan, _ := json.Marshal(map[string]any{
+		"unit": []map[string]any{{"unit_id": unitLain, "kondisi": "RUSAK_RINGAN"}},
+	})
+	code, out := s.kirimForm("POST", "/api/pengembalian/"+transID, mhs,
+		map[string]string{"data": string(laporan)}, map[string][]byte{"foto_" + unitLain: fotoPNG})
+	if code != http.StatusBadRequest {
+		t.Fatalf("status %d, ingin 400: %v", code, out)
+	}
+
+	sisa, err := os.ReadDir(s.dirUpload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sisa) != 0 {
+		t.Errorf("%d foto yatim tertinggal setelah pengembalian gagal", len(sisa))
+	}
+}
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+// saranAlat mengambil daftar saran dari endpoint suggest
+func saranAlat(out map[string]any) []string {
+	var saran []string
+	for _, item := range out["data"].([]any) {
+		saran = append(saran, item.(string))
+	}
+	return saran
+}
+
+func TestCariAlat(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	s.buatAlat(admin, "Osiloskop Digital", 1)
+	analog := s.buatAlat(admin, "Osiloskop Analog", 1)
+	s.buatAlat(admin, "Multimeter", 1)
+	s.harus(http.StatusCreated, "POST", "/api/admin/alat", admin, map[string]any{
+		"nama": "Probe", "kategori": "Aksesoris", "deskripsi": "Probe cadangan untuk osiloskop",
+		"stok_total": 1, "nilai_barang": 50000,
+	})
+	cari := func(q string) []string {
+		t.Helper()
+		return namaAlat(s.harus(http.StatusOK, "GET", "/api/alat?q="+url.QueryEscape(q), mhs, nil))
+	}
+
+	// Huruf besar kecil tidak berpengaruh, kecocokan di nama lebih relevan
+	// daripada di deskripsi
+	hasil := cari("OSILOSKOP")
+	if len(hasil) != 3 || hasil[2] != "Probe" {
+		t.Errorf("hasil %v, ingin dua osiloskop lalu Probe", hasil)
+	}
+	if hasil := cari("multimeter"); !slices.Equal(hasil, []string{"Multimeter"}) {
+		t.Errorf("hasil %v, ingin [Multimeter]", hasil)
+	}
+
+	s.harus(http.StatusOK, "DELETE", "/api/admin/alat/"+analog, admin, nil)
+	if hasil := cari("osiloskop"); slices.Contains(hasil, "Osiloskop Analog") || len(hasil) != 2 {
+		t.Errorf("hasil %v masih memuat alat yang dihapus", hasil)
+	}
+}
+
+func TestSaranAlat(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	s.buatAlat(admin, "Osiloskop Digital", 1)
+	analog := s.buatAlat(admin, "Osiloskop Analog", 1)
+	for i := 1; i <= 7; i++ {
+		s.buatAlat(admin, fmt.Sprintf("Sensor Suhu %d", i), 1)
+	}
+	saran := func(query string) []string {
+		t.Helper()
+		return saranAlat(s.harus(http.StatusOK, "GET", "/api/alat/suggest?"+query, mhs, nil))
+	}
+
+	// Salah ketik ringan dan huruf besar tetap menghasilkan saran
+	if hasil := saran("q=OSILOSKP"); len(hasil) != 2 {
+		t.Errorf("saran %v, ingin dua osiloskop", hasil)
+	}
+
+	// Jumlah saran dibatasi limit, default 5
+	if hasil := saran("q=sensor"); len(hasil) != 5 {
+		t.Errorf("%d saran tanpa limit, ingin 5", len(hasil))
+	}
+	if hasil := saran("q=sensor&limit=3"); len(hasil) != 3 {
+		t.Errorf("%d saran dengan limit=3, ingin 3", len(hasil))
+	}
+	if hasil := saran("q=sensor&limit=20"); len(hasil) != 7 {
+		t.Errorf("%d saran dengan limit=20, ingin 7", len(hasil))
+	}
+	s.harus(http.StatusBadRequest, "GET", "/api/alat/suggest?q=sensor&limit=21", mhs, nil)
+	s.harus(http.StatusBadRequest, "GET", "/api/alat/suggest?q=", mhs, nil)
+
+	s.harus(http.StatusOK, "DELETE", "/api/admin/alat/"+analog, admin, nil)
+	if hasil := saran("q=osiloskop"); !slices.Equal(hasil, []string{"Osiloskop Digital"}) {
+		t.Errorf("saran %v, ingin [Osiloskop Digital]", hasil)
+	}
+}
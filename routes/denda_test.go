@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"SIPAK/models"
+)
+
+// kembaliTerlambat memundurkan jatuh tempo transaksi sejumlah hari lalu
+// mengembalikannya, dan mengembalikan denda yang tercatat
+func (s *serverUji) kembaliTerlambat(mhs, transID string, hari int) map[string]any {
+	s.t.Helper()
+	s.aturJatuhTempo(transID, time.Now().Add(-time.Duration(hari)*24*time.Hour+time.Hour))
+	out := s.harus(http.StatusOK, "POST", "/api/pengembalian/"+transID, mhs, nil)
+	denda, _ := data(out)["denda"].(map[string]any)
+	if denda == nil {
+		s.t.Fatal("pengembalian terlambat tidak menghasilkan denda")
+	}
+	return denda
+}
+
+// buatAlatBerdenda membuat alat dengan denda tetap per hari
+func (s *serverUji) buatAlatBerdenda(admin string, tarif int64) string {
+	s.t.Helper()
+	out := s.harus(http.StatusCreated, "POST", "/api/admin/alat", admin, map[string]any{
+		"nama": "Osiloskop", "kategori": "Elektronik", "stok_total": 2, "nilai_barang": 1000000,
+		"kebijakan_denda": map[string]any{"tipe": models.DendaPerHari, "tarif_per_hari": tarif},
+	})
+	return data(out)["id"].(string)
+}
+
+func TestDendaDibayar(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlatBerdenda(admin, 5000)
+
+	transID, _ := s.pinjamDiambil(admin, mhs, alatID)
+	denda := s.kembaliTerlambat(mhs, transID, 3)
+	if denda["hari_terlambat"] != float64(3) || denda["jumlah"] != float64(15000) {
+		t.Fatalf("denda %v hari / %v, ingin 3 hari / 15000", denda["hari_terlambat"], denda["jumlah"])
+	}
+	dendaID := denda["id"].(string)
+
+	out := s.harus(http.StatusOK, "GET", "/api/denda/me", mhs, nil)
+	if list, _ := out["data"].([]any); len(list) != 1 {
+		t.Errorf("%d denda di /denda/me, ingin 1", len(list))
+	}
+
+	pinjam := map[string]any{"alat_id": alatID, "jumlah": 1}
+	out = s.harus(http.StatusForbidden, "POST", "/api/peminjaman", mhs, pinjam)
+	if out["kode"] != models.KodeDendaBelumLunas {
+		t.Errorf("kode %v, ingin %s", out["kode"], models.KodeDendaBelumLunas)
+	}
+
+	// Pembayaran sebagian belum melunasi denda
+	out = s.harus(http.StatusOK, "POST", "/api/admin/denda/"+dendaID+"/bayar", admin, map[string]any{"jumlah": 5000})
+	if data(out)["status"] != models.DendaBelumLunas || data(out)["total_dibayar"] != float64(5000) {
+		t.Errorf("setelah bayar sebagian: status %v, total_dibayar %v", data(out)["status"], data(out)["total_dibayar"])
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/denda/"+dendaID+"/bayar", admin, map[string]any{"jumlah": 20000})
+	s.harus(http.StatusForbidden, "POST", "/api/peminjaman", mhs, pinjam)
+
+	// Jumlah kosong melunasi sisa denda
+	out = s.harus(http.StatusOK, "POST", "/api/admin/denda/"+dendaID+"/bayar", admin, map[string]any{})
+	if data(out)["status"] != models.DendaLunas || data(out)["total_dibayar"] != float64(15000) {
+		t.Errorf("setelah lunas: status %v, total_dibayar %v", data(out)["status"], data(out)["total_dibayar"])
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/denda/"+dendaID+"/bayar", admin, map[string]any{"jumlah": 1000})
+
+	s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, pinjam)
+}
+
+func TestDendaDihapuskan(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlatBerdenda(admin, 2000)
+
+	transID, _ := s.pinjamDiambil(admin, mhs, alatID)
+	dendaID := s.kembaliTerlambat(mhs, transID, 1)["id"].(string)
+
+	pinjam := map[string]any{"alat_id": alatID, "jumlah": 1}
+	s.harus(http.StatusForbidden, "POST", "/api/peminjaman", mhs, pinjam)
+
+	s.harus(http.StatusForbidden, "POST", "/api/admin/denda/"+dendaID+"/hapuskan", mhs, map[string]any{"alasan": "Sakit"})
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/denda/"+dendaID+"/hapuskan", admin, map[string]any{"alasan": " "})
+	out := s.harus(http.StatusOK, "POST", "/api/admin/denda/"+dendaID+"/hapuskan", admin, map[string]any{"alasan": "Surat keterangan sakit"})
+	if data(out)["status"] != models.DendaDihapuskan || data(out)["alasan_hapus"] != "Surat keterangan sakit" {
+		t.Errorf("setelah dihapuskan: status %v, alasan %v", data(out)["status"], data(out)["alasan_hapus"])
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/denda/"+dendaID+"/bayar", admin, map[string]any{})
+
+	s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, pinjam)
+}
@@ -0,0 +1,264 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"SIPAK/config"
+	"SIPAK/mail"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	apiKeyUji      = "kunci-uji"
+	emailAdminUji  = "admin@kampus.ac.id"
+	sandiAdminUji  = "admin123"
+	jurusanUji     = "Teknik Informatika"
+	sandiMahasiswa = "rahasia123"
+)
+
+// kotakSurat menampung email yang dikirim selama test
+type kotakSurat struct {
+	mu    sync.Mutex
+	pesan []mail.Pesan
+	// gagal membuat semua pengiriman gagal, seperti SMTP yang mati
+	gagal bool
+}
+
+func (k *kotakSurat) Kirim(ctx context.Context, p mail.Pesan) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.gagal {
+		return errors.New("smtp tidak tersedia")
+	}
+	k.pesan = append(k.pesan, p)
+	return nil
+}
+
+var formatToken = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// tokenTerakhir mengambil token dari email terakhir untuk alamat ini
+func (k *kotakSurat) tokenTerakhir(t *testing.T, ke string) string {
+	t.Helper()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i := len(k.pesan) - 1; i >= 0; i-- {
+		if k.pesan[i].Ke == ke {
+			if token := formatToken.FindString(k.pesan[i].Isi); token != "" {
+				return token
+			}
+		}
+	}
+	t.Fatalf("tidak ada email bertoken untuk %s", ke)
+	return ""
+}
+
+// serverUji adalah router lengkap di atas repository in-memory
+type serverUji struct {
+	t     *testing.T
+	h     http.Handler
+	store *repository.Store
+	surat *kotakSurat
+	// dirUpload adalah folder penyimpanan file upload
+	dirUpload string
+}
+
+func newServerUji(t *testing.T) *serverUji {
+	t.Helper()
+	return newServerUjiStore(t, repository.NewMemoryStore())
+}
+
+// newServerUjiStore membuat server uji di atas store yang sudah disiapkan
+func newServerUjiStore(t *testing.T, store *repository.Store) *serverUji {
+	t.Helper()
+	config.AppConfig = config.Config{
+		JWTSecret:               "rahasia-uji",
+		APIKey:                  apiKeyUji,
+		DBDriver:                "memory",
+		DefaultMaksHariPinjam:   7,
+		DefaultMaksPerpanjangan: 1,
+		AccessTokenTTL:          time.Minute,
+		RefreshTokenTTL:         time.Hour,
+		ResetPasswordTTL:        time.Minute,
+		VerifikasiEmailTTL:      time.Hour,
+		PengembalianMandiri:     true,
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(sandiAdminUji), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := models.User{
+		ID:                 primitive.NewObjectID(),
+		Nama:               "Admin",
+		Email:              emailAdminUji,
+		PasswordHash:       string(hash),
+		Role:               models.RoleAdmin,
+		EmailTerverifikasi: true,
+		CreatedAt:          time.Now(),
+	}
+	if err := store.Users.Create(context.Background(), &admin); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files, err := storage.NewLocal(dir, "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	surat := &kotakSurat{}
+	return &serverUji{t: t, h: NewRouter(store, files, surat), store: store, surat: surat, dirUpload: dir}
+}
+
+// kirim mengirim request JSON dan mengembalikan status beserta body response
+func (s *serverUji) kirim(method, path, token string, body any) (int, map[string]any) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("X-API-Key", apiKeyUji)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.h.ServeHTTP(rec, req)
+
+	var out map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+// kirimForm mengirim form multipart berisi field teks dan file
+func (s *serverUji) kirimForm(method, path, token string, field map[string]string, file map[string][]byte) (int, map[string]any) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for nama, isi := range field {
+		if err := form.WriteField(nama, isi); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	for nama, isi := range file {
+		part, err := form.CreateFormFile(nama, nama+".png")
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		part.Write(isi)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("X-API-Key", apiKeyUji)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.h.ServeHTTP(rec, req)
+
+	var out map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+// unduh mengambil file upload dan mengembalikan status beserta isinya
+func (s *serverUji) unduh(url, token string) (int, []byte) {
+	req := httptest.NewRequest("GET", url, nil)
+	req.Header.Set("X-API-Key", apiKeyUji)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+// harus mengirim request dan menggagalkan test jika status tidak sesuai
+func (s *serverUji) harus(status int, method, path, token string, body any) map[string]any {
+	s.t.Helper()
+	code, out := s.kirim(method, path, token, body)
+	if code != status {
+		s.t.Fatalf("%s %s: status %d, ingin %d: %v", method, path, code, status, out)
+	}
+	return out
+}
+
+func data(out map[string]any) map[string]any {
+	d, _ := out["data"].(map[string]any)
+	return d
+}
+
+func (s *serverUji) login(email, password string) string {
+	s.t.Helper()
+	out := s.harus(http.StatusOK, "POST", "/api/auth/login", "", map[string]any{"email": email, "password": password})
+	return data(out)["token"].(string)
+}
+
+func (s *serverUji) loginAdmin() string {
+	return s.login(emailAdminUji, sandiAdminUji)
+}
+
+// daftarMahasiswa meregistrasi, memverifikasi email lalu login mahasiswa baru
+func (s *serverUji) daftarMahasiswa(admin, email, nim string) string {
+	s.t.Helper()
+	if _, err := s.store.Jurusan.FindByNama(context.Background(), jurusanUji); err != nil {
+		s.harus(http.StatusCreated, "POST", "/api/admin/jurusan", admin, map[string]any{"nama": jurusanUji, "fakultas": "Teknik"})
+	}
+	s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Mahasiswa " + nim, "email": email, "password": sandiMahasiswa, "nim": nim, "jurusan": jurusanUji,
+	})
+	s.harus(http.StatusOK, "POST", "/api/auth/verify-email", "", map[string]any{"token": s.surat.tokenTerakhir(s.t, email)})
+	return s.login(email, sandiMahasiswa)
+}
+
+// buatAlat membuat alat baru beserta unit fisiknya
+func (s *serverUji) buatAlat(admin, nama string, stok int) string {
+	s.t.Helper()
+	out := s.harus(http.StatusCreated, "POST", "/api/admin/alat", admin, map[string]any{
+		"nama": nama, "kategori": "Elektronik", "stok_total": stok, "nilai_barang": 1000000,
+	})
+	return data(out)["id"].(string)
+}
+
+// alat membaca alat langsung dari store
+func (s *serverUji) alat(id string) *models.Alat {
+	s.t.Helper()
+	oid, _ := primitive.ObjectIDFromHex(id)
+	alat, err := s.store.Alat.FindByID(context.Background(), oid)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return alat
+}
+
+// aturJatuhTempo mengganti jatuh tempo transaksi langsung di store
+func (s *serverUji) aturJatuhTempo(transID string, jatuhTempo time.Time) {
+	s.t.Helper()
+	ctx := context.Background()
+	oid, _ := primitive.ObjectIDFromHex(transID)
+	trans, err := s.store.Transactions.FindByID(ctx, oid)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	trans.JatuhTempo = jatuhTempo
+	if err := s.store.Transactions.Update(ctx, trans); err != nil {
+		s.t.Fatal(err)
+	}
+}
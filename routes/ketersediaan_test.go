@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// ketersediaan mengambil jumlah tersedia per slot harian dari kalender alat
+func (s *serverUji) ketersediaan(token, alatID, dari, sampai string) []float64 {
+	s.t.Helper()
+	out := s.harus(http.StatusOK, "GET", "/api/alat/"+alatID+"/ketersediaan?from="+dari+"&to="+sampai, token, nil)
+	var tersedia []float64
+	for _, slot := range out["data"].([]any) {
+		tersedia = append(tersedia, slot.(map[string]any)["tersedia"].(float64))
+	}
+	return tersedia
+}
+
+// TestKalenderKetersediaan memastikan peminjaman dan reservasi hanya
+// mengurangi ketersediaan pada hari yang beririsan dengan rentangnya
+func TestKalenderKetersediaan(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 4)
+
+	y, m, d := time.Now().AddDate(0, 0, 20).Date()
+	hari := func(n, jam int) time.Time { return time.Date(y, m, d+n, jam, 0, 0, 0, time.Local) }
+	dari, sampai := hari(0, 0).Format("2006-01-02"), hari(4, 0).Format("2006-01-02")
+
+	cek := func(ingin []float64) {
+		t.Helper()
+		got := s.ketersediaan(mhs, alatID, dari, sampai)
+		if len(got) != len(ingin) {
+			t.Fatalf("%d slot, ingin %d", len(got), len(ingin))
+		}
+		for i := range ingin {
+			if got[i] != ingin[i] {
+				t.Errorf("tersedia %v, ingin %v", got, ingin)
+				return
+			}
+		}
+	}
+	cek([]float64{4, 4, 4, 4, 4})
+
+	// Peminjaman menahan satu unit sampai jatuh tempo di hari kedua
+	transID, _ := s.pinjamDiambil(admin, mhs, alatID)
+	s.aturJatuhTempo(transID, hari(1, 12))
+	cek([]float64{3, 3, 4, 4, 4})
+
+	// Reservasi dua unit di hari keempat
+	s.harus(http.StatusCreated, "POST", "/api/reservasi", mhs, map[string]any{
+		"alat_id": alatID, "jumlah": 2,
+		"mulai": hari(3, 9).Format(time.RFC3339), "selesai": hari(3, 17).Format(time.RFC3339),
+	})
+	cek([]float64{3, 3, 4, 2, 4})
+}
@@ -0,0 +1,242 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pengajuanUji adalah satu transaksi beserta token peminjamnya
+type pengajuanUji struct {
+	id    string
+	token string
+}
+
+// periksaKonsistensi memastikan stok tidak negatif dan tidak ada unit yang
+// dipinjamkan ke dua transaksi sekaligus
+func periksaKonsistensi(t *testing.T, store *repository.Store, alatID primitive.ObjectID) {
+	ctx := context.Background()
+
+	alat, err := store.Alat.FindByID(ctx, alatID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if alat.StokTersedia < 0 || alat.StokTersedia > alat.StokTotal {
+		t.Errorf("stok_tersedia %d di luar 0..%d", alat.StokTersedia, alat.StokTotal)
+	}
+
+	aktif, _, err := store.Transactions.List(ctx, repository.TransactionFilter{
+		AlatID: &alatID,
+		Status: []string{models.StatusDisetujui, models.StatusDiambil, models.StatusSebagianKembali},
+	}, repository.ListOptions{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pemegang := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, trans := range aktif {
+		for _, unitID := range trans.UnitIDs {
+			if lain, ok := pemegang[unitID]; ok {
+				t.Errorf("unit %s dipinjamkan ke transaksi %s dan %s", unitID.Hex(), lain.Hex(), trans.ID.Hex())
+			}
+			pemegang[unitID] = trans.ID
+		}
+	}
+	if len(pemegang) > alat.StokTotal {
+		t.Errorf("%d unit dipinjamkan, stok total hanya %d", len(pemegang), alat.StokTotal)
+	}
+}
+
+// TestKonkurensiPersetujuanPengambilanPengembalian menjalankan setujui,
+// ambil dan kembalikan secara paralel untuk pengajuan yang jauh melebihi
+// stok, sambil terus memeriksa stok dan unit
+func TestKonkurensiPersetujuanPengambilanPengembalian(t *testing.T) {
+	ujiKonkurensiSiklus(t, newServerUji(t))
+}
+
+func ujiKonkurensiSiklus(t *testing.T, s *serverUji) {
+	admin := s.loginAdmin()
+
+	const stok = 3
+	alatHex := s.buatAlat(admin, "Proyektor", stok)
+	alatID, _ := primitive.ObjectIDFromHex(alatHex)
+
+	var pengajuan []pengajuanUji
+	for i := 0; i < 4; i++ {
+		mhs := s.daftarMahasiswa(admin, fmt.Sprintf("mhs%d@kampus.ac.id", i), fmt.Sprintf("F5512400%d", i))
+		for j := 0; j < 3; j++ {
+			out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatHex, "jumlah": 1})
+			pengajuan = append(pengajuan, pengajuanUji{id: data(out)["id"].(string), token: mhs})
+		}
+	}
+
+	// Pengamat memeriksa konsistensi terus-menerus selama request berjalan
+	selesai := make(chan struct{})
+	var pengamat sync.WaitGroup
+	pengamat.Add(1)
+	go func() {
+		defer pengamat.Done()
+		for {
+			select {
+			case <-selesai:
+				return
+			default:
+				periksaKonsistensi(t, s.store, alatID)
+			}
+		}
+	}()
+
+	// Setiap putaran menembakkan setujui, ambil dan kembalikan untuk semua
+	// transaksi sekaligus. Transisi yang tidak valid atau kehabisan stok
+	// ditolak, sisanya lanjut di putaran berikutnya.
+	for putaran := 0; putaran < 30; putaran++ {
+		var wg sync.WaitGroup
+		for _, p := range pengajuan {
+			wg.Add(3)
+			go func(p pengajuanUji) {
+				defer wg.Done()
+				s.kirim("POST", "/api/admin/peminjaman/"+p.id+"/setujui", admin, nil)
+			}(p)
+			go func(p pengajuanUji) {
+				defer wg.Done()
+				s.kirim("POST", "/api/admin/peminjaman/"+p.id+"/ambil", admin, nil)
+			}(p)
+			go func(p pengajuanUji) {
+				defer wg.Done()
+				s.kirim("POST", "/api/pengembalian/"+p.id, p.token, nil)
+			}(p)
+		}
+		wg.Wait()
+		periksaKonsistensi(t, s.store, alatID)
+		if t.Failed() {
+			break
+		}
+	}
+	close(selesai)
+	pengamat.Wait()
+
+	ctx := context.Background()
+	for _, p := range pengajuan {
+		oid, _ := primitive.ObjectIDFromHex(p.id)
+		trans, err := s.store.Transactions.FindByID(ctx, oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trans.Status != models.StatusDikembalikan {
+			t.Errorf("transaksi %s berstatus %s, ingin %s", p.id, trans.Status, models.StatusDikembalikan)
+		}
+	}
+
+	alat := s.alat(alatHex)
+	if alat.StokTersedia != stok || alat.StokTotal != stok {
+		t.Errorf("stok akhir %d/%d, ingin %d/%d", alat.StokTersedia, alat.StokTotal, stok, stok)
+	}
+	units, err := s.store.Unit.ListByAlat(ctx, alatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unit := range units {
+		if unit.Status != models.UnitTersedia || unit.TransactionID != nil {
+			t.Errorf("unit %s masih %s untuk transaksi %v", unit.KodeAset, unit.Status, unit.TransactionID)
+		}
+	}
+}
+
+// TestKonkurensiPersetujuanTidakMelebihiStok menyetujui banyak pengajuan
+// sekaligus dan memastikan hanya sebanyak stok yang lolos
+func TestKonkurensiPersetujuanTidakMelebihiStok(t *testing.T) {
+	ujiKonkurensiStok(t, newServerUji(t))
+}
+
+func ujiKonkurensiStok(t *testing.T, s *serverUji) {
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "mhs@kampus.ac.id", "F55124001")
+
+	const stok = 5
+	alatHex := s.buatAlat(admin, "Multimeter", stok)
+
+	var ids []string
+	for i := 0; i < 12; i++ {
+		out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatHex, "jumlah": 1})
+		ids = append(ids, data(out)["id"].(string))
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		disetujui int
+	)
+	mulai := make(chan struct{})
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			<-mulai
+			if code, _ := s.kirim("POST", "/api/admin/peminjaman/"+id+"/setujui", admin, nil); code == http.StatusOK {
+				mu.Lock()
+				disetujui++
+				mu.Unlock()
+			}
+		}(id)
+	}
+	close(mulai)
+	wg.Wait()
+
+	if disetujui != stok {
+		t.Errorf("%d pengajuan disetujui, ingin %d", disetujui, stok)
+	}
+	if alat := s.alat(alatHex); alat.StokTersedia != 0 {
+		t.Errorf("stok_tersedia %d, ingin 0", alat.StokTersedia)
+	}
+	alatID, _ := primitive.ObjectIDFromHex(alatHex)
+	periksaKonsistensi(t, s.store, alatID)
+}
+
+// TestKonkurensiMongo menjalankan test konkurensi di atas MongoDB sungguhan.
+// Di repository in-memory WithTransaction memakai satu mutex global sehingga
+// semua transaksi berjalan berurutan, jadi test di atas tidak bisa menangkap
+// write conflict yang hanya muncul di mongoTransactor. Test ini butuh replica
+// set (transaksi MongoDB tidak jalan di standalone) dan hanya dijalankan
+// kalau SIPAK_TEST_MONGO_URI diisi, misalnya:
+//
+//	SIPAK_TEST_MONGO_URI=mongodb://localhost:27017/?replicaSet=rs0 go test ./routes -run Mongo
+func TestKonkurensiMongo(t *testing.T) {
+	uri := os.Getenv("SIPAK_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("SIPAK_TEST_MONGO_URI tidak diisi")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	uji := map[string]func(*testing.T, *serverUji){
+		"SetujuiAmbilKembalikan":       ujiKonkurensiSiklus,
+		"PersetujuanTidakMelebihiStok": ujiKonkurensiStok,
+	}
+	for nama, fn := range uji {
+		t.Run(nama, func(t *testing.T) {
+			// Database terpisah per test supaya data tidak saling tercampur
+			db := client.Database(fmt.Sprintf("sipak_uji_%s", primitive.NewObjectID().Hex()))
+			t.Cleanup(func() { db.Drop(ctx) })
+			if err := repository.MigrateMongo(ctx, db); err != nil {
+				t.Fatal(err)
+			}
+			fn(t, newServerUjiStore(t, repository.NewMongoStore(client, db)))
+		})
+	}
+}
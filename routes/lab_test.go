@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// daftarLaboran mendaftarkan user baru sebagai laboran dan mengembalikan
+// token login beserta ID-nya
+func (s *serverUji) daftarLaboran(admin, email, nim string) (string, string) {
+	s.t.Helper()
+	s.daftarMahasiswa(admin, email, nim)
+	user, err := s.store.Users.FindByEmail(context.Background(), email)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.harus(http.StatusOK, "PATCH", "/api/admin/users/"+user.ID.Hex()+"/role", admin, map[string]any{"role": "laboran"})
+	return s.login(email, sandiMahasiswa), user.ID.Hex()
+}
+
+// buatLab membuat lab dengan satu staff
+func (s *serverUji) buatLab(admin, kode, staffID string) string {
+	s.t.Helper()
+	out := s.harus(http.StatusCreated, "POST", "/api/admin/lab", admin, map[string]any{
+		"kode": kode, "nama": "Lab " + kode, "staff_ids": []string{staffID},
+	})
+	return data(out)["id"].(string)
+}
+
+func TestPindahLabAlat(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	laboranA, idA := s.daftarLaboran(admin, "a@kampus.ac.id", "F55124001")
+	laboranB, idB := s.daftarLaboran(admin, "b@kampus.ac.id", "F55124002")
+	labA := s.buatLab(admin, "ELK", idA)
+	labB := s.buatLab(admin, "KIM", idB)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/admin/alat", laboranA, map[string]any{
+		"nama": "Osiloskop", "kategori": "Elektronik", "stok_total": 1, "nilai_barang": 1000000, "lab_id": labA,
+	})
+	alatID := data(out)["id"].(string)
+	ubah := func(token string, body map[string]any) int {
+		code, _ := s.kirim("PUT", "/api/admin/alat/"+alatID, token, body)
+		return code
+	}
+	if code := ubah(laboranB, map[string]any{"nama": "Osiloskop", "kategori": "Elektronik"}); code != http.StatusForbidden {
+		t.Errorf("laboran lab lain mengubah alat: status %d", code)
+	}
+
+	s.harus(http.StatusOK, "PUT", "/api/admin/alat/"+alatID, admin, map[string]any{
+		"nama": "Osiloskop", "kategori": "Elektronik", "lab_id": labB,
+	})
+	if alat := s.alat(alatID); alat.LabID == nil || alat.LabID.Hex() != labB {
+		t.Fatalf("lab_id %v, ingin %s", alat.LabID, labB)
+	}
+
+	if code := ubah(laboranA, map[string]any{"nama": "Osiloskop A", "kategori": "Elektronik"}); code != http.StatusForbidden {
+		t.Errorf("laboran lab lama masih bisa mengubah alat: status %d", code)
+	}
+	if code := ubah(laboranB, map[string]any{"nama": "Osiloskop B", "kategori": "Elektronik"}); code != http.StatusOK {
+		t.Errorf("laboran lab baru tidak bisa mengubah alat: status %d", code)
+	}
+	if alat := s.alat(alatID); alat.Nama != "Osiloskop B" {
+		t.Errorf("nama %q, ingin Osiloskop B", alat.Nama)
+	}
+}
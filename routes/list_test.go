@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+)
+
+// namaAlat mengambil nama alat dari response list secara berurutan
+func namaAlat(out map[string]any) []string {
+	var nama []string
+	for _, item := range out["data"].([]any) {
+		nama = append(nama, item.(map[string]any)["nama"].(string))
+	}
+	return nama
+}
+
+func TestListPaginasi(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	for _, nama := range []string{"Bor", "Amperemeter", "Eksikator", "Dinamometer", "Centrifuge"} {
+		s.buatAlat(admin, nama, 1)
+	}
+
+	out := s.harus(http.StatusOK, "GET", "/api/alat?sort=nama&limit=2&page=2", admin, nil)
+	if nama := namaAlat(out); len(nama) != 2 || nama[0] != "Centrifuge" || nama[1] != "Dinamometer" {
+		t.Errorf("halaman 2 berisi %v, ingin [Centrifuge Dinamometer]", nama)
+	}
+	meta := out["meta"].(map[string]any)
+	if meta["page"] != float64(2) || meta["limit"] != float64(2) || meta["total"] != float64(5) ||
+		meta["total_pages"] != float64(3) || meta["next_page"] != float64(3) {
+		t.Errorf("meta %v", meta)
+	}
+
+	// Halaman terakhir tidak punya next_page
+	out = s.harus(http.StatusOK, "GET", "/api/alat?sort=-nama&limit=2&page=3", admin, nil)
+	if nama := namaAlat(out); len(nama) != 1 || nama[0] != "Amperemeter" {
+		t.Errorf("halaman 3 berisi %v, ingin [Amperemeter]", nama)
+	}
+	if _, ada := out["meta"].(map[string]any)["next_page"]; ada {
+		t.Error("halaman terakhir masih punya next_page")
+	}
+
+	// Tanpa query memakai halaman 1 dengan limit default
+	out = s.harus(http.StatusOK, "GET", "/api/alat", admin, nil)
+	meta = out["meta"].(map[string]any)
+	if meta["page"] != float64(1) || meta["limit"] != float64(20) || meta["total_pages"] != float64(1) {
+		t.Errorf("meta default %v", meta)
+	}
+}
+
+func TestListBatasLimitDanSort(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+
+	out := s.harus(http.StatusOK, "GET", "/api/alat?limit=100", admin, nil)
+	if limit := out["meta"].(map[string]any)["limit"]; limit != float64(100) {
+		t.Errorf("limit %v, ingin 100", limit)
+	}
+	for _, q := range []string{"limit=101", "limit=0", "limit=-5", "limit=abc", "page=0"} {
+		s.harus(http.StatusBadRequest, "GET", "/api/alat?"+q, admin, nil)
+	}
+
+	// Sort hanya boleh memakai field yang diizinkan endpoint
+	s.harus(http.StatusOK, "GET", "/api/admin/users?sort=-created_at", admin, nil)
+	for _, path := range []string{
+		"/api/alat?sort=nilai_barang",
+		"/api/admin/users?sort=password_hash",
+		"/api/admin/users?sort=-password_hash",
+	} {
+		s.harus(http.StatusBadRequest, "GET", path, admin, nil)
+	}
+}
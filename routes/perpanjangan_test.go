@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"SIPAK/models"
+	"SIPAK/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestSetujuiPerpanjanganBentrokReservasi memastikan perpanjangan di
+// antrian admin diperiksa ulang terhadap reservasi yang dibuat belakangan
+func TestSetujuiPerpanjanganBentrokReservasi(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	lain := s.daftarMahasiswa(admin, "ani@kampus.ac.id", "F55124002")
+	alatID := s.buatAlat(admin, "Proyektor", 1)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	transID := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+
+	ctx := context.Background()
+	oid, _ := primitive.ObjectIDFromHex(transID)
+	jatuhTempo := func() time.Time {
+		trans, err := s.store.Transactions.FindByID(ctx, oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trans.JatuhTempo
+	}
+
+	// Perpanjangan pertama disetujui otomatis, yang kedua masuk antrian
+	// karena batas perpanjangan kategori sudah tercapai
+	s.harus(http.StatusOK, "POST", "/api/peminjaman/"+transID+"/perpanjang", mhs,
+		map[string]any{"jatuh_tempo": jatuhTempo().Add(48 * time.Hour).Format(time.RFC3339)})
+	lama := jatuhTempo()
+	s.harus(http.StatusAccepted, "POST", "/api/peminjaman/"+transID+"/perpanjang", mhs,
+		map[string]any{"jatuh_tempo": lama.Add(72 * time.Hour).Format(time.RFC3339)})
+
+	// Reservasi dibuat setelah pengajuan masuk antrian
+	mulai := lama.Add(24 * time.Hour)
+	s.harus(http.StatusCreated, "POST", "/api/reservasi", lain, map[string]any{
+		"alat_id": alatID, "jumlah": 1,
+		"mulai": mulai.Format(time.RFC3339), "selesai": mulai.Add(2 * time.Hour).Format(time.RFC3339),
+	})
+
+	s.harus(http.StatusConflict, "POST", "/api/admin/peminjaman/"+transID+"/perpanjangan/setujui", admin, nil)
+	if !jatuhTempo().Equal(lama) {
+		t.Fatalf("jatuh tempo berubah walaupun bentrok: %v", jatuhTempo())
+	}
+
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/perpanjangan/setujui", admin,
+		map[string]any{"abaikan_bentrok": true})
+	trans, err := s.store.Transactions.FindByID(ctx, oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terakhir := trans.Perpanjangan[len(trans.Perpanjangan)-1]
+	if terakhir.Status != models.PerpanjanganDisetujui || !terakhir.AbaikanBentrok {
+		t.Errorf("perpanjangan tidak sesuai: %+v", terakhir)
+	}
+
+	logs, _, err := s.store.Audit.List(ctx, repository.AuditFilter{
+		Aksi: models.AuditPerpanjanganSetujui, TargetID: &oid,
+	}, repository.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) == 0 {
+		t.Fatal("persetujuan tidak tercatat di audit log")
+	}
+	if b, _ := json.Marshal(logs[0].Sesudah); !strings.Contains(string(b), `"abaikan_bentrok":true`) {
+		t.Errorf("audit log tidak mencatat abaikan_bentrok: %s", b)
+	}
+}
+
+// pinjamDiambil membuat peminjaman yang sudah diambil dan mengembalikan
+// ID transaksi beserta jatuh temponya
+func (s *serverUji) pinjamDiambil(admin, mhs, alatID string) (string, time.Time) {
+	s.t.Helper()
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	transID := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+	oid, _ := primitive.ObjectIDFromHex(transID)
+	trans, err := s.store.Transactions.FindByID(context.Background(), oid)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return transID, trans.JatuhTempo
+}
+
+// TestPerpanjanganOtomatisBentrokReservasi memastikan perpanjangan yang
+// bentrok reservasi tidak disetujui otomatis, dan reservasi serta
+// perpanjangan paralel untuk rentang yang sama tidak lolos bersamaan
+func TestPerpanjanganOtomatisBentrokReservasi(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	lain := s.daftarMahasiswa(admin, "ani@kampus.ac.id", "F55124002")
+
+	alatID := s.buatAlat(admin, "Proyektor", 1)
+	transID, jatuhTempo := s.pinjamDiambil(admin, mhs, alatID)
+	mulai := jatuhTempo.Add(24 * time.Hour)
+	s.harus(http.StatusCreated, "POST", "/api/reservasi", lain, map[string]any{
+		"alat_id": alatID, "jumlah": 1,
+		"mulai": mulai.Format(time.RFC3339), "selesai": mulai.Add(2 * time.Hour).Format(time.RFC3339),
+	})
+	out := s.harus(http.StatusAccepted, "POST", "/api/peminjaman/"+transID+"/perpanjang", mhs,
+		map[string]any{"jatuh_tempo": jatuhTempo.Add(72 * time.Hour).Format(time.RFC3339)})
+	if msg, _ := out["message"].(string); !strings.Contains(msg, "direservasi") {
+		t.Errorf("pesan %q tidak menyebut bentrok reservasi", msg)
+	}
+
+	for i := 0; i < 10; i++ {
+		alatID := s.buatAlat(admin, "Multimeter", 1)
+		transID, jatuhTempo := s.pinjamDiambil(admin, mhs, alatID)
+		mulai := jatuhTempo.Add(24 * time.Hour)
+
+		var wg sync.WaitGroup
+		var reservasi, perpanjangan int
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			reservasi, _ = s.kirim("POST", "/api/reservasi", lain, map[string]any{
+				"alat_id": alatID, "jumlah": 1,
+				"mulai": mulai.Format(time.RFC3339), "selesai": mulai.Add(2 * time.Hour).Format(time.RFC3339),
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			perpanjangan, _ = s.kirim("POST", "/api/peminjaman/"+transID+"/perpanjang", mhs,
+				map[string]any{"jatuh_tempo": jatuhTempo.Add(72 * time.Hour).Format(time.RFC3339)})
+		}()
+		wg.Wait()
+		if reservasi == http.StatusCreated && perpanjangan == http.StatusOK {
+			t.Fatalf("reservasi dan perpanjangan otomatis sama-sama lolos untuk rentang yang sama")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package routes menyusun semua endpoint SIPAK menjadi satu http.Handler
+package routes
+
+import (
+	"net/http"
+
+	"SIPAK/handlers"
+	"SIPAK/mail"
+	"SIPAK/middleware"
+	"SIPAK/models"
+	"SIPAK/repository"
+	"SIPAK/storage"
+	"SIPAK/utils"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/cors"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NewRouter membuat router Chi dengan handler yang memakai repository di store,
+// menyimpan file upload di files dan mengirim email lewat mailer.
+// Dengan repository.NewMemoryStore() router bisa dites tanpa MongoDB.
+func NewRouter(store *repository.Store, files *storage.Local, mailer mail.Mailer) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(chimw.RequestID)
+	r.Use(chimw.Logger)
+	r.Use(chimw.Recoverer)
+
+	r.Use(cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"Link"},
+		AllowCredentials: false,
+		MaxAge:           300,
+	}).Handler)
+
+	// Tambah middleware API key global untuk semua endpoint /api
+	r.Route("/api", func(api chi.Router) {
+		// Semua endpoint di bawah /api harus pakai API Key
+		api.Use(middleware.APIKeyMiddleware)
+
+		// ==== AUTH (tanpa JWT, tapi wajib API Key) ====
+		authHandler := handlers.NewAuthHandler(store, mailer)
+		api.Post("/auth/register", authHandler.Register)
+		api.Post("/auth/login", authHandler.Login)
+		api.Post("/auth/refresh", authHandler.Refresh)
+		api.Post("/auth/forgot-password", authHandler.ForgotPassword)
+		api.Post("/auth/reset-password", authHandler.ResetPassword)
+		api.Post("/auth/verify-email", authHandler.VerifikasiEmail)
+		api.Post("/auth/resend-verification", authHandler.KirimUlangVerifikasi)
+
+		// Daftar jurusan dibutuhkan form registrasi sebelum user punya token
+		jurusanHandler := handlers.NewJurusanHandler(store)
+		api.Get("/jurusan", jurusanHandler.ListJurusan)
+
+		// ==== ENDPOINT YANG BUTUH JWT ====
+		api.Group(func(priv chi.Router) {
+			// Semua endpoint di group ini butuh JWT
+			priv.Use(middleware.AuthMiddleware(store.Sessions))
+
+			priv.Post("/auth/logout", authHandler.Logout)
+
+			// ----- Akun sendiri -----
+			profilHandler := handlers.NewProfilHandler(store, files)
+			priv.Get("/me", profilHandler.ProfilSaya)
+			priv.Patch("/me", profilHandler.UpdateProfilSaya)
+			priv.Put("/me/avatar", profilHandler.UploadAvatar)
+			priv.Put("/me/password", authHandler.GantiPassword)
+
+			// ----- Alat -----
+			alatHandler := handlers.NewAlatHandler(store)
+			priv.Get("/alat", alatHandler.ListAlat)
+			priv.Get("/alat/suggest", alatHandler.SuggestAlat)
+			priv.Get("/alat/{id}", alatHandler.GetAlatByID)
+			priv.Get("/alat/{id}/ketersediaan", alatHandler.Ketersediaan)
+
+			// ----- Peminjaman -----
+			pinjamHandler := handlers.NewPeminjamanHandler(store, files)
+			priv.Post("/peminjaman", pinjamHandler.PinjamAlat)
+			priv.Post("/peminjaman/{id}/batal", pinjamHandler.BatalkanPeminjamanSaya)
+			priv.Post("/peminjaman/{id}/perpanjang", pinjamHandler.AjukanPerpanjangan)
+			priv.Post("/pengembalian/{id}", pinjamHandler.KembalikanAlatSaya)
+			priv.Get("/peminjaman/me", pinjamHandler.ListTransaksiUser)
+			priv.Get("/riwayat", pinjamHandler.RiwayatSaya)
+
+			// ----- Reservasi -----
+			reservasiHandler := handlers.NewReservasiHandler(store)
+			priv.Post("/reservasi", reservasiHandler.BuatReservasi)
+			priv.Get("/reservasi/me", reservasiHandler.ReservasiSaya)
+			priv.Post("/reservasi/{id}/batal", reservasiHandler.BatalkanReservasiSaya)
+
+			// ----- Denda -----
+			dendaHandler := handlers.NewDendaHandler(store)
+			priv.Get("/denda/me", dendaHandler.DendaSaya)
+
+			// ----- Kasus kerusakan -----
+			kasusHandler := handlers.NewKasusHandler(store)
+			priv.Get("/kasus/me", kasusHandler.KasusSaya)
+
+			// ----- Kategori -----
+			kategoriHandler := handlers.NewKategoriHandler(store)
+			priv.Get("/kategori", kategoriHandler.ListKategori)
+
+			// ----- Lab -----
+			labHandler := handlers.NewLabHandler(store)
+			priv.Get("/lab", labHandler.ListLab)
+			priv.Get("/lab/{id}", labHandler.GetLab)
+
+			// ----- Endpoint admin, dibatasi permission role -----
+			izin := func(perms ...string) func(http.Handler) http.Handler {
+				return middleware.RequirePermission(store.Roles, perms...)
+			}
+
+			// CRUD alat admin
+			priv.With(izin(models.PermAlatWrite)).Post("/admin/alat", alatHandler.CreateAlat)
+			priv.With(izin(models.PermAlatWrite)).Put("/admin/alat/{id}", alatHandler.UpdateAlat)
+			priv.With(izin(models.PermAlatWrite)).Delete("/admin/alat/{id}", alatHandler.DeleteAlat)
+			priv.With(izin(models.PermAlatWrite)).Get("/admin/alat/dihapus", alatHandler.ListAlatDihapus)
+			priv.With(izin(models.PermAlatWrite)).Post("/admin/alat/{id}/pulihkan", alatHandler.PulihkanAlat)
+
+			// Unit fisik alat
+			priv.With(izin(models.PermAlatWrite)).Get("/admin/alat/{id}/unit", alatHandler.ListUnit)
+			priv.With(izin(models.PermAlatWrite)).Post("/admin/alat/{id}/unit", alatHandler.TambahUnit)
+			priv.With(izin(models.PermAlatWrite)).Put("/admin/unit/{id}", alatHandler.UpdateUnit)
+
+			// Buku besar mutasi & rekonsiliasi stok alat
+			stokHandler := handlers.NewStokHandler(store)
+			priv.With(izin(models.PermAlatWrite)).Get("/admin/stok/mutasi", stokHandler.ListMutasi)
+			priv.With(izin(models.PermAlatWrite)).Get("/admin/stok/rekonsiliasi", stokHandler.Rekonsiliasi)
+			priv.With(izin(models.PermAlatWrite)).Post("/admin/stok/rekonsiliasi", stokHandler.Rekonsiliasi)
+
+			// User management admin
+			userHandler := handlers.NewUserHandler(store)
+			priv.With(izin(models.PermUserManage)).Get("/admin/users", userHandler.ListUsers)
+			priv.With(izin(models.PermUserManage)).Get("/admin/users/nim/{nim}", userHandler.GetUserByNIM)
+			priv.With(izin(models.PermUserManage)).Patch("/admin/users/{id}/role", userHandler.UpdateUserRole)
+			priv.With(izin(models.PermUserManage)).Post("/admin/users/{id}/revoke-sessions", userHandler.RevokeSessions)
+
+			// Semua transaksi (admin)
+			priv.With(izin(models.PermPeminjamanRead)).Get("/admin/peminjaman", pinjamHandler.ListSemuaTransaksi)
+			priv.With(izin(models.PermPeminjamanRead)).Get("/admin/peminjaman/terlambat", pinjamHandler.ListTerlambat)
+			priv.With(izin(models.PermPeminjamanApprove)).Post("/admin/peminjaman/{id}/setujui", pinjamHandler.SetujuiPeminjaman)
+			priv.With(izin(models.PermPeminjamanApprove)).Post("/admin/peminjaman/{id}/tolak", pinjamHandler.TolakPeminjaman)
+			priv.With(izin(models.PermPeminjamanHandover)).Post("/admin/peminjaman/{id}/ambil", pinjamHandler.AmbilPeminjaman)
+			priv.With(izin(models.PermPeminjamanHandover)).Post("/admin/peminjaman/{id}/kembalikan", pinjamHandler.TerimaPengembalian)
+			priv.With(izin(models.PermPeminjamanApprove)).Post("/admin/peminjaman/{id}/batal", pinjamHandler.BatalkanPeminjaman)
+			priv.With(izin(models.PermPeminjamanRead)).Get("/admin/riwayat", pinjamHandler.RiwayatSemua)
+
+			// Antrian perpanjangan peminjaman
+			priv.With(izin(models.PermPeminjamanRead)).Get("/admin/perpanjangan", pinjamHandler.ListPerpanjangan)
+			priv.With(izin(models.PermPeminjamanApprove)).Post("/admin/peminjaman/{id}/perpanjangan/setujui", pinjamHandler.SetujuiPerpanjangan)
+			priv.With(izin(models.PermPeminjamanApprove)).Post("/admin/peminjaman/{id}/perpanjangan/tolak", pinjamHandler.TolakPerpanjangan)
+
+			// Reservasi alat
+			priv.With(izin(models.PermReservasiManage)).Get("/admin/reservasi", reservasiHandler.ListReservasi)
+			priv.With(izin(models.PermReservasiManage)).Post("/admin/reservasi/{id}/ambil", reservasiHandler.AmbilReservasi)
+
+			// Denda keterlambatan
+			priv.With(izin(models.PermDendaManage)).Get("/admin/denda", dendaHandler.ListDenda)
+			priv.With(izin(models.PermDendaManage)).Post("/admin/denda/{id}/bayar", dendaHandler.BayarDenda)
+			priv.With(izin(models.PermDendaManage)).Post("/admin/denda/{id}/hapuskan", dendaHandler.HapuskanDenda)
+
+			// Kasus kerusakan / kehilangan unit
+			priv.With(izin(models.PermKasusManage)).Get("/admin/kasus", kasusHandler.ListKasus)
+			priv.With(izin(models.PermKasusManage)).Post("/admin/kasus/{id}/selesaikan", kasusHandler.SelesaikanKasus)
+
+			// Aturan kategori alat
+			priv.With(izin(models.PermKategoriWrite)).Post("/admin/kategori", kategoriHandler.CreateKategori)
+			priv.With(izin(models.PermKategoriWrite)).Put("/admin/kategori/{id}", kategoriHandler.UpdateKategori)
+			priv.With(izin(models.PermKategoriWrite)).Delete("/admin/kategori/{id}", kategoriHandler.DeleteKategori)
+
+			// Aturan kuota & kelayakan peminjaman
+			aturanHandler := handlers.NewAturanHandler(store)
+			priv.With(izin(models.PermAturanManage)).Get("/admin/aturan", aturanHandler.ListAturan)
+			priv.With(izin(models.PermAturanManage)).Post("/admin/aturan", aturanHandler.CreateAturan)
+			priv.With(izin(models.PermAturanManage)).Put("/admin/aturan/{id}", aturanHandler.UpdateAturan)
+			priv.With(izin(models.PermAturanManage)).Delete("/admin/aturan/{id}", aturanHandler.DeleteAturan)
+
+			// Lab / lokasi alat beserta staff-nya
+			priv.With(izin(models.PermLabManage)).Post("/admin/lab", labHandler.CreateLab)
+			priv.With(izin(models.PermLabManage)).Put("/admin/lab/{id}", labHandler.UpdateLab)
+			priv.With(izin(models.PermLabManage)).Delete("/admin/lab/{id}", labHandler.DeleteLab)
+
+			// Daftar jurusan untuk registrasi
+			priv.With(izin(models.PermJurusanWrite)).Post("/admin/jurusan", jurusanHandler.CreateJurusan)
+			priv.With(izin(models.PermJurusanWrite)).Put("/admin/jurusan/{id}", jurusanHandler.UpdateJurusan)
+			priv.With(izin(models.PermJurusanWrite)).Delete("/admin/jurusan/{id}", jurusanHandler.DeleteJurusan)
+
+			// Audit log perubahan data
+			auditHandler := handlers.NewAuditHandler(store)
+			priv.With(izin(models.PermAuditRead)).Get("/admin/audit", auditHandler.ListAudit)
+
+			// Role & permission (super admin)
+			roleHandler := handlers.NewRoleHandler(store)
+			priv.With(izin(models.PermRoleManage)).Get("/admin/permissions", roleHandler.ListPermission)
+			priv.With(izin(models.PermRoleManage)).Get("/admin/roles", roleHandler.ListRole)
+			priv.With(izin(models.PermRoleManage)).Post("/admin/roles", roleHandler.CreateRole)
+			priv.With(izin(models.PermRoleManage)).Put("/admin/roles/{id}", roleHandler.UpdateRole)
+			priv.With(izin(models.PermRoleManage)).Delete("/admin/roles/{id}", roleHandler.DeleteRole)
+		})
+	})
+
+	// Avatar & foto pengembalian yang sudah diupload, hanya untuk pemilik
+	// atau staff. URL-nya tetap di luar /api karena disimpan apa adanya di data.
+	berkasHandler := handlers.NewBerkasHandler(store, files, files.BaseURL())
+	r.With(middleware.APIKeyMiddleware, middleware.AuthMiddleware(store.Sessions)).
+		Get(files.BaseURL()+"/{nama}", berkasHandler.AmbilBerkas)
+
+	// Root endpoint sederhana untuk cek status API
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSON(w, http.StatusOK, utils.JSONResponse{
+			Success: true,
+			Message: "SIPAK API berjalan 🚀",
+		})
+	})
+
+	return r
+}
@@ -0,0 +1,340 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"SIPAK/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRegisterDanLogin(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	s.harus(http.StatusCreated, "POST", "/api/admin/jurusan", admin, map[string]any{"nama": jurusanUji, "fakultas": "Teknik"})
+
+	daftar := map[string]any{
+		"nama": "Budi", "email": "budi@kampus.ac.id", "password": sandiMahasiswa, "nim": "F55124001", "jurusan": jurusanUji,
+	}
+	out := s.harus(http.StatusCreated, "POST", "/api/auth/register", "", daftar)
+	if data(out)["email_terverifikasi"] == true {
+		t.Error("user baru seharusnya belum terverifikasi")
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/auth/register", "", daftar)
+
+	s.harus(http.StatusUnauthorized, "POST", "/api/auth/login", "", map[string]any{"email": "budi@kampus.ac.id", "password": "salah"})
+	token := s.login("budi@kampus.ac.id", sandiMahasiswa)
+
+	out = s.harus(http.StatusOK, "GET", "/api/me", token, nil)
+	if data(out)["nim"] != "F55124001" || data(out)["jurusan"] != jurusanUji {
+		t.Errorf("profil tidak sesuai: %v", data(out))
+	}
+	s.harus(http.StatusUnauthorized, "GET", "/api/me", "", nil)
+	s.harus(http.StatusForbidden, "GET", "/api/admin/users", token, nil)
+}
+
+func TestRegisterTanpaNIM(t *testing.T) {
+	s := newServerUji(t)
+	out := s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Dosen Tamu", "email": "tamu@kampus.ac.id", "password": sandiMahasiswa,
+	})
+	if d := data(out); d["nim"] != nil || d["jurusan"] != nil {
+		t.Errorf("nim/jurusan seharusnya kosong: %v", d)
+	}
+	// NIM kosong tidak dianggap bentrok dengan akun lain tanpa NIM
+	s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Dosen Lain", "email": "lain@kampus.ac.id", "password": sandiMahasiswa,
+	})
+	s.harus(http.StatusBadRequest, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Budi", "email": "budi@kampus.ac.id", "password": sandiMahasiswa, "jurusan": "Tidak Ada",
+	})
+
+	// Tanpa NIM_REGEX format NIM tidak diperiksa
+	s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Ani", "email": "ani@kampus.ac.id", "password": sandiMahasiswa, "nim": "2024-001",
+	})
+	config.AppConfig.FormatNIM = regexp.MustCompile(`^[A-Z][0-9]{8}$`)
+	s.harus(http.StatusBadRequest, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Citra", "email": "citra@kampus.ac.id", "password": sandiMahasiswa, "nim": "2024-002",
+	})
+}
+
+// TestEmailGagalTidakMembocorkanAkun memastikan response lupa password dan
+// kirim ulang verifikasi sama untuk akun terdaftar walaupun email gagal
+func TestEmailGagalTidakMembocorkanAkun(t *testing.T) {
+	s := newServerUji(t)
+	s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Budi", "email": "budi@kampus.ac.id", "password": sandiMahasiswa,
+	})
+	s.surat.gagal = true
+
+	for _, path := range []string{"/api/auth/forgot-password", "/api/auth/resend-verification"} {
+		_, ada := s.kirim("POST", path, "", map[string]any{"email": "budi@kampus.ac.id"})
+		_, tidakAda := s.kirim("POST", path, "", map[string]any{"email": "siapa@kampus.ac.id"})
+		s.harus(http.StatusOK, "POST", path, "", map[string]any{"email": "budi@kampus.ac.id"})
+		if ada["message"] != tidakAda["message"] {
+			t.Errorf("%s: response berbeda %v dan %v", path, ada, tidakAda)
+		}
+	}
+}
+
+func TestPeminjamanButuhEmailTerverifikasi(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	alatID := s.buatAlat(admin, "Proyektor", 2)
+	s.harus(http.StatusCreated, "POST", "/api/admin/jurusan", admin, map[string]any{"nama": jurusanUji, "fakultas": "Teknik"})
+	s.harus(http.StatusCreated, "POST", "/api/auth/register", "", map[string]any{
+		"nama": "Budi", "email": "budi@kampus.ac.id", "password": sandiMahasiswa, "nim": "F55124001", "jurusan": jurusanUji,
+	})
+	token := s.login("budi@kampus.ac.id", sandiMahasiswa)
+
+	out := s.harus(http.StatusForbidden, "POST", "/api/peminjaman", token, map[string]any{"alat_id": alatID, "jumlah": 1})
+	if out["kode"] != "EMAIL_BELUM_TERVERIFIKASI" {
+		t.Errorf("kode %v, ingin EMAIL_BELUM_TERVERIFIKASI", out["kode"])
+	}
+}
+
+func TestAlurPinjamSetujuiAmbilKembalikan(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 5)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 2})
+	transID := data(out)["id"].(string)
+	if data(out)["status"] != "DIAJUKAN" {
+		t.Errorf("status %v, ingin DIAJUKAN", data(out)["status"])
+	}
+	if alat := s.alat(alatID); alat.StokTersedia != 5 {
+		t.Errorf("stok dipotong sebelum disetujui: %d", alat.StokTersedia)
+	}
+
+	// Peminjam tidak bisa menyetujui sendiri
+	s.harus(http.StatusForbidden, "POST", "/api/admin/peminjaman/"+transID+"/setujui", mhs, nil)
+	out = s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	if unit, _ := data(out)["unit_ids"].([]any); len(unit) != 2 {
+		t.Errorf("unit dipesan %d, ingin 2", len(unit))
+	}
+	if alat := s.alat(alatID); alat.StokTersedia != 3 {
+		t.Errorf("stok_tersedia %d setelah disetujui, ingin 3", alat.StokTersedia)
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+
+	out = s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+	if data(out)["status"] != "DIAMBIL" {
+		t.Errorf("status %v, ingin DIAMBIL", data(out)["status"])
+	}
+
+	out = s.harus(http.StatusOK, "POST", "/api/pengembalian/"+transID, mhs, nil)
+	if data(out)["status"] != "DIKEMBALIKAN" {
+		t.Errorf("status %v, ingin DIKEMBALIKAN", data(out)["status"])
+	}
+	if alat := s.alat(alatID); alat.StokTersedia != 5 {
+		t.Errorf("stok_tersedia %d setelah kembali, ingin 5", alat.StokTersedia)
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/pengembalian/"+transID, mhs, nil)
+
+	out = s.harus(http.StatusOK, "GET", "/api/riwayat", mhs, nil)
+	riwayat, _ := out["data"].([]any)
+	if len(riwayat) != 1 || riwayat[0].(map[string]any)["status"] != "DIKEMBALIKAN" {
+		t.Errorf("riwayat tidak sesuai: %v", riwayat)
+	}
+}
+
+func TestCheckInOlehStaff(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 1)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	transID := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+
+	// Alat tanpa lab wajib menyebut lokasi penerimaan
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/peminjaman/"+transID+"/kembalikan", admin, nil)
+	out = s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/kembalikan", admin, map[string]any{"lokasi": "Gudang"})
+	if data(out)["status"] != "DIKEMBALIKAN" {
+		t.Errorf("status %v, ingin DIKEMBALIKAN", data(out)["status"])
+	}
+	if alat := s.alat(alatID); alat.StokTersedia != 1 {
+		t.Errorf("stok_tersedia %d, ingin 1", alat.StokTersedia)
+	}
+}
+
+func TestTolakDanBatalPeminjaman(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 3)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	ditolak := data(out)["id"].(string)
+	out = s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+ditolak+"/tolak", admin, map[string]any{"alasan": "Stok untuk praktikum"})
+	if data(out)["status"] != "DITOLAK" {
+		t.Errorf("status %v, ingin DITOLAK", data(out)["status"])
+	}
+	s.harus(http.StatusBadRequest, "POST", "/api/admin/peminjaman/"+ditolak+"/setujui", admin, nil)
+
+	out = s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 2})
+	dibatalkan := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+dibatalkan+"/setujui", admin, nil)
+	if alat := s.alat(alatID); alat.StokTersedia != 1 {
+		t.Errorf("stok_tersedia %d, ingin 1", alat.StokTersedia)
+	}
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+dibatalkan+"/batal", admin, map[string]any{"alasan": "Jadwal berubah"})
+	if alat := s.alat(alatID); alat.StokTersedia != 3 {
+		t.Errorf("stok_tersedia %d setelah batal, ingin 3", alat.StokTersedia)
+	}
+}
+
+func TestCheckInPengembalianSebagian(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 3)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 3})
+	transID := data(out)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+
+	out = s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/kembalikan", admin, map[string]any{"lokasi": "Gudang", "jumlah": 1})
+	if data(out)["status"] != "SEBAGIAN_KEMBALI" {
+		t.Errorf("status %v, ingin SEBAGIAN_KEMBALI", data(out)["status"])
+	}
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/kembalikan", admin, map[string]any{"lokasi": "Lab Dasar"})
+
+	oid, _ := primitive.ObjectIDFromHex(transID)
+	trans, err := s.store.Transactions.FindByID(context.Background(), oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trans.Penerimaan) != 2 {
+		t.Fatalf("%d check-in tercatat, ingin 2", len(trans.Penerimaan))
+	}
+	pertama, kedua := trans.Penerimaan[0], trans.Penerimaan[1]
+	if pertama.Lokasi != "Gudang" || pertama.Jumlah != 1 || len(pertama.UnitIDs) != 1 {
+		t.Errorf("check-in pertama tidak sesuai: %+v", pertama)
+	}
+	if kedua.Lokasi != "Lab Dasar" || kedua.Jumlah != 2 || len(kedua.UnitIDs) != 2 {
+		t.Errorf("check-in kedua tidak sesuai: %+v", kedua)
+	}
+}
+
+// TestBukanPemilikSelaluDitolak memastikan user lain selalu mendapat 403
+// yang sama, apa pun status transaksinya
+func TestBukanPemilikSelaluDitolak(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	lain := s.daftarMahasiswa(admin, "ani@kampus.ac.id", "F55124002")
+	alatID := s.buatAlat(admin, "Proyektor", 2)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": alatID, "jumlah": 1})
+	transID := data(out)["id"].(string)
+
+	s.harus(http.StatusForbidden, "POST", "/api/pengembalian/"+transID, lain, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/ambil", admin, nil)
+	s.harus(http.StatusForbidden, "POST", "/api/pengembalian/"+transID, lain, nil)
+	s.harus(http.StatusOK, "POST", "/api/pengembalian/"+transID, mhs, nil)
+	s.harus(http.StatusForbidden, "POST", "/api/pengembalian/"+transID, lain, nil)
+	s.harus(http.StatusForbidden, "POST", "/api/peminjaman/"+transID+"/batal", lain, nil)
+}
+
+// TestHapusAlatMembatalkanSeluruhGrup memastikan hapus paksa alat ikut
+// membatalkan item grup alat lain dan melepas unitnya
+func TestHapusAlatMembatalkanSeluruhGrup(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	dihapus := s.buatAlat(admin, "Proyektor", 2)
+	lain := s.buatAlat(admin, "Multimeter", 2)
+
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{
+		"item": []map[string]any{{"alat_id": dihapus, "jumlah": 1}, {"alat_id": lain, "jumlah": 2}},
+	})
+	item, _ := data(out)["item"].([]any)
+	if len(item) != 2 {
+		t.Fatalf("%d item dibuat, ingin 2", len(item))
+	}
+	transID := item[0].(map[string]any)["id"].(string)
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+transID+"/setujui", admin, nil)
+	if alat := s.alat(lain); alat.StokTersedia != 0 {
+		t.Fatalf("stok_tersedia %d setelah disetujui, ingin 0", alat.StokTersedia)
+	}
+
+	out = s.harus(http.StatusOK, "DELETE", "/api/admin/alat/"+dihapus+"?force=true", admin, nil)
+	if n := data(out)["peminjaman_dibatalkan"]; n != float64(2) {
+		t.Errorf("peminjaman_dibatalkan %v, ingin 2", n)
+	}
+	for _, it := range item {
+		oid, _ := primitive.ObjectIDFromHex(it.(map[string]any)["id"].(string))
+		trans, err := s.store.Transactions.FindByID(context.Background(), oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trans.Status != "DIBATALKAN" {
+			t.Errorf("item %s berstatus %s, ingin DIBATALKAN", trans.ID.Hex(), trans.Status)
+		}
+	}
+	if alat := s.alat(lain); alat.StokTersedia != 2 {
+		t.Errorf("stok_tersedia alat lain %d, ingin 2", alat.StokTersedia)
+	}
+}
+
+// TestGantiRoleMencabutSession memastikan token dengan role lama tidak
+// bisa dipakai lagi setelah role user diturunkan
+func TestGantiRoleMencabutSession(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	user, err := s.store.Users.FindByEmail(context.Background(), "budi@kampus.ac.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/admin/users/" + user.ID.Hex() + "/role"
+
+	s.harus(http.StatusOK, "PATCH", path, admin, map[string]any{"role": "laboran"})
+	laboran := s.login("budi@kampus.ac.id", sandiMahasiswa)
+	s.harus(http.StatusOK, "GET", "/api/admin/peminjaman", laboran, nil)
+
+	out := s.harus(http.StatusOK, "PATCH", path, admin, map[string]any{"role": "mahasiswa"})
+	if data(out)["jumlah_session"] != float64(1) {
+		t.Errorf("jumlah_session %v, ingin 1", data(out)["jumlah_session"])
+	}
+	s.harus(http.StatusUnauthorized, "GET", "/api/admin/peminjaman", laboran, nil)
+	mhs := s.login("budi@kampus.ac.id", sandiMahasiswa)
+	s.harus(http.StatusForbidden, "GET", "/api/admin/peminjaman", mhs, nil)
+}
+
+func TestDaftarPeminjamanTerlambat(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	alatID := s.buatAlat(admin, "Proyektor", 2)
+
+	terlambat, _ := s.pinjamDiambil(admin, mhs, alatID)
+	s.aturJatuhTempo(terlambat, time.Now().Add(-36*time.Hour))
+	s.pinjamDiambil(admin, mhs, alatID)
+
+	s.harus(http.StatusForbidden, "GET", "/api/admin/peminjaman/terlambat", mhs, nil)
+	out := s.harus(http.StatusOK, "GET", "/api/admin/peminjaman/terlambat", admin, nil)
+	list, _ := out["data"].([]any)
+	if len(list) != 1 {
+		t.Fatalf("%d peminjaman terlambat, ingin 1", len(list))
+	}
+	item := list[0].(map[string]any)
+	if item["id"] != terlambat {
+		t.Errorf("id %v, ingin %s", item["id"], terlambat)
+	}
+	if item["hari_terlambat"] != float64(2) || item["email_peminjam"] != "budi@kampus.ac.id" || item["nama_alat"] != "Proyektor" {
+		t.Errorf("isi tidak sesuai: %v", item)
+	}
+}
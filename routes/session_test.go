@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// sesi adalah pasangan access token dan refresh token satu session login
+type sesi struct {
+	token   string
+	refresh string
+}
+
+func (s *serverUji) loginSesi(email, password string) sesi {
+	s.t.Helper()
+	out := s.harus(http.StatusOK, "POST", "/api/auth/login", "", map[string]any{"email": email, "password": password})
+	return sesi{token: data(out)["token"].(string), refresh: data(out)["refresh_token"].(string)}
+}
+
+func (s *serverUji) refresh(refreshToken string) (int, sesi) {
+	code, out := s.kirim("POST", "/api/auth/refresh", "", map[string]any{"refresh_token": refreshToken})
+	if code != http.StatusOK {
+		return code, sesi{}
+	}
+	return code, sesi{token: data(out)["token"].(string), refresh: data(out)["refresh_token"].(string)}
+}
+
+func TestRefreshMerotasiToken(t *testing.T) {
+	s := newServerUji(t)
+	lama := s.loginSesi(emailAdminUji, sandiAdminUji)
+
+	code, baru := s.refresh(lama.refresh)
+	if code != http.StatusOK {
+		t.Fatalf("refresh: status %d", code)
+	}
+	if baru.token == lama.token || baru.refresh == lama.refresh {
+		t.Fatal("refresh tidak menerbitkan token baru")
+	}
+	s.harus(http.StatusOK, "GET", "/api/me", baru.token, nil)
+	s.harus(http.StatusUnauthorized, "GET", "/api/me", lama.token, nil)
+
+	// Refresh token baru tetap bisa dirotasi lagi
+	code, ketiga := s.refresh(baru.refresh)
+	if code != http.StatusOK {
+		t.Fatalf("refresh kedua: status %d", code)
+	}
+	s.harus(http.StatusOK, "GET", "/api/me", ketiga.token, nil)
+}
+
+// TestRefreshTokenDipakaiUlang memastikan refresh token yang sudah dirotasi
+// dianggap bocor: session dicabut sehingga token terbaru pun tidak berlaku
+func TestRefreshTokenDipakaiUlang(t *testing.T) {
+	s := newServerUji(t)
+	lama := s.loginSesi(emailAdminUji, sandiAdminUji)
+	lain := s.loginSesi(emailAdminUji, sandiAdminUji)
+
+	_, baru := s.refresh(lama.refresh)
+	if code, _ := s.refresh(lama.refresh); code != http.StatusUnauthorized {
+		t.Fatalf("refresh token lama diterima: status %d", code)
+	}
+
+	s.harus(http.StatusUnauthorized, "GET", "/api/me", baru.token, nil)
+	if code, _ := s.refresh(baru.refresh); code != http.StatusUnauthorized {
+		t.Errorf("refresh token terbaru session yang dicabut diterima: status %d", code)
+	}
+	// Session lain milik user yang sama tidak ikut dicabut
+	s.harus(http.StatusOK, "GET", "/api/me", lain.token, nil)
+}
+
+func TestLogoutMencabutSession(t *testing.T) {
+	s := newServerUji(t)
+	sekarang := s.loginSesi(emailAdminUji, sandiAdminUji)
+	lain := s.loginSesi(emailAdminUji, sandiAdminUji)
+
+	s.harus(http.StatusOK, "POST", "/api/auth/logout", sekarang.token, nil)
+	s.harus(http.StatusUnauthorized, "GET", "/api/me", sekarang.token, nil)
+	if code, _ := s.refresh(sekarang.refresh); code != http.StatusUnauthorized {
+		t.Errorf("refresh token setelah logout diterima: status %d", code)
+	}
+	s.harus(http.StatusOK, "GET", "/api/me", lain.token, nil)
+}
+
+func TestAdminCabutSemuaSession(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+	satu := s.loginSesi("budi@kampus.ac.id", sandiMahasiswa)
+	dua := s.loginSesi("budi@kampus.ac.id", sandiMahasiswa)
+	user, err := s.store.Users.FindByEmail(context.Background(), "budi@kampus.ac.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/admin/users/" + user.ID.Hex() + "/revoke-sessions"
+
+	s.harus(http.StatusForbidden, "POST", path, satu.token, nil)
+	out := s.harus(http.StatusOK, "POST", path, admin, nil)
+	// Tiga session: dari daftarMahasiswa dan dua login di atas
+	if n := data(out)["jumlah_session"]; n != float64(3) {
+		t.Errorf("jumlah_session %v, ingin 3", n)
+	}
+	for _, x := range []sesi{satu, dua} {
+		s.harus(http.StatusUnauthorized, "GET", "/api/me", x.token, nil)
+		if code, _ := s.refresh(x.refresh); code != http.StatusUnauthorized {
+			t.Errorf("refresh token setelah dicabut diterima: status %d", code)
+		}
+	}
+	s.harus(http.StatusOK, "GET", "/api/me", admin, nil)
+	s.harus(http.StatusOK, "GET", "/api/me", s.login("budi@kampus.ac.id", sandiMahasiswa), nil)
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"SIPAK/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// transaksiTanpaUnit menyimpan transaksi DISETUJUI yang belum memegang
+// unit, seperti data lama sebelum unit dipesan saat persetujuan
+func (s *serverUji) transaksiTanpaUnit(alatID string) {
+	s.t.Helper()
+	oid, _ := primitive.ObjectIDFromHex(alatID)
+	admin, err := s.store.Users.FindByEmail(context.Background(), emailAdminUji)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	now := time.Now()
+	err = s.store.Transactions.Create(context.Background(), &models.Transaction{
+		ID:         primitive.NewObjectID(),
+		UserID:     admin.ID,
+		AlatID:     oid,
+		Jumlah:     1,
+		Status:     models.StatusDisetujui,
+		JatuhTempo: now.Add(24 * time.Hour),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// TestRekonsiliasiMelaporkanSisaSelisih memastikan pesan rekonsiliasi
+// hanya menyebut alat yang benar-benar sudah diperbaiki
+func TestRekonsiliasiMelaporkanSisaSelisih(t *testing.T) {
+	s := newServerUji(t)
+	admin := s.loginAdmin()
+	mhs := s.daftarMahasiswa(admin, "budi@kampus.ac.id", "F55124001")
+
+	// Unit tersedia cukup untuk transaksi tanpa unit, bisa diperbaiki
+	bisa := s.buatAlat(admin, "Multimeter", 2)
+	s.transaksiTanpaUnit(bisa)
+
+	// Satu-satunya unit sudah dipegang peminjaman lain
+	habis := s.buatAlat(admin, "Proyektor", 1)
+	out := s.harus(http.StatusCreated, "POST", "/api/peminjaman", mhs, map[string]any{"alat_id": habis, "jumlah": 1})
+	s.harus(http.StatusOK, "POST", "/api/admin/peminjaman/"+data(out)["id"].(string)+"/setujui", admin, nil)
+	s.transaksiTanpaUnit(habis)
+
+	out = s.harus(http.StatusOK, "POST", "/api/admin/stok/rekonsiliasi", admin, nil)
+	pesan, _ := out["message"].(string)
+	if !strings.Contains(pesan, "1 sudah diperbaiki") || !strings.Contains(pesan, "1 masih selisih") {
+		t.Errorf("pesan %q tidak menyebut hasil perbaikan dengan benar", pesan)
+	}
+	for _, item := range out["data"].([]any) {
+		item := item.(map[string]any)
+		hasil := item["hasil"].(map[string]any)
+		if masih := item["alat_id"] == habis; hasil["masih_selisih"] != masih {
+			t.Errorf("%s masih_selisih %v, ingin %v", item["nama"], hasil["masih_selisih"], masih)
+		}
+	}
+}
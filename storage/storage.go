@@ -0,0 +1,118 @@
+// Package storage menyimpan file yang diupload user, misalnya foto kondisi
+// alat saat pengembalian
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Storage menyimpan file dan mengembalikan URL untuk mengaksesnya
+type Storage interface {
+	// Simpan menyimpan isi r dengan nama unik berakhiran ext (misalnya ".jpg")
+	Simpan(ctx context.Context, ext string, r io.Reader) (url string, err error)
+	// Hapus menghapus file dari URL yang dikembalikan Simpan. URL yang
+	// bukan milik storage ini diabaikan.
+	Hapus(ctx context.Context, url string) error
+	// Sajikan menulis isi file dari URL yang dikembalikan Simpan ke
+	// response. Mengembalikan os.ErrNotExist jika file tidak ada.
+	Sajikan(w http.ResponseWriter, r *http.Request, url string) error
+}
+
+// Local menyimpan file di folder lokal. File hanya disajikan satu per satu
+// lewat Sajikan, tanpa daftar isi folder.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal membuat Local yang menyimpan file di dir. baseURL adalah path
+// tempat handler dipasang di router, misalnya "/uploads".
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	baseURL = "/" + strings.Trim(baseURL, "/")
+	return &Local{dir: dir, baseURL: baseURL}, nil
+}
+
+// Simpan menulis file ke folder lokal
+func (l *Local) Simpan(ctx context.Context, ext string, r io.Reader) (string, error) {
+	nama := primitive.NewObjectID().Hex() + ext
+
+	f, err := os.Create(filepath.Join(l.dir, nama))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path.Join(l.baseURL, nama), nil
+}
+
+// namaFile mengambil nama file dari URL yang dikembalikan Simpan
+func (l *Local) namaFile(url string) (string, bool) {
+	nama, ok := strings.CutPrefix(url, l.baseURL+"/")
+	if !ok || nama == "" || strings.ContainsAny(nama, `/\`) || strings.HasPrefix(nama, ".") {
+		return "", false
+	}
+	return nama, true
+}
+
+// Hapus menghapus file lokal dari URL-nya
+func (l *Local) Hapus(ctx context.Context, url string) error {
+	nama, ok := l.namaFile(url)
+	if !ok {
+		return nil
+	}
+	err := os.Remove(filepath.Join(l.dir, nama))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// BaseURL mengembalikan path tempat file disajikan
+func (l *Local) BaseURL() string {
+	return l.baseURL
+}
+
+// Sajikan menulis isi satu file dari URL-nya. Mengembalikan os.ErrNotExist
+// jika URL bukan milik storage ini atau filenya tidak ada; response belum
+// ditulis dalam kasus itu.
+func (l *Local) Sajikan(w http.ResponseWriter, r *http.Request, url string) error {
+	nama, ok := l.namaFile(url)
+	if !ok {
+		return os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(l.dir, nama))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return os.ErrNotExist
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, nama, info.ModTime(), f)
+	return nil
+}
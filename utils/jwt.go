@@ -15,13 +15,15 @@ type CustomClaims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateToken membuat JWT token baru
-func GenerateToken(userID, role string) (string, error) {
+// GenerateToken membuat JWT access token baru. jti dicatat di session
+// supaya token bisa dicabut sebelum kadaluarsa.
+func GenerateToken(userID, role, jti string) (string, error) {
 	claims := CustomClaims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // token 24 jam
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AppConfig.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "sipak-api",
 		},
@@ -36,7 +38,7 @@ func GenerateToken(userID, role string) (string, error) {
 func ParseToken(tokenStr string) (*CustomClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -7,9 +7,36 @@ import (
 
 // JSONResponse adalah format standar response API
 type JSONResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	// Kode adalah alasan error yang bisa dibaca mesin, misalnya kode aturan
+	// peminjaman yang dilanggar
+	Kode string      `json:"kode,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+// Meta berisi informasi paginasi untuk response list
+type Meta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	// NextPage kosong jika sudah di halaman terakhir
+	NextPage *int `json:"next_page,omitempty"`
+}
+
+// NewMeta menghitung jumlah halaman dan halaman berikutnya
+func NewMeta(page, limit int, total int64) *Meta {
+	meta := &Meta{Page: page, Limit: limit, Total: total, TotalPages: 1}
+	if limit > 0 {
+		meta.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if page < meta.TotalPages {
+		next := page + 1
+		meta.NextPage = &next
+	}
+	return meta
 }
 
 // WriteJSON menulis response JSON dengan status code tertentu
@@ -26,3 +53,12 @@ func WriteError(w http.ResponseWriter, status int, message string) {
 		Message: message,
 	})
 }
+
+// WriteErrorKode mengirim error standar beserta kode alasan
+func WriteErrorKode(w http.ResponseWriter, status int, kode, message string) {
+	WriteJSON(w, status, JSONResponse{
+		Success: false,
+		Message: message,
+		Kode:    kode,
+	})
+}
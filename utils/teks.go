@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PecahKata memecah teks menjadi kata huruf kecil, tanpa tanda baca
+func PecahKata(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// jarakEdit menghitung jarak Levenshtein antara dua kata
+func jarakEdit(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			biaya := 1
+			if a[i-1] == b[j-1] {
+				biaya = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+biaya)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// toleransiTypo adalah jumlah salah ketik yang masih diterima untuk kata
+// sepanjang n huruf. Kata pendek harus tepat supaya saran tidak melebar.
+func toleransiTypo(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// jarakPrefix mencari jarak edit terkecil antara kunci dan awalan kata.
+// Awalan yang dicoba sepanjang kunci ± toleransi supaya huruf yang
+// terlewat atau berlebih tetap cocok.
+func jarakPrefix(kunci, kata []rune) int {
+	toleransi := toleransiTypo(len(kunci))
+	terbaik := toleransi + 1
+	for n := len(kunci) - toleransi; n <= len(kunci)+toleransi; n++ {
+		if n < 1 || n > len(kata) {
+			continue
+		}
+		terbaik = min(terbaik, jarakEdit(kunci, kata[:n]))
+	}
+	return terbaik
+}
+
+// SaranPrefix memilih maksimal limit kandidat yang cocok dengan q. Setiap
+// kata di q harus menjadi awalan salah satu kata kandidat, dengan toleransi
+// salah ketik sesuai panjang kata. Hasil diurutkan dari jumlah salah ketik
+// paling sedikit, lalu nama terpendek.
+func SaranPrefix(q string, kandidat []string, limit int) []string {
+	kataKunci := PecahKata(q)
+	if len(kataKunci) == 0 {
+		return []string{}
+	}
+
+	type saran struct {
+		teks  string
+		jarak int
+	}
+	var cocok []saran
+	for _, k := range kandidat {
+		kata := PecahKata(k)
+		total := 0
+		for _, kunci := range kataKunci {
+			r := []rune(kunci)
+			terbaik := toleransiTypo(len(r)) + 1
+			for _, w := range kata {
+				terbaik = min(terbaik, jarakPrefix(r, []rune(w)))
+			}
+			if terbaik > toleransiTypo(len(r)) {
+				total = -1
+				break
+			}
+			total += terbaik
+		}
+		if total >= 0 {
+			cocok = append(cocok, saran{teks: k, jarak: total})
+		}
+	}
+
+	sort.SliceStable(cocok, func(i, j int) bool {
+		if cocok[i].jarak != cocok[j].jarak {
+			return cocok[i].jarak < cocok[j].jarak
+		}
+		return len(cocok[i].teks) < len(cocok[j].teks)
+	})
+	hasil := []string{}
+	for i := 0; i < len(cocok) && i < limit; i++ {
+		hasil = append(hasil, cocok[i].teks)
+	}
+	return hasil
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// ParseWaktu membaca waktu dari query/body. Format yang diterima:
+// RFC3339 ("2025-01-31T13:00:00+08:00") atau tanggal saja ("2025-01-31").
+// Untuk tanggal saja, akhirHari menentukan apakah hasilnya awal hari (00:00:00)
+// atau akhir hari (23:59:59) waktu lokal.
+func ParseWaktu(s string, akhirHari bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("format waktu harus RFC3339 atau YYYY-MM-DD")
+	}
+	if akhirHari {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}